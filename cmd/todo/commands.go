@@ -2,16 +2,20 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"os"
+	"os/user"
+	"strconv"
 	"strings"
 	"time"
 
 	"godoit/internal/alerts"
-	"godoit/internal/clock"
+	"godoit/internal/app"
 	"godoit/internal/core"
 	"godoit/internal/notifications"
-	"godoit/internal/repository"
 	"godoit/internal/server"
 	"godoit/internal/service"
 	"godoit/internal/store"
@@ -24,108 +28,274 @@ func must(err error) {
   }
 }
 
-// getStore returns the default store instance
-func getStore() *store.JSONStore {
-  s, err := store.DefaultStore()
+// getService returns the task service configured for this user. Changes
+// made through it are logged as coming from the CLI.
+func getService() *service.TaskService {
+  svc, err := app.NewTaskService()
   must(err)
-  return s
+  svc.SetOrigin(core.Origin{Source: "cli", User: currentUser()})
+  return svc
 }
 
-func getService() *service.TaskService {
-  s := getStore()
-  repo := repository.NewJSONTaskRepository(s)
-  return service.NewTaskService(repo, clock.SystemClock{})
+// currentUser returns the name of the user running the command, if known
+func currentUser() string {
+  if u, err := user.Current(); err == nil && u.Username != "" {
+    return u.Username
+  }
+  if name := os.Getenv("USER"); name != "" {
+    return name
+  }
+  return os.Getenv("USERNAME")
+}
+
+// resolveIDs turns a command argument into task IDs. By default the argument
+// is a list of task IDs such as "3,7-9"; with byIndex it lists positions in
+// the most recently displayed task list instead.
+func resolveIDs(arg string, byIndex bool) []int {
+  nums, err := core.ParseIDList(arg)
+  must(err)
+  if !byIndex {
+    return nums
+  }
+
+  view, err := loadLastView()
+  must(err)
+  if len(view) == 0 {
+    log.Fatal("Error: no list has been displayed yet; run \"godoit list\" first")
+  }
+
+  ids := make([]int, 0, len(nums))
+  for _, idx := range nums {
+    if idx > len(view) {
+      log.Fatalf("Invalid index: %d (last list showed %d task(s))", idx, len(view))
+    }
+    ids = append(ids, view[idx-1])
+  }
+  return ids
+}
+
+// saveLastView remembers the IDs of the tasks just displayed, in display order
+func saveLastView(tasks []core.Task) error {
+  path, err := store.GetLastViewFile()
+  if err != nil {
+    return err
+  }
+  ids := make([]int, 0, len(tasks))
+  for _, t := range tasks {
+    ids = append(ids, t.ID)
+  }
+  data, err := json.Marshal(ids)
+  if err != nil {
+    return err
+  }
+  return os.WriteFile(path, data, 0644)
+}
+
+// loadLastView returns the task IDs of the most recently displayed list
+func loadLastView() ([]int, error) {
+  path, err := store.GetLastViewFile()
+  if err != nil {
+    return nil, err
+  }
+  data, err := os.ReadFile(path)
+  if errors.Is(err, os.ErrNotExist) {
+    return nil, nil
+  }
+  if err != nil {
+    return nil, err
+  }
+  var ids []int
+  if err := json.Unmarshal(data, &ids); err != nil {
+    return nil, err
+  }
+  return ids, nil
+}
+
+// formatIDs renders task IDs as "#1, #2"
+func formatIDs(ids []int) string {
+  parts := make([]string, 0, len(ids))
+  for _, id := range ids {
+    parts = append(parts, fmt.Sprintf("#%d", id))
+  }
+  return strings.Join(parts, ", ")
+}
+
+// statusMark returns the one-character status shown in list output
+func statusMark(t core.Task) string {
+  if t.Skipped {
+    return "-"
+  }
+  switch t.State() {
+  case core.StatusInProgress:
+    return "▶"
+  case core.StatusWaiting:
+    return "…"
+  case core.StatusBlocked:
+    return "⊘"
+  case core.StatusCancelled:
+    return "✗"
+  case core.StatusDone:
+    return "✓"
+  }
+  return " "
+}
+
+// describeRepeatPolicy renders the non-default recurrence options of a task
+func describeRepeatPolicy(t core.Task) string {
+  var parts []string
+  if t.RepeatFrom == core.RepeatFromCompletion {
+    parts = append(parts, "after completion")
+  }
+  if t.SkipMissed {
+    parts = append(parts, "skipping missed")
+  }
+  if len(parts) == 0 {
+    return ""
+  }
+  return " (" + strings.Join(parts, ", ") + ")"
 }
 
 // RunAdd adds a new task
-func RunAdd(title, description string, dueStr, repeat string, priority int, tags, after string) {
+func RunAdd(title, description string, dueStr, scheduledStr, estimateStr, repeat, repeatFrom string, skipMissed bool, priority int, tags, after string, parent int, autoComplete bool, project string) {
   if title == "" {
     log.Fatal("Error: -title is required")
   }
 
+  svc := getService()
+
   var due *time.Time
+  var timed bool
   if dueStr != "" {
-    if t, err := time.Parse("2006-01-02", dueStr); err == nil {
-      due = &t
+    if t, hasTime, err := svc.ParseDue(dueStr); err == nil {
+      due, timed = &t, hasTime
     } else {
       log.Fatalf("Invalid -due: %v", err)
     }
   }
 
-  svc := getService()
+  var scheduled *time.Time
+  var scheduledTimed bool
+  if scheduledStr != "" {
+    if t, hasTime, err := svc.ParseDue(scheduledStr); err == nil {
+      scheduled, scheduledTimed = &t, hasTime
+    } else {
+      log.Fatalf("Invalid -scheduled: %v", err)
+    }
+  }
+
+  var estimate core.Estimate
+  if estimateStr != "" {
+    e, err := svc.ParseEstimate(estimateStr)
+    if err != nil {
+      log.Fatalf("Invalid -estimate: %v", err)
+    }
+    estimate = e
+  }
+
   created, err := svc.AddTask(context.Background(), service.AddTaskInput{
     Title:       title,
     Description: description,
     Due:         due,
+    Timed:       timed,
+    Scheduled:   scheduled,
+    ScheduledTimed: scheduledTimed,
     Priority:    priority,
+    Estimate:    estimate,
     Tags:        core.ParseTags(tags),
     Repeat:      repeat,
+    RepeatFrom:  repeatFrom,
+    SkipMissed:  skipMissed,
     DependsOn:   core.ParseIDs(after),
+    ParentID:    parent,
+    AutoComplete: autoComplete,
+    Project:     project,
   })
   must(err)
-  fmt.Printf("Added: %s (ID: %d)\n", created.Title, created.ID)
+  if created.Project != "" {
+    fmt.Printf("Added: %s (ID: %d) to project %s\n", created.Title, created.ID, created.Project)
+  } else {
+    fmt.Printf("Added: %s (ID: %d)\n", created.Title, created.ID)
+  }
 }
 
 // RunList lists tasks with optional filters
-func RunList(showAll, today, week, detailed bool, grep, tags, sortKey, before, after string) {
+func RunList(v core.View) {
   svc := getService()
-  now := time.Now()
-
-  // Apply time-based filters
-  if today {
-    startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-    endOfDay := startOfDay.AddDate(0, 0, 1)
-    before = endOfDay.Format("2006-01-02")
-    after = startOfDay.Format("2006-01-02")
-  } else if week {
-    startOfWeek := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
-    // Move to start of week (Sunday)
-    for startOfWeek.Weekday() != time.Sunday {
-      startOfWeek = startOfWeek.AddDate(0, 0, -1)
-    }
-    endOfWeek := startOfWeek.AddDate(0, 0, 7)
-    before = endOfWeek.Format("2006-01-02")
-    after = startOfWeek.Format("2006-01-02")
-  }
-
-  var beforePtr, afterPtr *time.Time
-  if before != "" { if t, err := time.Parse("2006-01-02", before); err == nil { beforePtr = &t } }
-  if after != "" { if t, err := time.Parse("2006-01-02", after); err == nil { afterPtr = &t } }
-
-  visible, err := svc.QueryTasks(context.Background(), service.Query{
-    ShowAll: showAll,
-    Grep:    grep,
-    SortKey: sortKey,
-    Tags:    tags,
-    Before:  beforePtr,
-    After:   afterPtr,
-  })
+  now := svc.Now()
+  loc := now.Location()
+
+  if v.Filter != "" {
+    if _, err := core.ParseFilter(v.Filter, now); err != nil {
+      var ferr *core.FilterError
+      if errors.As(err, &ferr) {
+        fmt.Fprintln(os.Stderr, ferr.Caret())
+      }
+      log.Fatal(err)
+    }
+  }
+
+  // Dates and the today/week periods are resolved by the service, so saved
+  // views show the same tasks here and over HTTP
+  q, err := svc.ViewQuery(v)
+  must(err)
+  visible, err := svc.QueryTasks(context.Background(), q)
   must(err)
 
-  // also fetch all tasks to compute dependency info
-  allTasks, err := svc.QueryTasks(context.Background(), service.Query{ShowAll: true, SortKey: sortKey})
+  // also fetch all tasks, in every project, to compute dependency info
+  allTasks, err := svc.QueryTasks(context.Background(), service.Query{ShowAll: true, SortKey: v.Sort, Project: core.ProjectAll})
   must(err)
 
+  // In tree view subtasks are listed under their parents
+  var roots []*core.TreeNode
+  if v.Tree {
+    roots = core.BuildTree(visible)
+    visible = visible[:0]
+    var walk func(nodes []*core.TreeNode)
+    walk = func(nodes []*core.TreeNode) {
+      for _, n := range nodes {
+        visible = append(visible, n.Task)
+        walk(n.Children)
+      }
+    }
+    walk(roots)
+  }
+
+  // Remember what was shown so "-index" addressing refers to this view
+  if err := saveLastView(visible); err != nil {
+    log.Printf("Warning: could not save list view: %v", err)
+  }
+
   if len(visible) == 0 {
     fmt.Println("(no tasks)")
     return
   }
 
+  // Urgency is shown in the detailed view
+  urgency := make(map[int]core.Urgency)
+  if v.Detailed {
+    scored, err := svc.WithUrgency(context.Background(), visible)
+    must(err)
+    for _, st := range scored {
+      urgency[st.ID] = st.Urgency
+    }
+  }
+
   // Print header based on view
-  if today {
+  if v.Today {
     fmt.Println("📅 Today's Tasks")
     fmt.Println("================")
-  } else if week {
+  } else if v.Week {
     fmt.Println("📅 This Week's Tasks")
     fmt.Println("====================")
   }
 
-  for i, t := range visible {
-    status := " "
-    if t.IsDone() {
-      status = "✓"
-    }
+  if v.Tree {
+    printTree(roots, allTasks, now)
+    fmt.Printf("\nTotal: %d task(s)\n", len(visible))
+    return
+  }
 
+  for i, t := range visible {
     // Determine priority indicator
     priorityStr := ""
     switch t.Priority {
@@ -138,16 +308,16 @@ func RunList(showAll, today, week, detailed bool, grep, tags, sortKey, before, a
     }
 
     fmt.Print(strings.Repeat("=", 50), "\n")
-    fmt.Printf("\n%2d. [%s] %s %s\n", i+1, status, priorityStr, t.Title)
+    fmt.Printf("\n%2d. #%-3d [%s] %s %s\n", i+1, t.ID, statusMark(t), priorityStr, t.Title)
 
     // Show description if present
-    if detailed && t.Description != "" {
+    if v.Detailed && t.Description != "" {
       fmt.Printf("    📝 %s\n", t.Description)
     }
 
     // Show due date with status indicator
     if t.Due != nil {
-      dueStr := t.Due.Format("2006-01-02 15:04")
+      dueStr := t.DueString(loc)
       if t.IsOverdue(now) {
         fmt.Printf("    ⏰ Due: %s (OVERDUE!)\n", dueStr)
       } else if t.IsDueSoon(now, 24*time.Hour) {
@@ -157,6 +327,20 @@ func RunList(showAll, today, week, detailed bool, grep, tags, sortKey, before, a
       }
     }
 
+    // Show scheduled date
+    if t.Scheduled != nil {
+      if t.IsDeferred(now) {
+        fmt.Printf("    💤 Scheduled: %s (hidden until then)\n", t.ScheduledString(loc))
+      } else if !t.IsDone() {
+        fmt.Printf("    🛫 Scheduled: %s\n", t.ScheduledString(loc))
+      }
+    }
+
+    // Show estimate
+    if t.Estimate > 0 {
+      fmt.Printf("    📐 Estimate: %s\n", t.Estimate)
+    }
+
     // Show tags
     if len(t.Tags) > 0 {
       fmt.Printf("    🏷️  ")
@@ -166,27 +350,70 @@ func RunList(showAll, today, week, detailed bool, grep, tags, sortKey, before, a
       fmt.Println()
     }
 
+    // Show project
+    if t.Project != "" {
+      fmt.Printf("    📁 Project: %s\n", t.Project)
+    }
+
     // Show repeat info
     if t.Repeat != "" {
-      fmt.Printf("    🔄 Repeats: %s\n", t.Repeat)
+      fmt.Printf("    🔄 Repeats: %s%s\n", core.DescribeRepeat(t.Repeat), describeRepeatPolicy(t))
+    }
+
+    // Show subtask relations
+    if t.ParentID != 0 {
+      fmt.Printf("    ↳ Subtask of #%d\n", t.ParentID)
+    }
+    if done, total := core.Progress(allTasks, t.ID); total > 0 {
+      fmt.Printf("    📂 Subtasks: %d/%d done\n", done, total)
     }
 
     // Show dependencies
     if len(t.DependsOn) > 0 {
-      fmt.Printf("    🔗 Depends on: %v\n", t.DependsOn)
+      fmt.Printf("    🔗 Depends on: %s\n", formatIDs(t.DependsOn))
       if !core.AllDependenciesMet(allTasks, t) {
         fmt.Printf("    ⚠️  BLOCKED (dependencies not met)\n")
       }
     }
 
     // Show creation date in detailed view
-    if detailed {
-      fmt.Printf("    🕐 Created: %s\n", t.CreatedAt.Format("2006-01-02 15:04"))
+    if v.Detailed {
+      fmt.Printf("    🕐 Created: %s\n", t.CreatedAt.In(loc).Format("2006-01-02 15:04"))
+    }
+
+    // Show urgency in detailed view, with what it is made of
+    if u := urgency[t.ID]; u.Score != 0 {
+      parts := make([]string, len(u.Factors))
+      for i, f := range u.Factors {
+        parts[i] = fmt.Sprintf("%s %+.1f", f.Name, f.Score)
+      }
+      fmt.Printf("    🔥 Urgency: %.1f (%s)\n", u.Score, strings.Join(parts, ", "))
+    }
+
+    // Show time spent in detailed view, and a running timer always
+    if t.TimerRunning() {
+      fmt.Printf("    ⏱️  Time: %s (timer running)\n", core.FormatTimeSpent(t.TimeSpent(now)))
+    } else if v.Detailed && len(t.TimeEntries) > 0 {
+      fmt.Printf("    ⏱️  Time: %s\n", core.FormatTimeSpent(t.TimeSpent(now)))
+    }
+
+    // Show where the task is in its lifecycle
+    if since, ok := t.StatusSince(); ok {
+      switch t.State() {
+      case core.StatusInProgress:
+        fmt.Printf("    🚧 In progress since %s\n", since.In(loc).Format("2006-01-02 15:04"))
+      case core.StatusWaiting:
+        fmt.Printf("    ⏳ Waiting since %s\n", since.In(loc).Format("2006-01-02 15:04"))
+      case core.StatusBlocked:
+        fmt.Printf("    ⛔ Blocked since %s\n", since.In(loc).Format("2006-01-02 15:04"))
+      }
     }
 
     // Show completion date if done
-    if t.IsDone() && t.DoneAt != nil {
-      fmt.Printf("    ✅ Completed: %s\n", t.DoneAt.Format("2006-01-02 15:04"))
+    if t.State() == core.StatusCancelled && !t.Skipped && t.DoneAt != nil {
+      fmt.Printf("    🚫 Cancelled: %s\n", t.DoneAt.In(loc).Format("2006-01-02 15:04"))
+    } else if t.State() == core.StatusDone && t.DoneAt != nil {
+      fmt.Printf("    ✅ Completed: %s\n", t.DoneAt.In(loc).Format("2006-01-02 15:04"))
     }
   }
 
@@ -194,49 +421,321 @@ func RunList(showAll, today, week, detailed bool, grep, tags, sortKey, before, a
   fmt.Printf("Total: %d task(s)\n", len(visible))
 }
 
-// RunDone marks a task as complete
-func RunDone(indexStr string) {
-  idx, err := core.Atoi1(indexStr)
-  must(err)
+// printTree prints tasks as an indented tree with each parent's progress
+func printTree(roots []*core.TreeNode, allTasks []core.Task, now time.Time) {
+  n := 0
+  var walk func(nodes []*core.TreeNode, prefix string, top bool)
+  walk = func(nodes []*core.TreeNode, prefix string, top bool) {
+    for i, node := range nodes {
+      t := node.Task
+      n++
+
+      branch, indent := "", ""
+      if !top {
+        if i == len(nodes)-1 {
+          branch, indent = "└── ", "    "
+        } else {
+          branch, indent = "├── ", "│   "
+        }
+      }
+
+      line := fmt.Sprintf("%2d. %s%s#%d [%s] %s", n, prefix, branch, t.ID, statusMark(t), t.Title)
+      if done, total := core.Progress(allTasks, t.ID); total > 0 {
+        line += fmt.Sprintf(" (%d/%d)", done, total)
+      }
+      if t.Due != nil {
+        line += " 📅 " + t.DueString(now.Location())
+        if t.IsOverdue(now) {
+          line += " (OVERDUE!)"
+        }
+      }
+      fmt.Println(line)
+
+      walk(node.Children, prefix+indent, false)
+    }
+  }
+  walk(roots, "", true)
+}
+
+// RunDone marks one or more tasks as complete
+func RunDone(arg string, byIndex bool) {
+  ids := resolveIDs(arg, byIndex)
 
   svc := getService()
-  visible, err := svc.QueryTasks(context.Background(), service.Query{ShowAll: false, SortKey: "due"})
-  must(err)
-  if idx < 1 || idx > len(visible) { log.Fatal("Invalid index") }
-  updated, err := svc.MarkDone(context.Background(), visible, idx)
-  must(err)
-  fmt.Println("Marked done:", visible[idx-1].Title)
-  if updated.Repeat != "" {
-    fmt.Println("Created next occurrence")
+  failed := false
+  for _, id := range ids {
+    updated, err := svc.MarkDoneByID(context.Background(), id)
+    if err != nil {
+      log.Printf("Error: task %d: %v", id, err)
+      failed = true
+      continue
+    }
+    fmt.Printf("Marked done: %s (ID: %d)\n", updated.Title, updated.ID)
+    if updated.Repeat != "" {
+      fmt.Println("Created next occurrence")
+    }
+  }
+  if failed {
+    os.Exit(1)
   }
 }
 
-// RunRemove removes a task
-func RunRemove(indexStr string) {
-  idx, err := core.Atoi1(indexStr)
-  must(err)
+// statusCommands maps the start, wait and cancel commands to the status they
+// move tasks to
+var statusCommands = map[string]core.Status{
+  "start":  core.StatusInProgress,
+  "wait":   core.StatusWaiting,
+  "cancel": core.StatusCancelled,
+}
+
+// RunStatus moves one or more tasks to a new status
+func RunStatus(arg string, byIndex bool, status core.Status) {
+  ids := resolveIDs(arg, byIndex)
+
+  svc := getService()
+  failed := false
+  for _, id := range ids {
+    updated, err := svc.SetStatus(context.Background(), id, status)
+    if err != nil {
+      log.Printf("Error: task %d: %v", id, err)
+      failed = true
+      continue
+    }
+    switch status {
+    case core.StatusInProgress:
+      fmt.Printf("Started: %s (ID: %d)\n", updated.Title, updated.ID)
+    case core.StatusWaiting:
+      fmt.Printf("Waiting: %s (ID: %d)\n", updated.Title, updated.ID)
+    case core.StatusCancelled:
+      fmt.Printf("Cancelled: %s (ID: %d)\n", updated.Title, updated.ID)
+    }
+  }
+  if failed {
+    os.Exit(1)
+  }
+}
+
+// RunRemove removes one or more tasks. Tasks other open tasks depend on are
+// handled according to the dependency policy (fail, cascade or rewrite).
+func RunRemove(arg string, byIndex bool, deps string, permanent bool) {
+  ids := resolveIDs(arg, byIndex)
+  if deps == "" && !permanent {
+    deps = string(core.RemoveKeep)
+  }
+  policy, err := core.ParseRemovePolicy(deps)
+  if err != nil {
+    log.Fatalf("Invalid -deps: %v", err)
+  }
+  if permanent && policy == core.RemoveKeep {
+    log.Fatal("Invalid -deps: keep only applies when moving tasks to the trash")
+  }
+
+  svc := getService()
+  remove := func(id int) ([]core.Task, []core.Task, error) {
+    if permanent {
+      removed, err := svc.DeleteTask(context.Background(), id, policy)
+      return removed, nil, err
+    }
+    return svc.TrashTask(context.Background(), id, policy)
+  }
+  verb, depVerb := "Moved to trash", "Moved dependent to trash"
+  if permanent {
+    verb, depVerb = "Removed", "Removed dependent"
+  }
+
+  failed := false
+  gone := make(map[int]bool)
+
+  // Tasks blocked only by dependents that are removed later in the same
+  // command are retried until no more progress is made
+  pending := ids
+  for len(pending) > 0 {
+    var retry []int
+    var retryErrs []error
+    for _, id := range pending {
+      if gone[id] {
+        continue
+      }
+      removed, waiting, err := remove(id)
+      var dependents *core.DependentsError
+      if errors.As(err, &dependents) {
+        retry = append(retry, id)
+        retryErrs = append(retryErrs, err)
+        continue
+      }
+      if err != nil {
+        log.Printf("Error: %v", err)
+        failed = true
+        continue
+      }
+      for i, t := range removed {
+        gone[t.ID] = true
+        if i == 0 {
+          fmt.Printf("%s: %s (ID: %d)\n", verb, t.Title, t.ID)
+        } else {
+          fmt.Printf("%s: %s (ID: %d)\n", depVerb, t.Title, t.ID)
+        }
+      }
+      for _, t := range waiting {
+        fmt.Printf("⚠️  %s (ID: %d) depends on it and stays blocked until it is restored\n", t.Title, t.ID)
+      }
+    }
+
+    if len(retry) == len(pending) {
+      for _, err := range retryErrs {
+        log.Printf("Error: %v", err)
+      }
+      failed = true
+      break
+    }
+    pending = retry
+  }
 
+  if failed {
+    os.Exit(1)
+  }
+}
+
+// RunTrashList shows the tasks in the trash, most recently deleted first
+func RunTrashList() {
   svc := getService()
-  visible, err := svc.QueryTasks(context.Background(), service.Query{ShowAll: true, SortKey: "due"})
+  trashed, err := svc.Trash(context.Background())
   must(err)
-  if idx < 1 || idx > len(visible) { log.Fatal("Invalid index") }
-  taskTitle := visible[idx-1].Title
-  must(svc.RemoveTask(context.Background(), visible, idx))
-  fmt.Println("Removed:", taskTitle)
+  if len(trashed) == 0 {
+    fmt.Println("The trash is empty")
+    return
+  }
+
+  loc := svc.Now().Location()
+  retention := svc.TrashRetention()
+  for _, t := range trashed {
+    deleted := t.DeletedAt.In(loc)
+    fmt.Printf("#%-3d %s  (deleted %s", t.ID, t.Title, deleted.Format("2006-01-02 15:04"))
+    if retention > 0 {
+      fmt.Printf(", purged after %s", deleted.Add(retention).Format("2006-01-02"))
+    }
+    fmt.Println(")")
+  }
 }
 
-// RunEdit edits an existing task
-func RunEdit(indexStr string, title, description, dueStr, repeat string, priority int, tags, after string) {
-  idx, err := core.Atoi1(indexStr)
+// RunTrashRestore takes tasks out of the trash
+func RunTrashRestore(arg string) {
+  ids := resolveIDs(arg, false)
+
+  svc := getService()
+  failed := false
+  for _, id := range ids {
+    t, err := svc.RestoreTask(context.Background(), id)
+    if err != nil {
+      log.Printf("Error: %v", err)
+      failed = true
+      continue
+    }
+    fmt.Printf("Restored: %s (ID: %d)\n", t.Title, t.ID)
+  }
+  if failed {
+    os.Exit(1)
+  }
+}
+
+// RunTrashEmpty removes every task in the trash for good
+func RunTrashEmpty() {
+  purged, err := getService().EmptyTrash(context.Background())
   must(err)
+  switch len(purged) {
+  case 0:
+    fmt.Println("The trash is already empty")
+  case 1:
+    fmt.Println("Removed 1 task for good")
+  default:
+    fmt.Printf("Removed %d tasks for good\n", len(purged))
+  }
+}
+
+// RunSkip skips one or more occurrences of recurring tasks
+func RunSkip(arg string, byIndex bool) {
+  ids := resolveIDs(arg, byIndex)
 
   svc := getService()
-  visible, err := svc.QueryTasks(context.Background(), service.Query{ShowAll: true, SortKey: "due"})
+  loc := svc.Now().Location()
+  failed := false
+  for _, id := range ids {
+    next, err := svc.SkipByID(context.Background(), id)
+    if err != nil {
+      log.Printf("Error: task %d: %v", id, err)
+      failed = true
+      continue
+    }
+    if next == nil {
+      fmt.Printf("Skipped #%d; the series has ended\n", id)
+    } else if next.Due != nil {
+      fmt.Printf("Skipped #%d; next occurrence #%d due %s\n", id, next.ID, next.DueString(loc))
+    } else {
+      fmt.Printf("Skipped #%d; next occurrence #%d\n", id, next.ID)
+    }
+  }
+  if failed {
+    os.Exit(1)
+  }
+}
+
+// RunSeries shows the occurrences of a recurring task, or ends the series
+func RunSeries(arg string, byIndex, stop bool) {
+  ids := resolveIDs(arg, byIndex)
+  if len(ids) != 1 {
+    log.Fatal("Error: series takes a single task")
+  }
+
+  svc := getService()
+  if stop {
+    task, err := svc.StopSeries(context.Background(), ids[0])
+    must(err)
+    fmt.Printf("Stopped series: %s (ID: %d)\n", task.Title, task.ID)
+    return
+  }
+
+  series, err := svc.SeriesOf(context.Background(), ids[0])
   must(err)
-  if idx < 1 || idx > len(visible) {
-    log.Fatalf("Invalid index: %d", idx)
+  loc := svc.Now().Location()
+
+  last := series[len(series)-1]
+  fmt.Printf("%s\n", last.Title)
+  if last.Repeat != "" {
+    fmt.Printf("🔄 Repeats: %s%s\n", core.DescribeRepeat(last.Repeat), describeRepeatPolicy(last))
+  } else {
+    fmt.Println("🔄 Series ended")
   }
-  targetID := visible[idx-1].ID
+  fmt.Print(strings.Repeat("=", 50), "\n")
+
+  for _, t := range series {
+    due := "(no due date)"
+    if t.Due != nil {
+      due = t.DueString(loc)
+    }
+    state := "open"
+    switch {
+    case t.Skipped:
+      state = "skipped"
+    case t.IsDone() && t.DoneAt != nil:
+      state = "done " + t.DoneAt.In(loc).Format("2006-01-02 15:04")
+    }
+    edited := ""
+    if t.Pattern != nil {
+      edited = " (edited)"
+    }
+    fmt.Printf("#%-4d %-22s %s%s\n", t.ID, due, state, edited)
+  }
+}
+
+// RunEdit edits an existing task
+func RunEdit(arg string, byIndex bool, title, description, dueStr, scheduledStr, estimateStr, repeat, repeatFrom string, skipMissed *bool, priority int, tags, after, parent string, autoComplete *bool, future bool, status, project string) {
+  ids := resolveIDs(arg, byIndex)
+  if len(ids) != 1 {
+    log.Fatal("Error: edit takes a single task")
+  }
+  targetID := ids[0]
+
+  svc := getService()
 
   var titlePtr *string
   if title != "" { titlePtr = &title }
@@ -247,7 +746,22 @@ func RunEdit(indexStr string, title, description, dueStr, repeat string, priorit
   }
 
   var duePtr *string
-  if dueStr != "" { duePtr = &dueStr }
+  if dueStr != "" {
+    if dueStr == "none" { empty := ""; duePtr = &empty } else { duePtr = &dueStr }
+  }
+
+  var scheduledPtr *string
+  if scheduledStr != "" {
+    if scheduledStr == "none" { empty := ""; scheduledPtr = &empty } else { scheduledPtr = &scheduledStr }
+  }
+
+  var estimatePtr *string
+  if estimateStr != "" {
+    if estimateStr == "none" { empty := ""; estimatePtr = &empty } else { estimatePtr = &estimateStr }
+  }
+
+  var fromPtr *string
+  if repeatFrom != "" { fromPtr = &repeatFrom }
 
   var prioPtr *int
   if priority > 0 { prioPtr = &priority }
@@ -257,6 +771,24 @@ func RunEdit(indexStr string, title, description, dueStr, repeat string, priorit
     if tags == "none" { empty := []string{}; tagsPtr = &empty } else { v := core.ParseTags(tags); tagsPtr = &v }
   }
 
+  var parentPtr *int
+  if parent != "" {
+    if parent == "none" {
+      zero := 0
+      parentPtr = &zero
+    } else {
+      id, err := strconv.Atoi(strings.TrimPrefix(parent, "#"))
+      if err != nil { log.Fatalf("Invalid -parent: %q is not a task ID", parent) }
+      parentPtr = &id
+    }
+  }
+
+  var statusPtr *string
+  if status != "" { statusPtr = &status }
+
+  var projectPtr *string
+  if project != "" { projectPtr = &project }
+
   var depsPtr *[]int
   if after != "" {
     if after == "none" { empty := []int{}; depsPtr = &empty } else { v := core.ParseIDs(after); depsPtr = &v }
@@ -266,37 +798,163 @@ func RunEdit(indexStr string, title, description, dueStr, repeat string, priorit
     Title:       titlePtr,
     Description: descPtr,
     Due:         duePtr,
+    Scheduled:   scheduledPtr,
     Priority:    prioPtr,
+    Estimate:    estimatePtr,
     Tags:        tagsPtr,
     Repeat:      func() *string { if repeat == "" { return nil }; if repeat == "none" { empty := ""; return &empty }; return &repeat }(),
+    RepeatFrom:  fromPtr,
+    SkipMissed:  skipMissed,
     DependsOn:   depsPtr,
+    ParentID:    parentPtr,
+    AutoComplete: autoComplete,
+    Scope:       func() core.EditScope { if future { return core.ScopeFuture }; return core.ScopeThis }(),
+    Status:      statusPtr,
+    Project:     projectPtr,
   })
   must(err)
   fmt.Println("Updated:", updated.Title)
 }
 
+// RunNext recommends the most important tasks that can be worked on now, or
+// with a target, shows the critical path leading to it
+func RunNext(n int, target, project string) {
+  svc := getService()
+  now := svc.Now()
+  loc := now.Location()
+
+  if target != "" {
+    ids := resolveIDs(target, false)
+    if len(ids) != 1 {
+      log.Fatal("Error: -for takes a single task")
+    }
+    cp, err := svc.CriticalPath(context.Background(), ids[0])
+    must(err)
+    if len(cp.Tasks) == 0 {
+      fmt.Printf("Task #%d is already done\n", ids[0])
+      return
+    }
+
+    fmt.Printf("Critical path to #%d (%d task(s), about %s of work):\n", ids[0], len(cp.Tasks), cp.Duration)
+    for i, t := range cp.Tasks {
+      fmt.Printf("%2d. #%-3d %s\n", i+1, t.ID, t.Title)
+    }
+    if cp.HasDue {
+      if cp.Slack < 0 {
+        fmt.Printf("⏰ Earliest finish %s is %s past the deadline\n", cp.Finish.In(loc).Format("2006-01-02 15:04"), (-cp.Slack).Round(time.Minute))
+      } else {
+        fmt.Printf("📅 Earliest finish %s, %s before the deadline\n", cp.Finish.In(loc).Format("2006-01-02 15:04"), cp.Slack.Round(time.Minute))
+      }
+    }
+    return
+  }
+
+  recs, err := svc.Recommend(context.Background(), n, project)
+  must(err)
+  if len(recs) == 0 {
+    fmt.Println("Nothing to do: no open task is ready to start")
+    return
+  }
+
+  for i, rec := range recs {
+    t := rec.Task
+    fmt.Printf("%2d. #%-3d %s\n", i+1, t.ID, t.Title)
+    if t.Due != nil {
+      fmt.Printf("    📅 Due: %s\n", t.DueString(loc))
+    }
+    if len(rec.Reasons) > 0 {
+      fmt.Printf("    💡 %s\n", strings.Join(rec.Reasons, ", "))
+    }
+  }
+}
+
+// RunSearch prints the tasks matching a search, most relevant first
+func RunSearch(query string, open bool, project string, limit int, detailed bool) {
+  svc := getService()
+  loc := svc.Now().Location()
+
+  results, err := svc.Search(context.Background(), query, service.SearchOptions{Open: open, Project: project, Limit: limit})
+  must(err)
+
+  // Remember what was shown so "-index" addressing refers to these results
+  shown := make([]core.Task, 0, len(results))
+  for _, r := range results {
+    shown = append(shown, r.Task)
+  }
+  if err := saveLastView(shown); err != nil {
+    log.Printf("Warning: could not save list view: %v", err)
+  }
+
+  if len(results) == 0 {
+    fmt.Println("(no matches)")
+    return
+  }
+
+  for i, r := range results {
+    t := r.Task
+    fmt.Printf("%2d. #%-3d [%s] %s  (%.2f, %s)\n", i+1, t.ID, statusMark(t), t.Title, r.Score, strings.Join(r.Fields, ", "))
+    if !detailed {
+      continue
+    }
+    if t.Description != "" {
+      fmt.Printf("    📝 %s\n", t.Description)
+    }
+    if len(t.Tags) > 0 {
+      fmt.Printf("    🏷️  #%s\n", strings.Join(t.Tags, " #"))
+    }
+    if t.Project != "" {
+      fmt.Printf("    📁 Project: %s\n", t.Project)
+    }
+    if t.DoneAt != nil {
+      fmt.Printf("    ✓ Done: %s\n", t.DoneAt.In(loc).Format("2006-01-02 15:04"))
+    }
+  }
+  if limit > 0 && len(results) == limit {
+    fmt.Printf("\nShowing the first %d match(es); use -n 0 for all\n", limit)
+  }
+}
+
+// RunGraph prints the dependency graph
+func RunGraph(format, tags, status, project string) {
+  f, err := core.ParseGraphFormat(format)
+  if err != nil {
+    log.Fatalf("Invalid -format: %v", err)
+  }
+
+  svc := getService()
+  graph, err := svc.Graph(context.Background(), f, tags, status, project)
+  must(err)
+  if graph == "" {
+    fmt.Println("(no tasks)")
+    return
+  }
+  fmt.Print(graph)
+}
+
 // RunAlerts shows alerts for due/overdue tasks
-func RunAlerts(watch bool, interval, ahead time.Duration) {
+func RunAlerts(watch bool, interval, ahead time.Duration, project string) {
   svc := getService()
   notifier := notifications.NewSystemNotifier(true)
   scanner := alerts.NewScanner(notifier)
+  scanner.SetClock(svc)
 
   if watch {
     // Watch mode with continuous monitoring
     loadFunc := func() ([]core.Task, error) {
-      return svc.QueryTasks(context.Background(), service.Query{ShowAll: true, SortKey: "due"})
+      return svc.QueryTasks(context.Background(), service.Query{ShowAll: true, SortKey: "due", Project: project})
     }
 
-    tasks, err := svc.QueryTasks(context.Background(), service.Query{ShowAll: true, SortKey: "due"})
+    tasks, err := svc.QueryTasks(context.Background(), service.Query{ShowAll: true, SortKey: "due", Project: project})
     must(err)
 
     scanner.Watch(tasks, interval, ahead, loadFunc)
   } else {
     // One-time scan
-    tasks, err := svc.QueryTasks(context.Background(), service.Query{ShowAll: true, SortKey: "due"})
+    tasks, err := svc.QueryTasks(context.Background(), service.Query{ShowAll: true, SortKey: "due", Project: project})
     must(err)
 
-    alertList := scanner.Scan(tasks, time.Now(), ahead)
+    now := svc.Now()
+    alertList := scanner.Scan(tasks, now, ahead)
 
     if len(alertList) == 0 {
       fmt.Println("No alerts")
@@ -305,9 +963,9 @@ func RunAlerts(watch bool, interval, ahead time.Duration) {
 
     fmt.Printf("=== Alerts ===\n\n")
     for _, alert := range alertList {
-      fmt.Printf("[%s] %s\n", alert.Type, alert.Message)
+      fmt.Printf("[%s] #%d %s\n", alert.Type, alert.Task.ID, alert.Message)
       if alert.Task.Due != nil {
-        fmt.Printf("  Due: %s\n", alert.Task.Due.Format("2006-01-02 15:04"))
+        fmt.Printf("  Due: %s\n", alert.Task.DueString(now.Location()))
       }
       if alert.Task.Priority > 1 {
         fmt.Printf("  Priority: %d\n", alert.Task.Priority)
@@ -317,31 +975,384 @@ func RunAlerts(watch bool, interval, ahead time.Duration) {
   }
 }
 
-// RunStats shows task statistics
-func RunStats() {
+// RunStats shows task statistics for a project
+func RunStats(project string) {
   svc := getService()
-  tasks, err := svc.QueryTasks(context.Background(), service.Query{ShowAll: true, SortKey: "due"})
+  stats, err := svc.Stats(context.Background(), project)
   must(err)
-  fmt.Print(core.StatsReport(tasks, time.Now()))
+  fmt.Print(core.FormatStats(stats))
+}
+
+// RunProjects lists the projects that have tasks, with their progress
+func RunProjects() {
+  svc := getService()
+  projects, err := svc.Projects(context.Background())
+  must(err)
+  if len(projects) == 0 {
+    fmt.Println("(no tasks)")
+    return
+  }
+
+  fmt.Printf("%-20s %6s %6s %8s %6s\n", "PROJECT", "OPEN", "DONE", "OVERDUE", "RATE")
+  for _, p := range projects {
+    name := p.Name
+    if name == "" {
+      name = "(none)"
+    }
+    if p.Name != "" && p.Name == svc.DefaultProject() {
+      name += " *"
+    }
+    fmt.Printf("%-20s %6d %6d %8d %5.0f%%\n", name, p.Pending, p.Completed, p.Overdue, p.CompletionRate)
+  }
+  if svc.DefaultProject() != "" {
+    fmt.Println("\n* default project")
+  }
+}
+
+// RunUndo undoes the last n operations, or redoes the last n undone ones
+func RunUndo(n int, redo bool) {
+  svc := getService()
+  step, verb, none := svc.Undo, "Undone", core.ErrNothingToUndo
+  if redo {
+    step, verb, none = svc.Redo, "Redone", core.ErrNothingToRedo
+  }
+
+  for i := 0; i < n; i++ {
+    op, err := step(context.Background())
+    if errors.Is(err, none) {
+      if i == 0 {
+        log.Fatalf("Error: %v", err)
+      }
+      fmt.Println(strings.ToUpper(err.Error()[:1]) + err.Error()[1:])
+      return
+    }
+    must(err)
+    fmt.Printf("%s: %s\n", verb, op.Summary())
+    if !redo {
+      op = op.Inverse()
+    }
+    for _, line := range op.Describe() {
+      fmt.Printf("    %s\n", line)
+    }
+  }
+}
+
+// RunUndoList shows the recorded operations, newest first
+func RunUndoList() {
+  svc := getService()
+  ops, err := svc.UndoHistory(context.Background())
+  must(err)
+  if len(ops) == 0 {
+    fmt.Println("(nothing to undo)")
+    return
+  }
+
+  loc := svc.Now().Location()
+  for i := len(ops) - 1; i >= 0; i-- {
+    op := ops[i]
+    mark := " "
+    if op.Undone {
+      mark = "↶" // can be redone
+    }
+    fmt.Printf("%s %4d  %s  %s\n", mark, op.Seq, op.At.In(loc).Format("2006-01-02 15:04"), op.Summary())
+  }
+}
+
+// RunHistory shows the audit log of a task: every change made to it, by whom
+// and from where, oldest first
+func RunHistory(arg string, byIndex bool) {
+  ids := resolveIDs(arg, byIndex)
+  if len(ids) != 1 {
+    log.Fatal("Error: history takes a single task")
+  }
+
+  svc := getService()
+  entries, err := svc.TaskHistory(context.Background(), ids[0])
+  must(err)
+  loc := svc.Now().Location()
+
+  var title string
+  if t, err := svc.GetTask(context.Background(), ids[0]); err == nil {
+    title = t.Title
+  } else {
+    // A removed task: use the last title it had
+    for _, e := range entries {
+      for _, f := range e.Fields {
+        if f.Field == "title" && f.New != nil {
+          _ = json.Unmarshal(f.New, &title)
+        }
+      }
+    }
+  }
+  fmt.Printf("History of #%d %q\n", ids[0], title)
+  fmt.Print(strings.Repeat("=", 50), "\n")
+  if len(entries) == 0 {
+    fmt.Println("(no recorded changes)")
+    return
+  }
+
+  for _, e := range entries {
+    fmt.Printf("%s  %s (%s)\n", e.At.In(loc).Format("2006-01-02 15:04"), e.Action, e.Origin)
+    switch e.Event {
+    case core.AuditCreated:
+      for _, f := range e.Fields {
+        if f.Field == "created_at" {
+          continue
+        }
+        fmt.Printf("    + %s: %s\n", f.Field, formatAuditValue(f.New, loc))
+      }
+    case core.AuditRemoved:
+      fmt.Println("    removed")
+    default:
+      for _, f := range e.Fields {
+        fmt.Printf("    %s: %s → %s\n", f.Field, formatAuditValue(f.Old, loc), formatAuditValue(f.New, loc))
+      }
+    }
+  }
+}
+
+// formatAuditValue shows a field value from the audit log: times in the
+// configured zone, lists of records by their count, and "—" for no value
+func formatAuditValue(raw json.RawMessage, loc *time.Location) string {
+  var v interface{}
+  if raw == nil || json.Unmarshal(raw, &v) != nil {
+    return "—"
+  }
+  switch v := v.(type) {
+  case string:
+    if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+      t = t.In(loc)
+      if t.Hour() == 0 && t.Minute() == 0 && t.Second() == 0 {
+        return t.Format("2006-01-02")
+      }
+      return t.Format("2006-01-02 15:04")
+    }
+    return strconv.Quote(v)
+  case []interface{}:
+    if len(v) > 0 {
+      if _, ok := v[0].(map[string]interface{}); ok && len(v) == 1 {
+        return "1 entry"
+      } else if ok {
+        return fmt.Sprintf("%d entries", len(v))
+      }
+    }
+  case map[string]interface{}:
+    return "{…}"
+  }
+  return string(raw)
+}
+
+// RunMove moves tasks, with their subtasks, to a project
+func RunMove(arg string, byIndex bool, project string) {
+  ids := resolveIDs(arg, byIndex)
+
+  svc := getService()
+  failed := false
+  for _, id := range ids {
+    moved, err := svc.MoveToProject(context.Background(), id, project)
+    if err != nil {
+      log.Printf("Error: task %d: %v", id, err)
+      failed = true
+      continue
+    }
+    if moved.Project == "" {
+      fmt.Printf("Moved: %s (ID: %d) out of its project\n", moved.Title, moved.ID)
+    } else {
+      fmt.Printf("Moved: %s (ID: %d) to project %s\n", moved.Title, moved.ID, moved.Project)
+    }
+  }
+  if failed {
+    os.Exit(1)
+  }
+}
+
+// RunTimer starts the timer on a task, stops the running timer, or shows
+// which task it is running on
+func RunTimer(action, arg string, byIndex bool) {
+  svc := getService()
+  ctx := context.Background()
+
+  switch action {
+  case "start":
+    ids := resolveIDs(arg, byIndex)
+    if len(ids) != 1 {
+      log.Fatal("Error: the timer runs on one task at a time")
+    }
+    task, err := svc.StartTimer(ctx, ids[0])
+    must(err)
+    fmt.Printf("Timer started: %s (ID: %d)\n", task.Title, task.ID)
+
+  case "stop":
+    task, err := svc.StopTimer(ctx, 0)
+    must(err)
+    last := task.TimeEntries[len(task.TimeEntries)-1]
+    fmt.Printf("Timer stopped: %s (ID: %d) after %s, %s in total\n",
+      task.Title, task.ID, core.FormatTimeSpent(last.Duration(svc.Now())), core.FormatTimeSpent(task.TimeSpent(svc.Now())))
+
+  case "status":
+    task, err := svc.RunningTimer(ctx)
+    must(err)
+    if task == nil {
+      fmt.Println("No timer running")
+      return
+    }
+    now := svc.Now()
+    last := task.TimeEntries[len(task.TimeEntries)-1]
+    fmt.Printf("Timer running: %s (ID: %d) for %s since %s, %s in total\n",
+      task.Title, task.ID, core.FormatTimeSpent(last.Duration(now)),
+      last.Start.In(now.Location()).Format("15:04"), core.FormatTimeSpent(task.TimeSpent(now)))
+
+  default:
+    log.Fatalf("Error: unknown timer action %q (use start, stop or status)", action)
+  }
+}
+
+// RunLog records time spent on a task without running the timer
+func RunLog(arg string, byIndex bool, durationStr, note string) {
+  ids := resolveIDs(arg, byIndex)
+  if len(ids) != 1 {
+    log.Fatal("Error: time is logged on one task at a time")
+  }
+  d, err := core.ParseTimeSpent(durationStr)
+  must(err)
+
+  svc := getService()
+  task, err := svc.LogTime(context.Background(), ids[0], d, note)
+  must(err)
+  fmt.Printf("Logged %s on %s (ID: %d), %s in total\n",
+    core.FormatTimeSpent(d), task.Title, task.ID, core.FormatTimeSpent(task.TimeSpent(svc.Now())))
 }
 
 // RunServer starts the HTTP API server
 func RunServer(host string, port int) {
-  s := getStore()
-  srv := server.NewServer(host, port, s)
+  srv := server.NewServer(host, port, getService())
 
   fmt.Printf("Starting HTTP server on %s:%d\n", host, port)
   fmt.Println("Press Ctrl+C to stop")
   fmt.Println("\nEndpoints:")
-  fmt.Println("  GET    /tasks          - List all tasks")
-  fmt.Println("  POST   /tasks          - Create a task")
-  fmt.Println("  GET    /tasks/:id      - Get a task")
-  fmt.Println("  PUT    /tasks/:id      - Update a task")
-  fmt.Println("  DELETE /tasks/:id      - Delete a task")
-  fmt.Println("  POST   /tasks/:id/done - Mark task as done")
-  fmt.Println("  GET    /stats          - Get statistics")
-  fmt.Println("  GET    /health         - Health check")
+  fmt.Println("  GET    /tasks                   - List all tasks")
+  fmt.Println("  POST   /tasks                   - Create a task")
+  fmt.Println("  GET    /tasks/next              - List actionable tasks")
+  fmt.Println("  GET    /tasks/:id               - Get a task")
+  fmt.Println("  PUT    /tasks/:id               - Update a task")
+  fmt.Println("  DELETE /tasks/:id               - Move a task to the trash")
+  fmt.Println("  POST   /tasks/:id/done          - Mark task as done")
+  fmt.Println("  POST   /tasks/:id/start         - Start a task")
+  fmt.Println("  POST   /tasks/:id/wait          - Mark a task as waiting")
+  fmt.Println("  POST   /tasks/:id/cancel        - Cancel a task")
+  fmt.Println("  GET    /tasks/:id/children      - List subtasks")
+  fmt.Println("  GET    /tasks/:id/series        - List a recurring series")
+  fmt.Println("  POST   /tasks/:id/skip          - Skip an occurrence")
+  fmt.Println("  POST   /tasks/:id/stop          - Stop a recurring series")
+  fmt.Println("  GET    /tasks/:id/critical-path - Get the critical path")
+  fmt.Println("  GET    /tasks/:id/time          - List time entries")
+  fmt.Println("  POST   /tasks/:id/time          - Log time")
+  fmt.Println("  POST   /tasks/:id/time/start    - Start the timer")
+  fmt.Println("  POST   /tasks/:id/time/stop     - Stop the timer")
+  fmt.Println("  GET    /tasks/:id/history       - Get the change history")
+  fmt.Println("  GET    /trash                   - List the trash")
+  fmt.Println("  POST   /trash/:id/restore       - Restore a task")
+  fmt.Println("  DELETE /trash                   - Empty the trash")
+  fmt.Println("  GET    /graph                   - Export the dependency graph")
+  fmt.Println("  GET    /projects                - List projects")
+  fmt.Println("  GET    /projects/:name/tasks    - List project tasks")
+  fmt.Println("  POST   /projects/:name/tasks    - Create a project task")
+  fmt.Println("  GET    /projects/:name/stats    - Get project statistics")
+  fmt.Println("  GET    /views                   - List saved views")
+  fmt.Println("  GET    /views/:name             - Get a saved view")
+  fmt.Println("  PUT    /views/:name             - Save a view")
+  fmt.Println("  DELETE /views/:name             - Delete a saved view")
+  fmt.Println("  GET    /search                  - Search tasks")
+  fmt.Println("  GET    /undo                    - Get the undo history")
+  fmt.Println("  POST   /undo                    - Undo the last change")
+  fmt.Println("  POST   /redo                    - Redo the last undone change")
+  fmt.Println("  GET    /stats                   - Get statistics")
+  fmt.Println("  GET    /health                  - Health check")
   fmt.Println()
 
   must(srv.Start())
 }
+
+// loadView returns the saved view with the given name, exiting if there is
+// none
+func loadView(name string) core.View {
+  v, err := getService().GetView(context.Background(), name)
+  must(err)
+  return v
+}
+
+// RunViewSave saves a view, replacing any view of the same name
+func RunViewSave(v core.View) {
+  svc := getService()
+  _, existsErr := svc.GetView(context.Background(), v.Name)
+  saved, err := svc.SaveView(context.Background(), v)
+  must(err)
+  if existsErr == nil {
+    fmt.Printf("Updated view: %s\n", saved.Name)
+  } else {
+    fmt.Printf("Saved view: %s\n", saved.Name)
+  }
+}
+
+// RunViews lists the saved views with the list options they stand for
+func RunViews() {
+  views, err := getService().Views(context.Background())
+  must(err)
+  if len(views) == 0 {
+    fmt.Println("(no saved views; create one with \"godoit view save <name> [list options]\")")
+    return
+  }
+
+  for _, v := range views {
+    args := viewArgs(v)
+    if args == "" {
+      args = "(all open tasks)"
+    }
+    fmt.Printf("%-20s %s\n", v.Name, args)
+  }
+}
+
+// RunViewShow prints the list command a view stands for
+func RunViewShow(name string) {
+  v := loadView(name)
+  fmt.Printf("godoit list %s\n", viewArgs(v))
+}
+
+// RunViewDelete removes a saved view
+func RunViewDelete(name string) {
+  must(getService().DeleteView(context.Background(), name))
+  fmt.Printf("Deleted view: %s\n", strings.ToLower(name))
+}
+
+// viewArgs formats a view as the list options it stands for
+func viewArgs(v core.View) string {
+  var args []string
+  for _, f := range []struct {
+    name string
+    on   bool
+  }{{"all", v.All}, {"today", v.Today}, {"week", v.Week}, {"deferred", v.Deferred}, {"detailed", v.Detailed}, {"tree", v.Tree}} {
+    if f.on {
+      args = append(args, "-"+f.name)
+    }
+  }
+  for _, f := range []struct{ name, value string }{
+    {"q", v.Filter}, {"grep", v.Grep}, {"tags", v.Tags}, {"sort", v.Sort},
+    {"status", v.Status}, {"project", v.Project}, {"before", v.Before}, {"after", v.After},
+  } {
+    if f.value != "" {
+      args = append(args, "-"+f.name, shellQuote(f.value))
+    }
+  }
+  return strings.Join(args, " ")
+}
+
+// shellQuote quotes s for a POSIX shell if it contains anything but plain
+// word characters
+func shellQuote(s string) string {
+  if strings.IndexFunc(s, func(r rune) bool {
+    return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_.,:+/@", r))
+  }) < 0 {
+    return s
+  }
+  return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
+
+	"godoit/internal/core"
 )
 
 // Version info (optional: injected at build time via -ldflags "-X main.Version=1.0.0")
@@ -24,19 +27,78 @@ Usage:
 Commands:
   add       Add a new task
   list      List tasks
-  done      Mark task as complete
-  edit      Edit an existing task
-  rm        Remove a task
+  view      Show a saved view (list, save, edit, show, delete views)
+  search    Search tasks by words, most relevant first
+  done      Mark tasks as complete (by ID, e.g. 3,7-9)
+  start     Mark tasks as in progress
+  wait      Mark tasks as waiting on something else
+  cancel    Cancel tasks without completing them
+  edit      Edit an existing task (by ID)
+  rm        Move tasks to the trash (by ID, e.g. 3,7-9; -permanent to remove for good)
+  trash     List, restore or empty deleted tasks (list, restore <ids>, empty)
+  move      Move tasks to another project
+  projects  List projects
+  undo      Undo the last change (-n N for more, -list to show them)
+  redo      Redo the last undone change
+  timer     Track time on a task (start <id>, stop, status)
+  log       Log time spent on a task (e.g. log 3 45m)
+  next      Recommend what to work on next
+  graph     Show the dependency graph (ASCII, DOT or Mermaid)
+  skip      Skip occurrences of recurring tasks
+  series    Show a recurring task's history (-stop ends the series)
+  history   Show every change made to a task, by whom and when
   alerts    Show due/overdue tasks
   stats     Show task analytics
   server    Start HTTP API server
   help      Show this help
   version   Show version info
 
+Tasks are addressed by the ID shown as "#<id>" in list output. Pass -index
+to done/start/wait/cancel/edit/rm/move/skip/series/history/timer/log to use positions in the last displayed list instead.
+
+add, list, next, graph, alerts and stats take -project <name> ("all" for every
+project, "none" for tasks outside any project); without it they use the
+default_project from config.json, or all projects if none is set.
+
 Run "godoit <command> -h" for detailed help on each command.
 `, Version, BuildTime)
 }
 
+// parseArgs parses fs from args, allowing flags after the positional
+// arguments ("edit 3 -title x"), and returns the positional arguments
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+  var positional []string
+  for {
+    _ = fs.Parse(args)
+    args = fs.Args()
+    if len(args) == 0 {
+      return positional
+    }
+    positional = append(positional, args[0])
+    args = args[1:]
+  }
+}
+
+// bindListFlags defines the list filter and display flags on fs, storing
+// them in v. The current contents of v are the defaults, so parsing only
+// changes the options given on the command line.
+func bindListFlags(fs *flag.FlagSet, v *core.View) {
+  fs.BoolVar(&v.All, "all", v.All, "Show completed tasks too")
+  fs.BoolVar(&v.Today, "today", v.Today, "Show only today's tasks")
+  fs.BoolVar(&v.Week, "week", v.Week, "Show only this week's tasks")
+  fs.BoolVar(&v.Deferred, "deferred", v.Deferred, "Include tasks scheduled to start later")
+  fs.BoolVar(&v.Detailed, "detailed", v.Detailed, "Show detailed task information")
+  fs.BoolVar(&v.Tree, "tree", v.Tree, "Show subtasks indented under their parents")
+  fs.StringVar(&v.Grep, "grep", v.Grep, "Filter by substring (case-insensitive)")
+  fs.StringVar(&v.Filter, "q", v.Filter, "Filter expression, e.g. 'tag:work and (priority>=2 or due<+3d) and not status:done'")
+  fs.StringVar(&v.Tags, "tags", v.Tags, "Filter by tags (comma=OR, plus=AND)")
+  fs.StringVar(&v.Sort, "sort", v.Sort, "Sort by keys due|priority|created|status|title|urgency, each with an optional :asc or :desc, e.g. priority:desc,due (default due)")
+  fs.StringVar(&v.Status, "status", v.Status, "Only tasks with this status: todo, in-progress, waiting, blocked, done, cancelled, open or all")
+  fs.StringVar(&v.Project, "project", v.Project, "Only tasks in this project (all, none; default: the default project)")
+  fs.StringVar(&v.Before, "before", v.Before, "Filter tasks due before date (YYYY-MM-DD, eom, 2w, ...)")
+  fs.StringVar(&v.After, "after", v.After, "Filter tasks due after date (YYYY-MM-DD, today, ...)")
+}
+
 func main() {
   log.SetFlags(0)
 
@@ -53,78 +115,332 @@ func main() {
     addFlags := flag.NewFlagSet("add", flag.ExitOnError)
     title := addFlags.String("title", "", "Task title (required)")
     description := addFlags.String("desc", "", "Task description (optional)")
-    dueStr := addFlags.String("due", "", "Due date YYYY-MM-DD")
-    repeat := addFlags.String("repeat", "", "Repeat rule: daily|weekly|monthly")
+    dueStr := addFlags.String("due", "", "Due date: YYYY-MM-DD or e.g. tomorrow, \"next fri 14:00\", \"in 3 days\"")
+    scheduled := addFlags.String("scheduled", "", "Hide the task until this date (same formats as -due)")
+    estimate := addFlags.String("estimate", "", "Expected effort, e.g. 30m, 2h or 3pt (story points)")
+    repeat := addFlags.String("repeat", "", "Repeat rule, e.g. weekly, \"every 2 weeks\", mon,wed,fri, \"2nd tuesday\", \"last day\"")
+    repeatFrom := addFlags.String("repeat-from", "due", "Schedule the next occurrence from the 'due' date or from 'completion'")
+    skipMissed := addFlags.Bool("skip-missed", false, "Skip occurrences that are already overdue when completing late")
     priority := addFlags.Int("p", 1, "Priority (1-3, default 1)")
     tags := addFlags.String("tags", "", "Comma-separated tags")
     after := addFlags.String("after", "", "Comma-separated dependency task IDs")
+    parent := addFlags.Int("parent", 0, "Make the task a subtask of this task ID")
+    autoComplete := addFlags.Bool("auto-complete", false, "Complete the task automatically once all its subtasks are done")
+    project := addFlags.String("project", "", "Project to add the task to (default: the parent's project, or the default project)")
     _ = addFlags.Parse(args)
 
-    RunAdd(*title, *description, *dueStr, *repeat, *priority, *tags, *after)
+    RunAdd(*title, *description, *dueStr, *scheduled, *estimate, *repeat, *repeatFrom, *skipMissed, *priority, *tags, *after, *parent, *autoComplete, *project)
 
   case "list", "ls":
     lsFlags := flag.NewFlagSet("list", flag.ExitOnError)
-    showAll := lsFlags.Bool("all", false, "Show completed tasks too")
-    today := lsFlags.Bool("today", false, "Show only today's tasks")
-    week := lsFlags.Bool("week", false, "Show only this week's tasks")
-    detailed := lsFlags.Bool("detailed", false, "Show detailed task information")
-    grep := lsFlags.String("grep", "", "Filter by substring (case-insensitive)")
-    tags := lsFlags.String("tags", "", "Filter by tags (comma=OR, plus=AND)")
-    sortKey := lsFlags.String("sort", "due", "Sort by: due|priority|created|status|title")
-    before := lsFlags.String("before", "", "Filter tasks before YYYY-MM-DD")
-    after := lsFlags.String("after", "", "Filter tasks after YYYY-MM-DD")
+    var v core.View
+    bindListFlags(lsFlags, &v)
+    save := lsFlags.String("save", "", "Also save these options as a view with this name (see godoit view)")
     _ = lsFlags.Parse(args)
 
-    RunList(*showAll, *today, *week, *detailed, *grep, *tags, *sortKey, *before, *after)
+    if *save != "" {
+      v.Name = *save
+      RunViewSave(v)
+    }
+    RunList(v)
+
+  case "view":
+    if len(args) == 0 {
+      RunViews()
+      break
+    }
+
+    action, rest := args[0], args[1:]
+    switch action {
+    case "list", "ls":
+      RunViews()
+
+    case "save":
+      saveFlags := flag.NewFlagSet("view save", flag.ExitOnError)
+      var v core.View
+      bindListFlags(saveFlags, &v)
+      names := parseArgs(saveFlags, rest)
+      if len(names) != 1 {
+        log.Fatal("Usage: godoit view save <name> [list options]")
+      }
+      v.Name = names[0]
+      RunViewSave(v)
+
+    case "edit":
+      if len(rest) < 1 {
+        log.Fatal("Usage: godoit view edit <name> [list options to change]")
+      }
+      editFlags := flag.NewFlagSet("view edit", flag.ExitOnError)
+      v := loadView(rest[0])
+      bindListFlags(editFlags, &v)
+      if extra := parseArgs(editFlags, rest[1:]); len(extra) > 0 {
+        log.Fatal("Usage: godoit view edit <name> [list options to change]")
+      }
+      RunViewSave(v)
+
+    case "show":
+      if len(rest) != 1 {
+        log.Fatal("Usage: godoit view show <name>")
+      }
+      RunViewShow(rest[0])
+
+    case "delete", "rm":
+      if len(rest) != 1 {
+        log.Fatal("Usage: godoit view delete <name>")
+      }
+      RunViewDelete(rest[0])
+
+    default:
+      // Run the view; list options given after the name apply this time only
+      runFlags := flag.NewFlagSet("view", flag.ExitOnError)
+      v := loadView(action)
+      bindListFlags(runFlags, &v)
+      if extra := parseArgs(runFlags, rest); len(extra) > 0 {
+        log.Fatal("Usage: godoit view <name> [list options]")
+      }
+      RunList(v)
+    }
 
   case "done":
     doneFlags := flag.NewFlagSet("done", flag.ExitOnError)
-    _ = doneFlags.Parse(args)
+    byIndex := doneFlags.Bool("index", false, "Treat arguments as positions in the last displayed list")
+    ids := parseArgs(doneFlags, args)
 
-    if doneFlags.NArg() < 1 {
-      log.Fatal("Usage: godoit done <index>")
+    if len(ids) < 1 {
+      log.Fatal("Usage: godoit done [-index] <ids>")
     }
 
-    RunDone(doneFlags.Arg(0))
+    RunDone(strings.Join(ids, ","), *byIndex)
+
+  case "start", "wait", "cancel":
+    statusFlags := flag.NewFlagSet(cmd, flag.ExitOnError)
+    byIndex := statusFlags.Bool("index", false, "Treat arguments as positions in the last displayed list")
+    ids := parseArgs(statusFlags, args)
+
+    if len(ids) < 1 {
+      log.Fatalf("Usage: godoit %s [-index] <ids>", cmd)
+    }
+
+    RunStatus(strings.Join(ids, ","), *byIndex, statusCommands[cmd])
 
   case "edit":
     editFlags := flag.NewFlagSet("edit", flag.ExitOnError)
     title := editFlags.String("title", "", "New task title")
     description := editFlags.String("desc", "", "New task description (or 'none' to clear)")
-    dueStr := editFlags.String("due", "", "Due date YYYY-MM-DD (or 'none' to clear)")
+    dueStr := editFlags.String("due", "", "Due date: YYYY-MM-DD, tomorrow, \"in 3 days\", ... (or 'none' to clear)")
+    scheduled := editFlags.String("scheduled", "", "Hide the task until this date (or 'none' to clear)")
+    estimate := editFlags.String("estimate", "", "Expected effort, e.g. 30m, 2h or 3pt (or 'none' to clear)")
     repeat := editFlags.String("repeat", "", "Repeat rule (or 'none' to clear)")
+    repeatFrom := editFlags.String("repeat-from", "", "Schedule the next occurrence from 'due' or 'completion'")
+    skipMissed := editFlags.Bool("skip-missed", false, "Skip overdue occurrences (-skip-missed=false to turn off)")
     priority := editFlags.Int("p", 0, "Priority (1-3, 0 to keep current)")
     tags := editFlags.String("tags", "", "Tags (or 'none' to clear)")
     after := editFlags.String("after", "", "Dependencies (or 'none' to clear)")
-    _ = editFlags.Parse(args)
+    parent := editFlags.String("parent", "", "Parent task ID (or 'none' to make it top-level)")
+    autoComplete := editFlags.Bool("auto-complete", false, "Complete automatically once all subtasks are done (-auto-complete=false to turn off)")
+    future := editFlags.Bool("future", false, "For recurring tasks, also apply the edit to future occurrences")
+    status := editFlags.String("status", "", "Status: todo, in-progress, waiting, blocked, cancelled or done")
+    project := editFlags.String("project", "", "Move the task and its subtasks to this project ('none' for no project)")
+    byIndex := editFlags.Bool("index", false, "Treat the argument as a position in the last displayed list")
+    ids := parseArgs(editFlags, args)
 
-    if editFlags.NArg() < 1 {
-      log.Fatal("Usage: godoit edit <index> [options]")
+    if len(ids) < 1 {
+      log.Fatal("Usage: godoit edit [-index] <id> [options]")
     }
 
-    RunEdit(editFlags.Arg(0), *title, *description, *dueStr, *repeat, *priority, *tags, *after)
+    // -skip-missed only changes the task when given explicitly
+    var skipMissedPtr, autoCompletePtr *bool
+    editFlags.Visit(func(f *flag.Flag) {
+      switch f.Name {
+      case "skip-missed":
+        skipMissedPtr = skipMissed
+      case "auto-complete":
+        autoCompletePtr = autoComplete
+      }
+    })
+
+    RunEdit(strings.Join(ids, ","), *byIndex, *title, *description, *dueStr, *scheduled, *estimate, *repeat, *repeatFrom, skipMissedPtr, *priority, *tags, *after, *parent, autoCompletePtr, *future, *status, *project)
 
   case "remove", "rm":
     rmFlags := flag.NewFlagSet("rm", flag.ExitOnError)
-    _ = rmFlags.Parse(args)
+    byIndex := rmFlags.Bool("index", false, "Treat arguments as positions in the last displayed list")
+    deps := rmFlags.String("deps", "", "If other tasks depend on it: keep (they wait for it to be restored; default), fail, cascade (remove them too) or rewrite (they inherit its dependencies); with -permanent the default is fail")
+    permanent := rmFlags.Bool("permanent", false, "Remove for good instead of moving to the trash")
+    ids := parseArgs(rmFlags, args)
+
+    if len(ids) < 1 {
+      log.Fatal("Usage: godoit rm [-index] [-permanent] <ids>")
+    }
+
+    RunRemove(strings.Join(ids, ","), *byIndex, *deps, *permanent)
+
+  case "trash":
+    sub := "list"
+    if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+      sub, args = args[0], args[1:]
+    }
+    trashFlags := flag.NewFlagSet("trash "+sub, flag.ExitOnError)
+    ids := parseArgs(trashFlags, args)
+
+    switch sub {
+    case "list", "ls":
+      RunTrashList()
+    case "restore":
+      if len(ids) < 1 {
+        log.Fatal("Usage: godoit trash restore <ids>")
+      }
+      RunTrashRestore(strings.Join(ids, ","))
+    case "empty":
+      RunTrashEmpty()
+    default:
+      log.Fatalf("Unknown trash command: %s (use list, restore or empty)", sub)
+    }
+
+  case "move", "mv":
+    moveFlags := flag.NewFlagSet("move", flag.ExitOnError)
+    byIndex := moveFlags.Bool("index", false, "Treat arguments as positions in the last displayed list")
+    project := moveFlags.String("project", "", "Project to move the tasks to ('none' for no project)")
+    ids := parseArgs(moveFlags, args)
+
+    if len(ids) < 1 || *project == "" {
+      log.Fatal("Usage: godoit move -project <name> [-index] <ids>")
+    }
 
-    if rmFlags.NArg() < 1 {
-      log.Fatal("Usage: godoit rm <index>")
+    RunMove(strings.Join(ids, ","), *byIndex, *project)
+
+  case "undo", "redo":
+    undoFlags := flag.NewFlagSet(cmd, flag.ExitOnError)
+    n := undoFlags.Int("n", 1, "Number of changes to "+cmd)
+    list := false
+    if cmd == "undo" {
+      undoFlags.BoolVar(&list, "list", false, "List the changes that can be undone and redone instead")
+    }
+    _ = undoFlags.Parse(args)
+
+    if list {
+      RunUndoList()
+      break
+    }
+    if *n < 1 {
+      log.Fatalf("Usage: godoit %s [-n N]", cmd)
+    }
+    RunUndo(*n, cmd == "redo")
+
+  case "projects":
+    RunProjects()
+
+  case "timer":
+    timerFlags := flag.NewFlagSet("timer", flag.ExitOnError)
+    byIndex := timerFlags.Bool("index", false, "Treat the argument as a position in the last displayed list")
+    rest := parseArgs(timerFlags, args)
+
+    if len(rest) < 1 || (rest[0] == "start" && len(rest) != 2) {
+      log.Fatal("Usage: godoit timer start [-index] <id> | timer stop | timer status")
+    }
+
+    id := ""
+    if len(rest) > 1 {
+      id = rest[1]
+    }
+    RunTimer(rest[0], id, *byIndex)
+
+  case "log":
+    logFlags := flag.NewFlagSet("log", flag.ExitOnError)
+    byIndex := logFlags.Bool("index", false, "Treat the argument as a position in the last displayed list")
+    note := logFlags.String("note", "", "Note to attach to the time entry")
+    rest := parseArgs(logFlags, args)
+
+    if len(rest) != 2 {
+      log.Fatal("Usage: godoit log [-index] [-note <text>] <id> <duration>")
+    }
+
+    RunLog(rest[0], *byIndex, rest[1], *note)
+
+  case "next":
+    nextFlags := flag.NewFlagSet("next", flag.ExitOnError)
+    n := nextFlags.Int("n", 1, "Number of tasks to recommend")
+    target := nextFlags.String("for", "", "Show the critical path to this task ID instead")
+    project := nextFlags.String("project", "", "Only recommend tasks in this project")
+    _ = nextFlags.Parse(args)
+
+    RunNext(*n, *target, *project)
+
+  case "search", "find":
+    searchFlags := flag.NewFlagSet("search", flag.ExitOnError)
+    open := searchFlags.Bool("open", false, "Only open tasks (by default completed and cancelled tasks are searched too)")
+    project := searchFlags.String("project", "", "Only tasks in this project ('none' for tasks outside any project; default: every project)")
+    n := searchFlags.Int("n", 20, "Show at most this many matches (0 for all)")
+    detailed := searchFlags.Bool("detailed", false, "Show description, tags, project and completion date")
+    // Flags go before the search, so "-word" in it excludes the word
+    _ = searchFlags.Parse(args)
+    words := searchFlags.Args()
+
+    if len(words) < 1 {
+      log.Fatal(`Usage: godoit search [-open] [-project <name>] [-n N] [--] <words, "phrases", prefix*, -excluded>`)
     }
 
-    RunRemove(rmFlags.Arg(0))
+    RunSearch(strings.Join(words, " "), *open, *project, *n, *detailed)
+
+  case "graph":
+    graphFlags := flag.NewFlagSet("graph", flag.ExitOnError)
+    format := graphFlags.String("format", "ascii", "Output format: ascii, dot or mermaid")
+    tags := graphFlags.String("tags", "", "Only tasks with these tags (comma=OR, plus=AND)")
+    status := graphFlags.String("status", "open", "Only tasks with this status: open, all or a task status")
+    project := graphFlags.String("project", "", "Only tasks in this project")
+    _ = graphFlags.Parse(args)
+
+    RunGraph(*format, *tags, *status, *project)
+
+  case "skip":
+    skipFlags := flag.NewFlagSet("skip", flag.ExitOnError)
+    byIndex := skipFlags.Bool("index", false, "Treat arguments as positions in the last displayed list")
+    ids := parseArgs(skipFlags, args)
+
+    if len(ids) < 1 {
+      log.Fatal("Usage: godoit skip [-index] <ids>")
+    }
+
+    RunSkip(strings.Join(ids, ","), *byIndex)
+
+  case "series":
+    seriesFlags := flag.NewFlagSet("series", flag.ExitOnError)
+    stop := seriesFlags.Bool("stop", false, "End the series: open occurrences stay but stop repeating")
+    byIndex := seriesFlags.Bool("index", false, "Treat the argument as a position in the last displayed list")
+    ids := parseArgs(seriesFlags, args)
+
+    if len(ids) < 1 {
+      log.Fatal("Usage: godoit series [-stop] [-index] <id>")
+    }
+
+    RunSeries(ids[0], *byIndex, *stop)
+
+  case "history":
+    historyFlags := flag.NewFlagSet("history", flag.ExitOnError)
+    byIndex := historyFlags.Bool("index", false, "Treat the argument as a position in the last displayed list")
+    ids := parseArgs(historyFlags, args)
+
+    if len(ids) < 1 {
+      log.Fatal("Usage: godoit history [-index] <id>")
+    }
+
+    RunHistory(ids[0], *byIndex)
 
   case "alerts":
     alertFlags := flag.NewFlagSet("alerts", flag.ExitOnError)
     watch := alertFlags.Bool("watch", false, "Continuously monitor for upcoming tasks")
     interval := alertFlags.Duration("interval", 60*time.Second, "Polling interval for watch mode")
     ahead := alertFlags.Duration("ahead", 24*time.Hour, "Lookahead window for alerts")
+    project := alertFlags.String("project", "", "Only alert on tasks in this project")
     _ = alertFlags.Parse(args)
 
-    RunAlerts(*watch, *interval, *ahead)
+    RunAlerts(*watch, *interval, *ahead, *project)
 
   case "stats":
-    RunStats()
+    statsFlags := flag.NewFlagSet("stats", flag.ExitOnError)
+    project := statsFlags.String("project", "", "Only count tasks in this project")
+    _ = statsFlags.Parse(args)
+
+    RunStats(*project)
 
   case "server":
     serverFlags := flag.NewFlagSet("server", flag.ExitOnError)
@@ -2,7 +2,6 @@ module godoit
 
 go 1.25
 
-require (
-	github.com/gofrs/flock v0.12.1 // indirect
-	golang.org/x/sys v0.22.0 // indirect
-)
+require github.com/gofrs/flock v0.12.1
+
+require golang.org/x/sys v0.22.0 // indirect
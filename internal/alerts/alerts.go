@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"time"
 
+	"godoit/internal/clock"
 	"godoit/internal/core"
 	"godoit/internal/notifications"
 )
@@ -16,6 +17,7 @@ const (
 	AlertDueSoon    AlertType = "Due Soon"
 	AlertBlocked    AlertType = "Blocked"
 	AlertDependency AlertType = "Dependency"
+	AlertAvailable  AlertType = "Now Available"
 )
 
 // Alert represents a task alert
@@ -28,24 +30,44 @@ type Alert struct {
 // Scanner scans tasks for alerts
 type Scanner struct {
 	notifier notifications.Notifier
+	clock    clock.Clock
 }
 
 // NewScanner creates a new alert scanner
 func NewScanner(notifier notifications.Notifier) *Scanner {
 	return &Scanner{
 		notifier: notifier,
+		clock:    clock.SystemClock{},
 	}
 }
 
+// SetClock sets the clock used by Watch; its location decides the day
+// boundaries of all-day tasks
+func (s *Scanner) SetClock(c clock.Clock) {
+	s.clock = c
+}
+
 // Scan scans tasks and returns alerts
 func (s *Scanner) Scan(tasks []core.Task, now time.Time, lookahead time.Duration) []Alert {
 	alerts := make([]Alert, 0)
 
+	loc := now.Location()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
 	for _, task := range tasks {
 		if task.IsDone() {
 			continue
 		}
 
+		// Check for deferred tasks that became available today
+		if task.Scheduled != nil && !task.IsDeferred(now) && !task.AvailableAt(loc).Before(today) {
+			alerts = append(alerts, Alert{
+				Task:    task,
+				Type:    AlertAvailable,
+				Message: fmt.Sprintf("Task now available: %s", task.Title),
+			})
+		}
+
 		// Check for overdue tasks
 		if task.IsOverdue(now) {
 			alerts = append(alerts, Alert{
@@ -58,17 +80,20 @@ func (s *Scanner) Scan(tasks []core.Task, now time.Time, lookahead time.Duration
 
 		// Check for tasks due soon
 		if task.IsDueSoon(now, lookahead) {
-			dueIn := task.Due.Sub(now)
+			message := fmt.Sprintf("Task due today: %s", task.Title)
+			if dueIn := task.DueIn(now.Location()).Sub(now); dueIn > 0 {
+				message = fmt.Sprintf("Task due in %s: %s", formatDuration(dueIn), task.Title)
+			}
 			alerts = append(alerts, Alert{
 				Task:    task,
 				Type:    AlertDueSoon,
-				Message: fmt.Sprintf("Task due in %s: %s", formatDuration(dueIn), task.Title),
+				Message: message,
 			})
 			continue
 		}
 
-		// Check for blocked tasks
-		if !core.AllDependenciesMet(tasks, task) {
+		// Check for blocked tasks; deferred ones are not expected to start yet
+		if !task.IsDeferred(now) && !core.AllDependenciesMet(tasks, task) {
 			alerts = append(alerts, Alert{
 				Task:    task,
 				Type:    AlertBlocked,
@@ -100,7 +125,7 @@ func (s *Scanner) Watch(tasks []core.Task, interval, lookahead time.Duration, lo
 	fmt.Println("Press Ctrl+C to stop...")
 
 	// Initial scan
-	s.printAlerts(tasks, time.Now(), lookahead)
+	s.printAlerts(tasks, s.clock.Now(), lookahead)
 
 	for range ticker.C {
 		// Reload tasks
@@ -113,9 +138,10 @@ func (s *Scanner) Watch(tasks []core.Task, interval, lookahead time.Duration, lo
 			tasks = newTasks
 		}
 
-		alerts := s.ScanAndNotify(tasks, time.Now(), lookahead)
+		now := s.clock.Now()
+		alerts := s.ScanAndNotify(tasks, now, lookahead)
 		if len(alerts) > 0 {
-			s.printAlerts(tasks, time.Now(), lookahead)
+			s.printAlerts(tasks, now, lookahead)
 		}
 	}
 }
@@ -134,7 +160,7 @@ func (s *Scanner) printAlerts(tasks []core.Task, now time.Time, lookahead time.D
 	for _, alert := range alerts {
 		fmt.Printf("[%s] %s\n", alert.Type, alert.Message)
 		if alert.Task.Due != nil {
-			fmt.Printf("  Due: %s\n", alert.Task.Due.Format("2006-01-02 15:04"))
+			fmt.Printf("  Due: %s\n", alert.Task.DueString(now.Location()))
 		}
 		if alert.Task.Priority > 1 {
 			fmt.Printf("  Priority: %d\n", alert.Task.Priority)
@@ -186,6 +212,7 @@ func GetAlertSummary(tasks []core.Task, now time.Time, lookahead time.Duration)
 	overdue := 0
 	dueSoon := 0
 	blocked := 0
+	available := 0
 
 	for _, alert := range alerts {
 		switch alert.Type {
@@ -195,11 +222,13 @@ func GetAlertSummary(tasks []core.Task, now time.Time, lookahead time.Duration)
 			dueSoon++
 		case AlertBlocked:
 			blocked++
+		case AlertAvailable:
+			available++
 		}
 	}
 
 	summary := fmt.Sprintf("%d alerts: ", len(alerts))
-	parts := make([]string, 0, 3)
+	parts := make([]string, 0, 4)
 
 	if overdue > 0 {
 		parts = append(parts, fmt.Sprintf("%d overdue", overdue))
@@ -210,6 +239,9 @@ func GetAlertSummary(tasks []core.Task, now time.Time, lookahead time.Duration)
 	if blocked > 0 {
 		parts = append(parts, fmt.Sprintf("%d blocked", blocked))
 	}
+	if available > 0 {
+		parts = append(parts, fmt.Sprintf("%d now available", available))
+	}
 
 	for i, part := range parts {
 		if i > 0 {
@@ -1,7 +1,69 @@
 package app
 
+import (
+	"fmt"
+
+	"godoit/internal/clock"
+	"godoit/internal/config"
+	"godoit/internal/repository"
+	"godoit/internal/service"
+	"godoit/internal/store"
+)
+
 const (
     AppName = "godoit"
 )
 
+// NewTaskService wires the default store, repository and service according
+// to the user's configuration. The CLI and the HTTP server both use it.
+func NewTaskService() (*service.TaskService, error) {
+    cfg, err := config.Load()
+    if err != nil {
+        return nil, err
+    }
+    loc, err := cfg.Location()
+    if err != nil {
+        return nil, err
+    }
+    point, err := cfg.StoryPointDuration()
+    if err != nil {
+        return nil, err
+    }
+    weights, err := cfg.UrgencyWeights()
+    if err != nil {
+        return nil, err
+    }
+    retention, err := cfg.TrashRetentionDuration()
+    if err != nil {
+        return nil, err
+    }
 
+    s, err := store.TaskStore(cfg.Storage)
+    if err != nil {
+        return nil, err
+    }
+    vs, err := store.ViewStore()
+    if err != nil {
+        return nil, err
+    }
+    us, err := store.UndoStore()
+    if err != nil {
+        return nil, err
+    }
+    al, err := store.AuditStore()
+    if err != nil {
+        return nil, err
+    }
+    repo := repository.NewJSONTaskRepository(s)
+    svc := service.NewTaskService(repo, clock.ZonedClock{Clock: clock.SystemClock{}, Location: loc})
+    if err := svc.SetDefaultProject(cfg.DefaultProject); err != nil {
+        return nil, fmt.Errorf("invalid default_project: %w", err)
+    }
+    svc.SetStoryPoint(point)
+    svc.SetUrgencyWeights(weights)
+    svc.SetTrashRetention(retention)
+    svc.SetViewRepository(repository.NewJSONViewRepository(vs))
+    svc.SetUndoRepository(repository.NewJSONUndoRepository(us))
+    svc.SetAuditRepository(repository.NewJSONAuditRepository(al))
+    return svc, nil
+}
@@ -10,4 +10,11 @@ type SystemClock struct{}
 
 func (SystemClock) Now() time.Time { return time.Now() }
 
+// ZonedClock reports the time of another clock in a fixed location, so that
+// "today" and day boundaries follow the user's configured time zone.
+type ZonedClock struct {
+    Clock    Clock
+    Location *time.Location
+}
 
+func (c ZonedClock) Now() time.Time { return c.Clock.Now().In(c.Location) }
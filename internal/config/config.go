@@ -0,0 +1,137 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"godoit/internal/core"
+	"godoit/internal/store"
+)
+
+// Config holds user settings read from config.json in the config directory.
+// Every field is optional; the zero value means "use the default".
+type Config struct {
+	// TimeZone is an IANA zone name such as "Europe/Berlin" used for due
+	// dates, today/week views, stats and alerts. Defaults to the system zone.
+	TimeZone string `json:"time_zone,omitempty"`
+
+	// DefaultProject is the project new tasks are added to, and that
+	// listings, stats and alerts are limited to, when no project is given.
+	// Defaults to no project and all projects respectively.
+	DefaultProject string `json:"default_project,omitempty"`
+
+	// StoryPoint is the effort one story point of an estimate stands for,
+	// as a duration such as "4h". Defaults to core.DefaultStoryPoint.
+	StoryPoint string `json:"story_point,omitempty"`
+
+	// Urgency overrides some or all of the urgency weights, e.g.
+	// {"due": 10, "tags": {"urgent": 4}}. See core.UrgencyWeights.
+	Urgency json.RawMessage `json:"urgency,omitempty"`
+
+	// Storage is how tasks are saved: "json" rewrites tasks.json on every
+	// change, "journal" appends changes to tasks.journal and compacts them
+	// into tasks.json from time to time. Defaults to "json".
+	Storage string `json:"storage,omitempty"`
+
+	// TrashRetention is how long deleted tasks stay in the trash before
+	// they are purged, in days ("30d") or as a duration ("12h"), or "never".
+	// Defaults to core.DefaultTrashRetention.
+	TrashRetention string `json:"trash_retention,omitempty"`
+}
+
+// GetConfigFile returns the full path to the config file
+func GetConfigFile() (string, error) {
+	dir, err := store.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "config.json"), nil
+}
+
+// Load reads the config file, returning defaults if it does not exist
+func Load() (Config, error) {
+	var cfg Config
+
+	path, err := GetConfigFile()
+	if err != nil {
+		return cfg, err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// StoryPointDuration returns the configured story point size, or the
+// default if unset
+func (c Config) StoryPointDuration() (time.Duration, error) {
+	if c.StoryPoint == "" {
+		return core.DefaultStoryPoint, nil
+	}
+	d, err := time.ParseDuration(c.StoryPoint)
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid story_point %q (e.g. 4h)", c.StoryPoint)
+	}
+	return d, nil
+}
+
+// TrashRetentionDuration returns the configured trash retention, 0 for
+// "never", or the default if unset
+func (c Config) TrashRetentionDuration() (time.Duration, error) {
+	switch s := strings.TrimSpace(c.TrashRetention); {
+	case s == "":
+		return core.DefaultTrashRetention, nil
+	case s == "never":
+		return 0, nil
+	case strings.HasSuffix(s, "d"):
+		days, err := strconv.Atoi(strings.TrimSuffix(s, "d"))
+		if err == nil && days > 0 {
+			return time.Duration(days) * 24 * time.Hour, nil
+		}
+	default:
+		if d, err := time.ParseDuration(s); err == nil && d > 0 {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid trash_retention %q (e.g. 30d, 12h or never)", c.TrashRetention)
+}
+
+// UrgencyWeights returns the default urgency weights with the configured
+// overrides applied
+func (c Config) UrgencyWeights() (core.UrgencyWeights, error) {
+	w := core.DefaultUrgencyWeights()
+	if len(c.Urgency) == 0 {
+		return w, nil
+	}
+	if err := json.Unmarshal(c.Urgency, &w); err != nil {
+		return w, fmt.Errorf("invalid urgency: %w", err)
+	}
+	return w, nil
+}
+
+// Location returns the configured time zone, or the system zone if unset
+func (c Config) Location() (*time.Location, error) {
+	if c.TimeZone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(c.TimeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid time_zone %q: %w", c.TimeZone, err)
+	}
+	return loc, nil
+}
@@ -0,0 +1,139 @@
+package core
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// What an audit entry did to its task
+const (
+	AuditCreated = "created"
+	AuditChanged = "changed"
+	AuditRemoved = "removed"
+)
+
+// Origin is who or what made a change: the interface it came through, such
+// as "cli" or "http", the user, as far as it is known, and for HTTP the
+// address of the client. The user of an HTTP request is only what the client
+// claims; the address is what the server saw.
+type Origin struct {
+	Source string `json:"source,omitempty"`
+	User   string `json:"user,omitempty"`
+	Addr   string `json:"addr,omitempty"`
+}
+
+// String describes the origin, e.g. "alice via cli" or "bob via http from
+// 10.0.0.7"
+func (o Origin) String() string {
+	var parts []string
+	if o.User != "" {
+		parts = append(parts, o.User)
+	}
+	if o.Source != "" {
+		parts = append(parts, "via "+o.Source)
+	}
+	if o.Addr != "" {
+		parts = append(parts, "from "+o.Addr)
+	}
+	if len(parts) == 0 {
+		return "unknown"
+	}
+	return strings.Join(parts, " ")
+}
+
+// FieldChange is the old and new value of one task field, in its JSON form.
+// A value is missing when the field was empty.
+type FieldChange struct {
+	Field string          `json:"field"`
+	Old   json.RawMessage `json:"old,omitempty"`
+	New   json.RawMessage `json:"new,omitempty"`
+}
+
+// AuditEntry records what one operation did to one task. Entries are only
+// ever added to the audit log, never changed.
+//
+// IDs of tasks removed for good are given out again, so TaskCreated, the
+// task's CreatedAt, tells apart the tasks that had the same ID.
+type AuditEntry struct {
+	TaskID      int           `json:"task_id"`
+	TaskCreated time.Time     `json:"task_created,omitzero"`
+	At          time.Time     `json:"at"`
+	Action      string        `json:"action"` // the operation, as in Operation.Action, e.g. "edit" or "undo edit"
+	Event       string        `json:"event"`  // AuditCreated, AuditChanged or AuditRemoved
+	Origin                    // who made the change
+	Fields      []FieldChange `json:"fields,omitempty"` // set fields for created tasks, changed ones otherwise
+}
+
+// FieldChanges returns the fields that differ between two versions of a
+// task, with their values before and after
+func FieldChanges(before, after Task) []FieldChange {
+	var changes []FieldChange
+	bv, av := reflect.ValueOf(before), reflect.ValueOf(after)
+	for i := 0; i < bv.NumField(); i++ {
+		b, a := bv.Field(i), av.Field(i)
+		if reflect.DeepEqual(b.Interface(), a.Interface()) {
+			continue
+		}
+		name, _, _ := strings.Cut(bv.Type().Field(i).Tag.Get("json"), ",")
+		changes = append(changes, FieldChange{Field: name, Old: fieldJSON(b), New: fieldJSON(a)})
+	}
+	return changes
+}
+
+// fieldJSON encodes a field value, or returns nil for an empty one
+func fieldJSON(v reflect.Value) json.RawMessage {
+	if v.IsZero() || (v.Kind() == reflect.Slice && v.Len() == 0) {
+		return nil
+	}
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// AuditEntries returns the audit entries for an operation, one per task it
+// changed
+func AuditEntries(op Operation, origin Origin) []AuditEntry {
+	entries := make([]AuditEntry, 0, len(op.Changes))
+	for _, c := range op.Changes {
+		e := AuditEntry{TaskID: c.ID, At: op.At, Action: op.Action, Origin: origin}
+		switch {
+		case c.Before == nil:
+			e.Event = AuditCreated
+			e.TaskCreated = c.After.CreatedAt
+			e.Fields = FieldChanges(Task{ID: c.ID}, *c.After)
+		case c.After == nil:
+			e.Event = AuditRemoved
+			e.TaskCreated = c.Before.CreatedAt
+		default:
+			e.Event = AuditChanged
+			e.TaskCreated = c.After.CreatedAt
+			e.Fields = FieldChanges(*c.Before, *c.After)
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// TaskAudit picks from the audit entries for one task ID, oldest first, those
+// of the task created at created; the zero time picks the task created last.
+// Entries that do not say when their task was created match any task.
+func TaskAudit(entries []AuditEntry, created time.Time) []AuditEntry {
+	if created.IsZero() {
+		for _, e := range entries {
+			if e.TaskCreated.After(created) {
+				created = e.TaskCreated
+			}
+		}
+	}
+	var result []AuditEntry
+	for _, e := range entries {
+		if e.TaskCreated.IsZero() || e.TaskCreated.Equal(created) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
@@ -0,0 +1,94 @@
+package core
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAuditEntries(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	due := now.Add(24 * time.Hour)
+	tasks := []Task{{ID: 1, Title: "Spec", Priority: 1, CreatedAt: now}}
+	origin := Origin{Source: "cli", User: "alice"}
+
+	_, history := record(t, tasks, nil, "edit", func(tasks []Task) ([]Task, error) {
+		tasks[0].Due = &due
+		tasks[0].Priority = 3
+		return append(tasks, Task{ID: 2, Title: "Review", Priority: 1, CreatedAt: now}), nil
+	})
+	op := history[0]
+	op.At = now
+	entries := AuditEntries(op, origin)
+	if len(entries) != 2 {
+		t.Fatalf("Expected an entry per task, got %+v", entries)
+	}
+
+	changed := entries[0]
+	if changed.TaskID != 1 || changed.Event != AuditChanged || changed.Origin != origin || !changed.At.Equal(now) {
+		t.Errorf("Unexpected entry %+v", changed)
+	}
+	want := []FieldChange{
+		{Field: "due", New: []byte(`"2025-10-23T09:00:00Z"`)},
+		{Field: "priority", Old: []byte(`1`), New: []byte(`3`)},
+	}
+	if !reflect.DeepEqual(changed.Fields, want) {
+		t.Errorf("Expected due and priority changes, got %+v", changed.Fields)
+	}
+
+	// A new task lists the fields it was created with, without its ID
+	created := entries[1]
+	var fields []string
+	for _, f := range created.Fields {
+		fields = append(fields, f.Field)
+	}
+	if created.Event != AuditCreated || !reflect.DeepEqual(fields, []string{"title", "created_at", "priority"}) {
+		t.Errorf("Unexpected entry for the new task: %s %v", created.Event, fields)
+	}
+	if s := origin.String(); s != "alice via cli" {
+		t.Errorf("Origin.String() = %q", s)
+	}
+	if s := (Origin{Source: "http", User: "bob", Addr: "10.0.0.7"}).String(); s != "bob via http from 10.0.0.7" {
+		t.Errorf("Origin.String() = %q", s)
+	}
+}
+
+func TestTaskAuditSeparatesReusedIDs(t *testing.T) {
+	first := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+
+	// Task 1 is removed for good and its ID given to a new task
+	var entries []AuditEntry
+	var history []Operation
+	tasks, history := record(t, nil, history, "add", func(tasks []Task) ([]Task, error) {
+		return append(tasks, Task{ID: 1, Title: "Old", CreatedAt: first}), nil
+	})
+	tasks, history = record(t, tasks, history, "remove", func(tasks []Task) ([]Task, error) {
+		return tasks[:0], nil
+	})
+	_, history = record(t, tasks, history, "add", func(tasks []Task) ([]Task, error) {
+		return append(tasks, Task{ID: NextID(tasks), Title: "New", CreatedAt: second}), nil
+	})
+	for _, op := range history {
+		entries = append(entries, AuditEntries(op, Origin{Source: "cli"})...)
+	}
+	legacy := AuditEntry{TaskID: 1, At: first, Action: "edit", Event: AuditChanged}
+	entries = append([]AuditEntry{legacy}, entries...)
+
+	events := func(entries []AuditEntry) []string {
+		var out []string
+		for _, e := range entries {
+			out = append(out, e.Action+" "+e.Event)
+		}
+		return out
+	}
+	if got := events(TaskAudit(entries, second)); !reflect.DeepEqual(got, []string{"edit changed", "add created"}) {
+		t.Errorf("New task: got %v", got)
+	}
+	if got := events(TaskAudit(entries, first)); !reflect.DeepEqual(got, []string{"edit changed", "add created", "remove removed"}) {
+		t.Errorf("Old task: got %v", got)
+	}
+	if got := events(TaskAudit(entries, time.Time{})); !reflect.DeepEqual(got, []string{"edit changed", "add created"}) {
+		t.Errorf("Without a task: expected the latest one, got %v", got)
+	}
+}
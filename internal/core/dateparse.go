@@ -0,0 +1,272 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseDate resolves a user-supplied date expression against now. It accepts
+// absolute dates ("2025-10-31", "2025-10-31 14:30", RFC 3339) as well as
+// natural language:
+//
+//	today, tomorrow, yesterday
+//	friday, next friday, this fri
+//	in 3 days, 2w, +10d, 3 days ago
+//	next week, next month, end of month (eom), end of week (eow), end of year (eoy)
+//
+// Any of these may be followed by a time of day ("tomorrow 14:30",
+// "friday at 9am", "noon") and then an explicit time zone ("14:30 UTC",
+// "tomorrow 9am America/New_York"). Expressions without a time resolve to
+// midnight. Results are in now's location unless a zone is given.
+//
+// A bare weekday means the nearest such day on or after today; "next" skips
+// today, so on a Friday "friday" is today and "next friday" is a week later.
+func ParseDate(input string, now time.Time) (time.Time, error) {
+	t, _, err := ParseDue(input, now)
+	return t, err
+}
+
+// ParseDue is like ParseDate but also reports whether the expression named a
+// time of day (a timed task) or only a date (an all-day task)
+func ParseDue(input string, now time.Time) (t time.Time, timed bool, err error) {
+	s := strings.TrimSpace(input)
+	if s == "" {
+		return time.Time{}, false, fmt.Errorf("empty date")
+	}
+
+	// An explicit zone overrides now's location
+	if i := strings.LastIndexByte(s, ' '); i > 0 {
+		if loc, ok := parseZone(s[i+1:]); ok {
+			s = strings.TrimSpace(s[:i])
+			now = now.In(loc)
+		}
+	}
+
+	s = strings.ToLower(s)
+	loc := now.Location()
+
+	// Full timestamps carry their own offset
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00"} {
+		if t, err := time.Parse(layout, strings.ToUpper(s)); err == nil {
+			return t, true, nil
+		}
+	}
+	for _, layout := range []string{"2006-01-02t15:04:05", "2006-01-02t15:04"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, true, nil
+		}
+	}
+
+	if s == "now" {
+		return now, true, nil
+	}
+
+	fields := strings.Fields(s)
+
+	// Split off a trailing time of day, optionally introduced by "at"
+	hour, minute, hasTime := 0, 0, false
+	if n := len(fields); n > 0 {
+		if h, m, ok := parseClock(fields[n-1]); ok {
+			hour, minute, hasTime = h, m, true
+			fields = fields[:n-1]
+			if n := len(fields); n > 0 && fields[n-1] == "at" {
+				fields = fields[:n-1]
+			}
+		}
+	}
+
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	var day time.Time
+	if len(fields) == 0 {
+		if !hasTime {
+			return time.Time{}, false, dateError(input)
+		}
+		day = today
+	} else {
+		d, exact, err := parseDay(strings.Join(fields, " "), now, today)
+		if err != nil {
+			return time.Time{}, false, dateError(input)
+		}
+		if exact {
+			// Relative offsets in minutes or hours already include the time
+			if hasTime {
+				return time.Time{}, false, dateError(input)
+			}
+			return d, true, nil
+		}
+		day = d
+	}
+
+	return time.Date(day.Year(), day.Month(), day.Day(), hour, minute, 0, 0, loc), hasTime, nil
+}
+
+// parseZone recognizes a trailing time zone token: "UTC", "Z", "Local" or an
+// IANA name such as "Europe/Berlin"
+func parseZone(name string) (*time.Location, bool) {
+	switch strings.ToUpper(name) {
+	case "UTC", "Z":
+		return time.UTC, true
+	case "LOCAL":
+		return time.Local, true
+	}
+	if !strings.Contains(name, "/") {
+		return nil, false
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, false
+	}
+	return loc, true
+}
+
+func dateError(input string) error {
+	return fmt.Errorf("unrecognized date %q (try YYYY-MM-DD, \"tomorrow\", \"next friday\" or \"in 3 days\")", input)
+}
+
+var weekdayNames = map[string]time.Weekday{
+	"sunday": time.Sunday, "sun": time.Sunday,
+	"monday": time.Monday, "mon": time.Monday,
+	"tuesday": time.Tuesday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wednesday": time.Wednesday, "wed": time.Wednesday,
+	"thursday": time.Thursday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"friday": time.Friday, "fri": time.Friday,
+	"saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// offsetPattern matches "3d", "+2w", "-1mo", "3 days", "in 3 days", "3 days ago"
+var offsetPattern = regexp.MustCompile(`^(in\s+)?([+-]?\d+)\s*([a-z]+)(\s+ago)?$`)
+
+// parseDay resolves the date part of an expression to midnight of that day.
+// exact is set when the expression is an offset in minutes or hours, in
+// which case the returned time is not truncated to the day.
+func parseDay(s string, now, today time.Time) (t time.Time, exact bool, err error) {
+	loc := today.Location()
+
+	for _, layout := range []string{"2006-01-02", "2006/01/02"} {
+		if d, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return d, false, nil
+		}
+	}
+
+	switch s {
+	case "today", "tod":
+		return today, false, nil
+	case "tomorrow", "tmr", "tom":
+		return today.AddDate(0, 0, 1), false, nil
+	case "yesterday":
+		return today.AddDate(0, 0, -1), false, nil
+	case "next week":
+		return today.AddDate(0, 0, 7), false, nil
+	case "next month":
+		return today.AddDate(0, 1, 0), false, nil
+	case "next year":
+		return today.AddDate(1, 0, 0), false, nil
+	case "end of week", "eow":
+		return today.AddDate(0, 0, int(time.Saturday-today.Weekday())), false, nil
+	case "end of month", "eom":
+		return time.Date(today.Year(), today.Month()+1, 0, 0, 0, 0, 0, loc), false, nil
+	case "end of year", "eoy":
+		return time.Date(today.Year(), time.December, 31, 0, 0, 0, 0, loc), false, nil
+	}
+
+	fields := strings.Fields(s)
+	if len(fields) <= 2 {
+		name := fields[len(fields)-1]
+		if wd, ok := weekdayNames[name]; ok {
+			skipToday := false
+			if len(fields) == 2 {
+				switch fields[0] {
+				case "next":
+					skipToday = true
+				case "this", "on":
+				default:
+					return time.Time{}, false, fmt.Errorf("unrecognized date")
+				}
+			}
+			days := (int(wd) - int(today.Weekday()) + 7) % 7
+			if days == 0 && skipToday {
+				days = 7
+			}
+			return today.AddDate(0, 0, days), false, nil
+		}
+	}
+
+	if m := offsetPattern.FindStringSubmatch(s); m != nil {
+		n, err := strconv.Atoi(m[2])
+		if err != nil {
+			return time.Time{}, false, err
+		}
+		if m[4] != "" {
+			if m[1] != "" {
+				return time.Time{}, false, fmt.Errorf("unrecognized date")
+			}
+			n = -n
+		}
+		switch m[3] {
+		case "min", "mins", "minute", "minutes":
+			return now.Add(time.Duration(n) * time.Minute), true, nil
+		case "h", "hr", "hrs", "hour", "hours":
+			return now.Add(time.Duration(n) * time.Hour), true, nil
+		case "d", "day", "days":
+			return today.AddDate(0, 0, n), false, nil
+		case "w", "wk", "wks", "week", "weeks":
+			return today.AddDate(0, 0, 7*n), false, nil
+		case "mo", "month", "months":
+			return today.AddDate(0, n, 0), false, nil
+		case "y", "yr", "yrs", "year", "years":
+			return today.AddDate(n, 0, 0), false, nil
+		}
+	}
+
+	return time.Time{}, false, fmt.Errorf("unrecognized date")
+}
+
+// clockPattern matches "14:30", "9am", "9:15pm"
+var clockPattern = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)?$`)
+
+// parseClock parses a time of day token
+func parseClock(s string) (hour, minute int, ok bool) {
+	switch s {
+	case "noon":
+		return 12, 0, true
+	case "midnight":
+		return 0, 0, true
+	}
+
+	m := clockPattern.FindStringSubmatch(s)
+	if m == nil || (m[2] == "" && m[3] == "") {
+		// A bare number is not a time ("in 3 days" must keep its 3)
+		return 0, 0, false
+	}
+
+	hour, _ = strconv.Atoi(m[1])
+	if m[2] != "" {
+		minute, _ = strconv.Atoi(m[2])
+	}
+
+	switch m[3] {
+	case "am":
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		if hour == 12 {
+			hour = 0
+		}
+	case "pm":
+		if hour < 1 || hour > 12 {
+			return 0, 0, false
+		}
+		if hour != 12 {
+			hour += 12
+		}
+	}
+
+	if hour > 23 || minute > 59 {
+		return 0, 0, false
+	}
+	return hour, minute, true
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDate(t *testing.T) {
+	// Wednesday, 2025-10-22 10:15 UTC
+	now := time.Date(2025, 10, 22, 10, 15, 0, 0, time.UTC)
+	day := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		input    string
+		expected time.Time
+	}{
+		{"2025-10-31", day(2025, 10, 31)},
+		{"2025-10-31 14:30", time.Date(2025, 10, 31, 14, 30, 0, 0, time.UTC)},
+		{"today", day(2025, 10, 22)},
+		{"Tomorrow", day(2025, 10, 23)},
+		{"tomorrow 14:30", time.Date(2025, 10, 23, 14, 30, 0, 0, time.UTC)},
+		{"friday", day(2025, 10, 24)},
+		{"fri at 9am", time.Date(2025, 10, 24, 9, 0, 0, 0, time.UTC)},
+		{"wednesday", day(2025, 10, 22)},
+		{"next wednesday", day(2025, 10, 29)},
+		{"next friday", day(2025, 10, 24)},
+		{"in 3 days", day(2025, 10, 25)},
+		{"3 days ago", day(2025, 10, 19)},
+		{"2w", day(2025, 11, 5)},
+		{"+10d", day(2025, 11, 1)},
+		{"in 2 hours", time.Date(2025, 10, 22, 12, 15, 0, 0, time.UTC)},
+		{"end of month", day(2025, 10, 31)},
+		{"eow", day(2025, 10, 25)},
+		{"next month", day(2025, 11, 22)},
+		{"noon", time.Date(2025, 10, 22, 12, 0, 0, 0, time.UTC)},
+		{"5:30pm", time.Date(2025, 10, 22, 17, 30, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseDate(tt.input, now)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if !result.Equal(tt.expected) {
+				t.Errorf("Expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestParseDue(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+	now := time.Date(2025, 10, 22, 10, 15, 0, 0, ny)
+
+	due, timed, err := ParseDue("friday", now)
+	if err != nil || timed {
+		t.Fatalf("Expected all-day date, got timed=%v err=%v", timed, err)
+	}
+	if !due.Equal(time.Date(2025, 10, 24, 0, 0, 0, 0, ny)) {
+		t.Errorf("Expected midnight in the caller's zone, got %s", due)
+	}
+
+	due, timed, err = ParseDue("tomorrow 14:30 UTC", now)
+	if err != nil || !timed {
+		t.Fatalf("Expected timed date, got timed=%v err=%v", timed, err)
+	}
+	if !due.Equal(time.Date(2025, 10, 23, 14, 30, 0, 0, time.UTC)) {
+		t.Errorf("Expected 14:30 UTC, got %s", due)
+	}
+
+	due, _, err = ParseDue("2025-10-31 09:00 Europe/Berlin", now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if due.UTC().Hour() != 8 {
+		t.Errorf("Expected 08:00 UTC (CET), got %s", due.UTC())
+	}
+}
+
+func TestTaskDueInZones(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("time zone database not available")
+	}
+
+	// An all-day task for Oct 22 stored as UTC midnight (the legacy format)
+	// is not overdue on the evening of Oct 22 in New York.
+	due := time.Date(2025, 10, 22, 0, 0, 0, 0, time.UTC)
+	task := Task{ID: 1, Title: "Test", Due: &due}
+	evening := time.Date(2025, 10, 22, 21, 0, 0, 0, ny)
+	if task.IsOverdue(evening) {
+		t.Error("All-day task should not be overdue before its day ends")
+	}
+	if !task.IsDueSoon(evening, time.Hour) {
+		t.Error("All-day task should be due soon on its due date")
+	}
+	if !task.IsOverdue(evening.Add(4 * time.Hour)) {
+		t.Error("All-day task should be overdue after its day ends")
+	}
+
+	// The same instant as a timed task is long overdue
+	task.Timed = true
+	if !task.IsOverdue(evening) {
+		t.Error("Timed task should be overdue after its due time")
+	}
+}
+
+func TestParseDateInvalid(t *testing.T) {
+	now := time.Date(2025, 10, 22, 10, 15, 0, 0, time.UTC)
+
+	for _, input := range []string{"", "someday", "2025-13-01", "in 3 parsecs", "next", "25:00", "in 2 hours 14:00"} {
+		if _, err := ParseDate(input, now); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrSelfDependency is returned when a task is made to depend on itself
+var ErrSelfDependency = errors.New("a task cannot depend on itself")
+
+// UnknownDependencyError is returned when a dependency names a task that
+// does not exist
+type UnknownDependencyError struct {
+	ID int
+}
+
+func (e *UnknownDependencyError) Error() string {
+	return fmt.Sprintf("unknown dependency: task %d not found", e.ID)
+}
+
+// DependencyCycleError is returned when dependencies would form a cycle.
+// Path starts and ends with the same task, e.g. [1 2 1].
+type DependencyCycleError struct {
+	Path []int
+}
+
+func (e *DependencyCycleError) Error() string {
+	return "dependency cycle: " + joinIDs(e.Path, " → ")
+}
+
+// DependentsError is returned when removing a task other open tasks depend on
+// under RemoveFail
+type DependentsError struct {
+	ID         int
+	Dependents []int
+}
+
+func (e *DependentsError) Error() string {
+	return fmt.Sprintf("task %d is required by %s (remove with the cascade or rewrite policy)", e.ID, joinIDs(e.Dependents, ", "))
+}
+
+func joinIDs(ids []int, sep string) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = fmt.Sprintf("#%d", id)
+	}
+	return strings.Join(parts, sep)
+}
+
+// ValidateDependencies checks that the task with the given ID may depend on
+// deps: no task depends on itself, every dependency exists and no cycle is
+// formed. The task itself need not be in tasks yet.
+func ValidateDependencies(tasks []Task, id int, deps []int) error {
+	byID := make(map[int]*Task, len(tasks))
+	for i := range tasks {
+		byID[tasks[i].ID] = &tasks[i]
+	}
+
+	for _, dep := range deps {
+		if dep == id {
+			return ErrSelfDependency
+		}
+		if byID[dep] == nil {
+			return &UnknownDependencyError{ID: dep}
+		}
+	}
+
+	// Look for a path from any dependency back to the task
+	edges := func(n int) []int {
+		if n == id {
+			return deps
+		}
+		if t := byID[n]; t != nil {
+			return t.DependsOn
+		}
+		return nil
+	}
+
+	visited := make(map[int]bool)
+	var path []int
+	var visit func(n int) bool
+	visit = func(n int) bool {
+		if n == id && len(path) > 0 {
+			return true
+		}
+		if visited[n] {
+			return false
+		}
+		visited[n] = true
+		path = append(path, n)
+		for _, next := range edges(n) {
+			if visit(next) {
+				return true
+			}
+		}
+		path = path[:len(path)-1]
+		return false
+	}
+
+	if visit(id) {
+		return &DependencyCycleError{Path: append(path, id)}
+	}
+	return nil
+}
+
+// RemovePolicy decides what happens to tasks that depend on a removed task
+type RemovePolicy string
+
+const (
+	RemoveFail    RemovePolicy = "fail"    // refuse while open tasks depend on it (default)
+	RemoveCascade RemovePolicy = "cascade" // remove open dependents too, transitively
+	RemoveRewrite RemovePolicy = "rewrite" // dependents inherit the removed task's dependencies
+	RemoveKeep    RemovePolicy = "keep"    // dependents keep depending on it (trash only, default there)
+)
+
+// ParseRemovePolicy validates a removal policy; empty means RemoveFail
+func ParseRemovePolicy(s string) (RemovePolicy, error) {
+	switch p := RemovePolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case "":
+		return RemoveFail, nil
+	case RemoveFail, RemoveCascade, RemoveRewrite, RemoveKeep:
+		return p, nil
+	default:
+		return "", fmt.Errorf("invalid dependency policy %q (use fail, cascade, rewrite or keep)", s)
+	}
+}
+
+// Dependents returns the IDs of open tasks, outside the trash, that depend on
+// the given task
+func Dependents(tasks []Task, id int) []int {
+	var ids []int
+	for _, t := range tasks {
+		if !t.IsDone() && !t.IsTrashed() && containsID(t.DependsOn, id) {
+			ids = append(ids, t.ID)
+		}
+	}
+	return ids
+}
+
+// RemoveWithPolicy removes the task with the given ID, handling the tasks
+// that depend on it according to policy. It returns the remaining tasks and
+// the removed ones, the requested task first. Completed tasks never block
+// removal; references to removed tasks are dropped from them.
+func RemoveWithPolicy(tasks []Task, id int, policy RemovePolicy) ([]Task, []Task, error) {
+	if _, err := GetByID(tasks, id); err != nil {
+		return tasks, nil, err
+	}
+	if policy == RemoveKeep {
+		return tasks, nil, fmt.Errorf("dependency policy %q only applies to the trash", policy)
+	}
+	order, err := removalOrder(tasks, id, policy)
+	if err != nil {
+		return tasks, nil, err
+	}
+	remove := make(map[int]bool, len(order))
+	for _, rid := range order {
+		remove[rid] = true
+	}
+
+	removed := make([]Task, 0, len(order))
+	for _, rid := range order {
+		t, _ := GetByID(tasks, rid)
+		removed = append(removed, *t)
+	}
+
+	result := make([]Task, 0, len(tasks)-len(order))
+	for _, t := range tasks {
+		if remove[t.ID] {
+			continue
+		}
+		result = append(result, t)
+	}
+
+	// Drop references to removed tasks, such as those on completed dependents
+	dropReferences(result, remove)
+
+	return result, removed, nil
+}
+
+// removalOrder returns the IDs of the tasks to remove with the given task
+// under policy, the task itself first. RemoveRewrite rewrites the
+// dependents' dependencies in tasks.
+func removalOrder(tasks []Task, id int, policy RemovePolicy) ([]int, error) {
+	remove := map[int]bool{id: true}
+	order := []int{id}
+
+	switch policy {
+	case RemoveFail, "":
+		if dependents := Dependents(tasks, id); len(dependents) > 0 {
+			return nil, &DependentsError{ID: id, Dependents: dependents}
+		}
+	case RemoveCascade:
+		for i := 0; i < len(order); i++ {
+			for _, dep := range Dependents(tasks, order[i]) {
+				if !remove[dep] {
+					remove[dep] = true
+					order = append(order, dep)
+				}
+			}
+		}
+	case RemoveRewrite:
+		target, _ := GetByID(tasks, id)
+		inherited := target.DependsOn
+		for i := range tasks {
+			if containsID(tasks[i].DependsOn, id) {
+				tasks[i].DependsOn = mergeIDs(tasks[i].DependsOn, inherited, tasks[i].ID)
+			}
+		}
+	case RemoveKeep:
+	default:
+		return nil, fmt.Errorf("invalid dependency policy %q", policy)
+	}
+	return order, nil
+}
+
+// dropReferences removes the dependencies on the given tasks
+func dropReferences(tasks []Task, gone map[int]bool) {
+	for i := range tasks {
+		var kept []int
+		for _, dep := range tasks[i].DependsOn {
+			if !gone[dep] {
+				kept = append(kept, dep)
+			}
+		}
+		if len(kept) != len(tasks[i].DependsOn) {
+			tasks[i].DependsOn = kept
+		}
+	}
+}
+
+func containsID(ids []int, id int) bool {
+	for _, x := range ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeIDs appends extra to ids, skipping duplicates and self
+func mergeIDs(ids, extra []int, self int) []int {
+	merged := make([]int, 0, len(ids)+len(extra))
+	for _, id := range append(append([]int{}, ids...), extra...) {
+		if id != self && !containsID(merged, id) {
+			merged = append(merged, id)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,81 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidateDependencies(t *testing.T) {
+	// 3 depends on 2, 2 depends on 1
+	tasks := []Task{{ID: 1}, {ID: 2, DependsOn: []int{1}}, {ID: 3, DependsOn: []int{2}}, {ID: 4}}
+
+	if err := ValidateDependencies(tasks, 4, []int{1, 3}); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+	if err := ValidateDependencies(tasks, 5, []int{3}); err != nil {
+		t.Errorf("Unexpected error for a new task: %v", err)
+	}
+
+	if err := ValidateDependencies(tasks, 4, []int{4}); !errors.Is(err, ErrSelfDependency) {
+		t.Errorf("Expected ErrSelfDependency, got %v", err)
+	}
+
+	var unknown *UnknownDependencyError
+	if err := ValidateDependencies(tasks, 4, []int{1, 9}); !errors.As(err, &unknown) || unknown.ID != 9 {
+		t.Errorf("Expected UnknownDependencyError for 9, got %v", err)
+	}
+
+	var cycle *DependencyCycleError
+	err := ValidateDependencies(tasks, 1, []int{3})
+	if !errors.As(err, &cycle) {
+		t.Fatalf("Expected DependencyCycleError, got %v", err)
+	}
+	if !reflect.DeepEqual(cycle.Path, []int{1, 3, 2, 1}) {
+		t.Errorf("Expected path 1→3→2→1, got %v", cycle.Path)
+	}
+}
+
+func TestRemoveWithPolicy(t *testing.T) {
+	done := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	fixture := func() []Task {
+		// 3 depends on 2, 2 depends on 1; 4 is done and depends on 2
+		return []Task{
+			{ID: 1},
+			{ID: 2, DependsOn: []int{1}},
+			{ID: 3, DependsOn: []int{2}},
+			{ID: 4, DependsOn: []int{2}, DoneAt: &done},
+		}
+	}
+
+	var dependents *DependentsError
+	if _, _, err := RemoveWithPolicy(fixture(), 2, RemoveFail); !errors.As(err, &dependents) || !reflect.DeepEqual(dependents.Dependents, []int{3}) {
+		t.Errorf("Expected DependentsError listing #3, got %v", err)
+	}
+
+	// Done tasks do not block removal and lose the dangling reference
+	tasks, removed, err := RemoveWithPolicy(fixture(), 3, RemoveFail)
+	if err != nil || len(removed) != 1 || len(tasks) != 3 {
+		t.Fatalf("Expected #3 removed, got %v, %v", removed, err)
+	}
+
+	tasks, removed, err = RemoveWithPolicy(fixture(), 1, RemoveCascade)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(removed) != 3 || removed[0].ID != 1 || len(tasks) != 1 || tasks[0].ID != 4 {
+		t.Errorf("Expected #1, #2 and #3 removed, got %v", removed)
+	}
+	if len(tasks[0].DependsOn) != 0 {
+		t.Errorf("Expected dangling reference dropped, got %v", tasks[0].DependsOn)
+	}
+
+	tasks, _, err = RemoveWithPolicy(fixture(), 2, RemoveRewrite)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got, _ := GetByID(tasks, 3); !reflect.DeepEqual(got.DependsOn, []int{1}) {
+		t.Errorf("Expected #3 to inherit #1, got %v", got.DependsOn)
+	}
+}
@@ -0,0 +1,100 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultStoryPoint is the effort one story point stands for unless
+// configured otherwise
+const DefaultStoryPoint = 4 * time.Hour
+
+// Estimate is the expected effort for a task. Estimates given in story
+// points are converted to time when parsed; in JSON an estimate is written
+// as a duration string such as "1h30m".
+type Estimate time.Duration
+
+// Duration returns the estimate as a time.Duration
+func (e Estimate) Duration() time.Duration {
+	return time.Duration(e)
+}
+
+// String formats the estimate compactly, e.g. "2h", "1h30m" or "45m"
+func (e Estimate) String() string {
+	m := int(time.Duration(e).Round(time.Minute) / time.Minute)
+	switch {
+	case m < 60:
+		return fmt.Sprintf("%dm", m)
+	case m%60 == 0:
+		return fmt.Sprintf("%dh", m/60)
+	default:
+		return fmt.Sprintf("%dh%dm", m/60, m%60)
+	}
+}
+
+// MarshalJSON writes the estimate as a duration string
+func (e Estimate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(e.String())
+}
+
+// UnmarshalJSON reads a duration string such as "1h30m"
+func (e *Estimate) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("estimate must be a duration string: %w", err)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("invalid estimate %q: %w", s, err)
+	}
+	*e = Estimate(d)
+	return nil
+}
+
+// storyPointUnits are the suffixes that mark an estimate in story points
+var storyPointUnits = []string{"points", "point", "pts", "pt", "sp"}
+
+// ParseEstimate parses an effort estimate: a duration such as "30m", "2h" or
+// "1h30m", or story points such as "3pt" or "5 points", each worth point.
+func ParseEstimate(s string, point time.Duration) (Estimate, error) {
+	in := strings.ToLower(strings.TrimSpace(s))
+	for _, unit := range storyPointUnits {
+		if num, ok := strings.CutSuffix(in, unit); ok {
+			points, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+			if err != nil || points <= 0 {
+				break
+			}
+			return Estimate(time.Duration(points * float64(point)).Round(time.Minute)), nil
+		}
+	}
+
+	d, err := time.ParseDuration(strings.ReplaceAll(in, " ", ""))
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid estimate %q (e.g. 30m, 2h, 3pt)", s)
+	}
+	return Estimate(d), nil
+}
+
+// EstimateOrDefault is an EstimateFunc that uses the task's estimate, or
+// DefaultEstimate when it has none
+func EstimateOrDefault(t Task) time.Duration {
+	if t.Estimate > 0 {
+		return t.Estimate.Duration()
+	}
+	return DefaultEstimate
+}
+
+// ActualEffort returns how long a completed task took: the time tracked on
+// it, or the time from creation to completion when none was tracked
+func (t *Task) ActualEffort(now time.Time) time.Duration {
+	if len(t.TimeEntries) > 0 {
+		return t.TimeSpent(now)
+	}
+	if t.DoneAt == nil {
+		return 0
+	}
+	return t.DoneAt.Sub(t.CreatedAt)
+}
@@ -0,0 +1,96 @@
+package core
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestParseEstimate(t *testing.T) {
+	cases := map[string]time.Duration{
+		"30m":      30 * time.Minute,
+		"2h":       2 * time.Hour,
+		"1h 30m":   90 * time.Minute,
+		"3pt":      12 * time.Hour,
+		"0.5 pts":  2 * time.Hour,
+		"2 points": 8 * time.Hour,
+		"1SP":      4 * time.Hour,
+	}
+	for in, want := range cases {
+		if got, err := ParseEstimate(in, DefaultStoryPoint); err != nil || got.Duration() != want {
+			t.Errorf("ParseEstimate(%q) = %s, %v; want %s", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "soon", "-2h", "3", "pt"} {
+		if _, err := ParseEstimate(bad, DefaultStoryPoint); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestEstimateJSON(t *testing.T) {
+	task := Task{ID: 1, Estimate: Estimate(90 * time.Minute)}
+	data, err := json.Marshal(task)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var back Task
+	if err := json.Unmarshal(data, &back); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if back.Estimate != task.Estimate {
+		t.Errorf("Expected %s after a round trip, got %s", task.Estimate, back.Estimate)
+	}
+	if got := back.Estimate.String(); got != "1h30m" {
+		t.Errorf("Expected 1h30m, got %q", got)
+	}
+}
+
+func TestEstimateStats(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC) // a Wednesday
+	created := now.Add(-3 * time.Hour)
+	friday := time.Date(2025, 10, 24, 0, 0, 0, 0, time.UTC)
+	later := time.Date(2025, 11, 10, 0, 0, 0, 0, time.UTC)
+	tracked := now.Add(-time.Hour)
+	tasks := []Task{
+		// Estimated 2h, took 3h from creation to completion
+		{ID: 1, CreatedAt: created, DoneAt: &now, Estimate: Estimate(2 * time.Hour)},
+		// Estimated 1h, tracked 1h
+		{ID: 2, CreatedAt: created, DoneAt: &now, Estimate: Estimate(time.Hour),
+			TimeEntries: []TimeEntry{{Start: tracked, End: &now}}},
+		{ID: 3, Due: &friday, Estimate: Estimate(4 * time.Hour), Tags: []string{"Work"}},
+		{ID: 4, Due: &later, Estimate: Estimate(time.Hour), Tags: []string{"work", "home"}},
+		{ID: 5},
+	}
+
+	stats := CalculateStats(tasks, now)
+	if stats.EstimatedRemainingMS != (5 * time.Hour).Milliseconds() {
+		t.Errorf("Expected 5h remaining, got %dms", stats.EstimatedRemainingMS)
+	}
+	if stats.EstimatedWeekMS != (4 * time.Hour).Milliseconds() {
+		t.Errorf("Expected 4h due this week, got %dms", stats.EstimatedWeekMS)
+	}
+	if stats.EstimateByTagMS["work"] != (5*time.Hour).Milliseconds() || stats.EstimateByTagMS["home"] != time.Hour.Milliseconds() {
+		t.Errorf("Unexpected estimates by tag: %v", stats.EstimateByTagMS)
+	}
+	if stats.EstimateAccuracy != 75 {
+		t.Errorf("Expected 75%% accuracy (3h estimated, 4h actual), got %.1f", stats.EstimateAccuracy)
+	}
+}
+
+func TestCriticalPathUsesEstimates(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	tasks := []Task{
+		{ID: 1, Estimate: Estimate(3 * time.Hour)},
+		{ID: 2, DependsOn: []int{1}},
+	}
+
+	cp, err := FindCriticalPath(tasks, 2, now, EstimateOrDefault)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if cp.Duration != 3*time.Hour+DefaultEstimate {
+		t.Errorf("Expected %s of work, got %s", 3*time.Hour+DefaultEstimate, cp.Duration)
+	}
+}
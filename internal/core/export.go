@@ -0,0 +1,222 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// GraphFormat selects how a dependency graph is rendered
+type GraphFormat string
+
+const (
+	GraphDOT     GraphFormat = "dot"     // Graphviz
+	GraphMermaid GraphFormat = "mermaid" // Mermaid flowchart
+	GraphASCII   GraphFormat = "ascii"   // indented tree for the terminal
+)
+
+// ParseGraphFormat validates a graph format name; empty means ASCII
+func ParseGraphFormat(s string) (GraphFormat, error) {
+	switch f := GraphFormat(strings.ToLower(strings.TrimSpace(s))); f {
+	case "":
+		return GraphASCII, nil
+	case GraphDOT, GraphMermaid, GraphASCII:
+		return f, nil
+	case "graphviz", "gv":
+		return GraphDOT, nil
+	case "text", "tree":
+		return GraphASCII, nil
+	default:
+		return "", fmt.Errorf("invalid graph format %q (use dot, mermaid or ascii)", s)
+	}
+}
+
+// NodeState classifies a task for styling in a dependency graph
+type NodeState string
+
+const (
+	NodeReady     NodeState = "ready"
+	NodeBlocked   NodeState = "blocked"
+	NodeOverdue   NodeState = "overdue"
+	NodeDone      NodeState = "done"
+	NodeCancelled NodeState = "cancelled"
+)
+
+// StateOf returns how a task is drawn. State is judged against all tasks so
+// that dependencies filtered out of the graph still count.
+func StateOf(all []Task, t Task, now time.Time) NodeState {
+	switch {
+	case t.State() == StatusCancelled:
+		return NodeCancelled
+	case t.IsDone():
+		return NodeDone
+	case t.IsOverdue(now):
+		return NodeOverdue
+	case !AllDependenciesMet(all, t):
+		return NodeBlocked
+	default:
+		return NodeReady
+	}
+}
+
+// RenderGraph draws the dependency graph of the selected tasks, with an edge
+// from each dependency to the task that depends on it. Edges to tasks that
+// are not selected are left out; all is used to decide each node's state.
+func RenderGraph(format GraphFormat, selected, all []Task, now time.Time) (string, error) {
+	switch format {
+	case GraphDOT:
+		return renderDOT(selected, all, now), nil
+	case GraphMermaid:
+		return renderMermaid(selected, all, now), nil
+	case GraphASCII, "":
+		return renderASCII(selected, all, now), nil
+	default:
+		return "", fmt.Errorf("invalid graph format %q", format)
+	}
+}
+
+// graphEdges returns the dependency edges between selected tasks as
+// [from, to] pairs, in a stable order
+func graphEdges(selected []Task) [][2]int {
+	in := make(map[int]bool, len(selected))
+	for _, t := range selected {
+		in[t.ID] = true
+	}
+
+	var edges [][2]int
+	for _, t := range selected {
+		for _, dep := range t.DependsOn {
+			if in[dep] {
+				edges = append(edges, [2]int{dep, t.ID})
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+	return edges
+}
+
+func nodeLabel(t Task) string {
+	return fmt.Sprintf("#%d %s", t.ID, t.Title)
+}
+
+var dotStyles = map[NodeState]string{
+	NodeReady:     `shape=box`,
+	NodeBlocked:   `shape=box, style=dashed, color=orange`,
+	NodeOverdue:   `shape=box, color=red, penwidth=2`,
+	NodeDone:      `shape=box, style=filled, fillcolor=lightgray, fontcolor=gray40`,
+	NodeCancelled: `shape=box, style=dotted, fontcolor=gray40`,
+}
+
+func renderDOT(selected, all []Task, now time.Time) string {
+	var b strings.Builder
+	b.WriteString("digraph tasks {\n")
+	b.WriteString("  rankdir=LR;\n")
+	for _, t := range selected {
+		label := strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(nodeLabel(t))
+		fmt.Fprintf(&b, "  t%d [label=\"%s\", %s];\n", t.ID, label, dotStyles[StateOf(all, t, now)])
+	}
+	for _, e := range graphEdges(selected) {
+		fmt.Fprintf(&b, "  t%d -> t%d;\n", e[0], e[1])
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+func renderMermaid(selected, all []Task, now time.Time) string {
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	for _, t := range selected {
+		label := strings.NewReplacer(`"`, "#quot;").Replace(nodeLabel(t))
+		fmt.Fprintf(&b, "  t%d[\"%s\"]\n", t.ID, label)
+	}
+	for _, e := range graphEdges(selected) {
+		fmt.Fprintf(&b, "  t%d --> t%d\n", e[0], e[1])
+	}
+
+	b.WriteString("  classDef blocked stroke:#f90,stroke-dasharray:5 5\n")
+	b.WriteString("  classDef overdue stroke:#d00,stroke-width:3px\n")
+	b.WriteString("  classDef done fill:#ddd,color:#777\n")
+	b.WriteString("  classDef cancelled stroke-dasharray:2 2,color:#999\n")
+	for _, state := range []NodeState{NodeBlocked, NodeOverdue, NodeDone, NodeCancelled} {
+		var nodes []string
+		for _, t := range selected {
+			if StateOf(all, t, now) == state {
+				nodes = append(nodes, fmt.Sprintf("t%d", t.ID))
+			}
+		}
+		if len(nodes) > 0 {
+			fmt.Fprintf(&b, "  class %s %s\n", strings.Join(nodes, ","), state)
+		}
+	}
+	return b.String()
+}
+
+var asciiMarks = map[NodeState]string{
+	NodeReady:     "[ ]",
+	NodeBlocked:   "[…]",
+	NodeOverdue:   "[!]",
+	NodeDone:      "[✓]",
+	NodeCancelled: "[✗]",
+}
+
+// renderASCII prints each task under the tasks it depends on, starting from
+// tasks with no selected dependencies. A task reached again through another
+// dependency is printed once more with "(see above)" instead of its subtree.
+func renderASCII(selected, all []Task, now time.Time) string {
+	byID := make(map[int]Task, len(selected))
+	for _, t := range selected {
+		byID[t.ID] = t
+	}
+	dependents := make(map[int][]int)
+	hasDeps := make(map[int]bool)
+	for _, e := range graphEdges(selected) {
+		dependents[e[0]] = append(dependents[e[0]], e[1])
+		hasDeps[e[1]] = true
+	}
+
+	var b strings.Builder
+	printed := make(map[int]bool)
+	var walk func(id int, prefix, branch, indent string)
+	walk = func(id int, prefix, branch, indent string) {
+		t := byID[id]
+		line := fmt.Sprintf("%s%s%s %s", prefix, branch, asciiMarks[StateOf(all, t, now)], nodeLabel(t))
+		if printed[id] {
+			b.WriteString(line + " (see above)\n")
+			return
+		}
+		printed[id] = true
+		b.WriteString(line + "\n")
+
+		children := dependents[id]
+		for i, child := range children {
+			if i == len(children)-1 {
+				walk(child, prefix+indent, "└── ", "    ")
+			} else {
+				walk(child, prefix+indent, "├── ", "│   ")
+			}
+		}
+	}
+
+	for _, t := range selected {
+		if !hasDeps[t.ID] {
+			walk(t.ID, "", "", "")
+		}
+	}
+	// Tasks only reachable through a cycle
+	for _, t := range selected {
+		if !printed[t.ID] {
+			walk(t.ID, "", "", "")
+		}
+	}
+
+	if b.Len() > 0 {
+		b.WriteString("\n[ ] ready  […] blocked  [!] overdue  [✓] done  [✗] cancelled\n")
+	}
+	return b.String()
+}
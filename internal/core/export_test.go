@@ -0,0 +1,97 @@
+package core
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func exportFixture(now time.Time) []Task {
+	yesterday := now.AddDate(0, 0, -1)
+	return []Task{
+		{ID: 1, Title: "Spec", DoneAt: &now},
+		{ID: 2, Title: `Build "v2"`, DependsOn: []int{1}},
+		{ID: 3, Title: "Test", DependsOn: []int{2}},
+		{ID: 4, Title: "Docs", DependsOn: []int{1}, Due: &yesterday},
+		{ID: 5, Title: "Ship", DependsOn: []int{3, 4}},
+	}
+}
+
+func TestRenderGraphDOT(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	tasks := exportFixture(now)
+
+	out, err := RenderGraph(GraphDOT, tasks, tasks, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, want := range []string{
+		"digraph tasks {",
+		`t2 [label="#2 Build \"v2\"", shape=box];`,
+		`t1 [label="#1 Spec", shape=box, style=filled`,
+		`t3 [label="#3 Test", shape=box, style=dashed, color=orange];`,
+		`t4 [label="#4 Docs", shape=box, color=red, penwidth=2];`,
+		"t3 -> t5;",
+		"t4 -> t5;",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("DOT output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRenderGraphMermaid(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	tasks := exportFixture(now)
+
+	// Without the done task its edges disappear, but #2 is still ready
+	out, _ := RenderGraph(GraphMermaid, FilterByStatus(tasks, false), tasks, now)
+	for _, want := range []string{
+		"graph LR",
+		`t2["#2 Build #quot;v2#quot;"]`,
+		"t2 --> t3",
+		"class t3,t5 blocked",
+		"class t4 overdue",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Mermaid output missing %q:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "t1") {
+		t.Errorf("Expected the done task to be left out:\n%s", out)
+	}
+}
+
+func TestRenderGraphASCII(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	tasks := exportFixture(now)
+
+	out, _ := RenderGraph(GraphASCII, tasks, tasks, now)
+	expected := strings.Join([]string{
+		"[✓] #1 Spec",
+		`├── [ ] #2 Build "v2"`,
+		"│   └── […] #3 Test",
+		"│       └── […] #5 Ship",
+		"└── [!] #4 Docs",
+		"    └── […] #5 Ship (see above)",
+	}, "\n")
+	if !strings.HasPrefix(out, expected+"\n") {
+		t.Errorf("Expected:\n%s\ngot:\n%s", expected, out)
+	}
+
+	if out, _ := RenderGraph(GraphASCII, nil, tasks, now); out != "" {
+		t.Errorf("Expected empty output for no tasks, got %q", out)
+	}
+}
+
+func TestParseGraphFormat(t *testing.T) {
+	cases := map[string]GraphFormat{"": GraphASCII, "DOT": GraphDOT, "graphviz": GraphDOT, "mermaid": GraphMermaid, "tree": GraphASCII}
+	for in, want := range cases {
+		if got, err := ParseGraphFormat(in); err != nil || got != want {
+			t.Errorf("ParseGraphFormat(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseGraphFormat("svg"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -1,6 +1,7 @@
 package core
 
 import (
+	"fmt"
 	"strings"
 	"time"
 )
@@ -20,6 +21,33 @@ func FilterByStatus(tasks []Task, showAll bool) []Task {
 	return result
 }
 
+// FilterByStatusName filters tasks by a status name: "open" (the default),
+// "all", or one of the task statuses (see ParseStatus). "blocked" also
+// matches open tasks waiting on dependencies.
+func FilterByStatusName(tasks []Task, status string) ([]Task, error) {
+	switch strings.ToLower(strings.TrimSpace(status)) {
+	case "", "open", "pending":
+		return FilterByStatus(tasks, false), nil
+	case "all":
+		return tasks, nil
+	}
+
+	state, err := ParseStatus(status)
+	if err != nil {
+		return nil, fmt.Errorf("invalid status %q (use open, all, todo, in-progress, waiting, blocked, cancelled or done)", status)
+	}
+	if state == StatusBlocked {
+		result := make([]Task, 0)
+		for _, t := range tasks {
+			if t.State() == StatusBlocked || (!t.IsDone() && !AllDependenciesMet(tasks, t)) {
+				result = append(result, t)
+			}
+		}
+		return result, nil
+	}
+	return FilterByState(tasks, state), nil
+}
+
 // FilterByTags filters tasks by tags
 // tagStr format: "tag1,tag2" (comma = OR), "tag1+tag2" (plus = AND)
 func FilterByTags(tasks []Task, tagStr string) []Task {
@@ -84,7 +112,8 @@ func filterByTagsAND(tasks []Task, tagStr string) []Task {
 	return result
 }
 
-// FilterByDate filters tasks by due date range
+// FilterByDate filters tasks by due date range given as YYYY-MM-DD strings
+// (interpreted in UTC). Unparseable bounds are ignored.
 func FilterByDate(tasks []Task, beforeStr, afterStr string) []Task {
 	var before, after *time.Time
 
@@ -100,6 +129,14 @@ func FilterByDate(tasks []Task, beforeStr, afterStr string) []Task {
 		}
 	}
 
+	return FilterByDueRange(tasks, before, after)
+}
+
+// FilterByDueRange keeps tasks due no later than before and no earlier than
+// after (either bound may be nil). All-day tasks are placed at the start of
+// their due date in the bound's location, so a range built from the user's
+// day boundaries includes the right dates.
+func FilterByDueRange(tasks []Task, before, after *time.Time) []Task {
 	if before == nil && after == nil {
 		return tasks
 	}
@@ -110,11 +147,11 @@ func FilterByDate(tasks []Task, beforeStr, afterStr string) []Task {
 			continue
 		}
 
-		if before != nil && task.Due.After(*before) {
+		if before != nil && task.DueIn(before.Location()).After(*before) {
 			continue
 		}
 
-		if after != nil && task.Due.Before(*after) {
+		if after != nil && task.DueIn(after.Location()).Before(*after) {
 			continue
 		}
 
@@ -158,15 +195,16 @@ func SearchTasks(tasks []Task, query string) []Task {
 	return result
 }
 
-// FilterByDependencies returns tasks that have all dependencies met
-func FilterByDependencies(tasks []Task, onlyReady bool) []Task {
+// FilterByDependencies returns tasks that have all dependencies met and are
+// not scheduled to start after now
+func FilterByDependencies(tasks []Task, onlyReady bool, now time.Time) []Task {
 	if !onlyReady {
 		return tasks
 	}
 
 	result := make([]Task, 0)
 	for _, task := range tasks {
-		if task.IsDone() {
+		if task.IsDone() || task.IsDeferred(now) {
 			continue
 		}
 		if AllDependenciesMet(tasks, task) {
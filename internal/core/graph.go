@@ -0,0 +1,257 @@
+package core
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultEstimate is the duration assumed for a task without an estimate
+// when computing critical paths
+const DefaultEstimate = time.Hour
+
+// EstimateFunc returns how long a task is expected to take
+type EstimateFunc func(Task) time.Duration
+
+// TopoOrder returns the tasks ordered so that every task comes after the
+// tasks it depends on. Tasks that are otherwise unordered keep their input
+// order. Dependencies on tasks not in the list are ignored.
+func TopoOrder(tasks []Task) ([]Task, error) {
+	index := make(map[int]int, len(tasks))
+	for i, t := range tasks {
+		index[t.ID] = i
+	}
+
+	indegree := make([]int, len(tasks))
+	dependents := make([][]int, len(tasks))
+	for i, t := range tasks {
+		for _, dep := range t.DependsOn {
+			if j, ok := index[dep]; ok {
+				indegree[i]++
+				dependents[j] = append(dependents[j], i)
+			}
+		}
+	}
+
+	// Kahn's algorithm, always taking the earliest ready task
+	var ready []int
+	for i := range tasks {
+		if indegree[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+
+	order := make([]Task, 0, len(tasks))
+	for len(ready) > 0 {
+		sort.Ints(ready)
+		i := ready[0]
+		ready = ready[1:]
+		order = append(order, tasks[i])
+		for _, j := range dependents[i] {
+			indegree[j]--
+			if indegree[j] == 0 {
+				ready = append(ready, j)
+			}
+		}
+	}
+
+	if len(order) < len(tasks) {
+		for i, t := range tasks {
+			if indegree[i] > 0 {
+				if err := ValidateDependencies(tasks, t.ID, t.DependsOn); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return nil, fmt.Errorf("dependency cycle")
+	}
+	return order, nil
+}
+
+// Blocks returns the IDs of open tasks that directly or transitively depend
+// on the task with the given ID, in breadth-first order
+func Blocks(tasks []Task, id int) []int {
+	seen := map[int]bool{id: true}
+	queue := []int{id}
+	var blocked []int
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dep := range Dependents(tasks, current) {
+			if !seen[dep] {
+				seen[dep] = true
+				blocked = append(blocked, dep)
+				queue = append(queue, dep)
+			}
+		}
+	}
+	return blocked
+}
+
+// CriticalPath is the longest chain of open tasks that must be finished
+// before, and including, a target task
+type CriticalPath struct {
+	Tasks    []Task        `json:"tasks"`    // in the order they have to be done, target last
+	Duration time.Duration `json:"duration"` // total estimated work along the path
+	Finish   time.Time     `json:"finish"`   // earliest finish if work starts now
+	Slack    time.Duration `json:"slack"`    // time left before the target's deadline; negative when late
+	HasDue   bool          `json:"has_due"`  // whether the target has a due date (Slack is meaningful)
+}
+
+// MarshalJSON writes durations as strings such as "2h30m0s"
+func (cp CriticalPath) MarshalJSON() ([]byte, error) {
+	type plain CriticalPath
+	out := struct {
+		plain
+		Duration string `json:"duration"`
+		Slack    string `json:"slack,omitempty"`
+	}{plain: plain(cp), Duration: cp.Duration.Round(time.Second).String()}
+	if cp.HasDue {
+		out.Slack = cp.Slack.Round(time.Second).String()
+	}
+	return json.Marshal(out)
+}
+
+// FindCriticalPath computes the critical path to the target task. Each open
+// task takes estimate(task), or DefaultEstimate when estimate is nil; done
+// tasks take no time.
+func FindCriticalPath(tasks []Task, target int, now time.Time, estimate EstimateFunc) (CriticalPath, error) {
+	if estimate == nil {
+		estimate = func(Task) time.Duration { return DefaultEstimate }
+	}
+
+	byID := make(map[int]Task, len(tasks))
+	for _, t := range tasks {
+		byID[t.ID] = t
+	}
+	goal, ok := byID[target]
+	if !ok {
+		return CriticalPath{}, fmt.Errorf("task %d not found", target)
+	}
+
+	// Longest path by memoized depth-first search over open dependencies
+	length := make(map[int]time.Duration)
+	next := make(map[int]int) // the dependency the path continues through
+	var visit func(id int) time.Duration
+	visit = func(id int) time.Duration {
+		if d, ok := length[id]; ok {
+			return d
+		}
+		t, ok := byID[id]
+		if !ok || t.IsDone() {
+			length[id] = 0
+			return 0
+		}
+		length[id] = 0 // guards against cycles in corrupt data
+		var best time.Duration
+		for _, dep := range t.DependsOn {
+			if d := visit(dep); d > best {
+				best = d
+				next[id] = dep
+			}
+		}
+		length[id] = best + estimate(t)
+		return length[id]
+	}
+	total := visit(target)
+
+	var path []Task
+	for id := target; id != 0; id = next[id] {
+		if t := byID[id]; !t.IsDone() {
+			path = append([]Task{t}, path...)
+		}
+	}
+
+	cp := CriticalPath{Tasks: path, Duration: total, Finish: now.Add(total)}
+	if goal.Due != nil {
+		cp.HasDue = true
+		cp.Slack = goal.Deadline(now.Location()).Sub(cp.Finish)
+	}
+	return cp, nil
+}
+
+// Recommendation is an actionable task with the reasons it was ranked
+type Recommendation struct {
+	Task     Task     `json:"task"`
+	Score    float64  `json:"score"`
+	Unblocks int      `json:"unblocks"` // open tasks waiting on it, directly or transitively
+	Reasons  []string `json:"reasons"`
+}
+
+// RankActionable ranks the tasks that can be worked on right now: open, not
+// waiting, blocked or deferred, with all dependencies met and no open
+// subtasks. The score adds up
+//
+//	priority     2 per level (low 2, medium 4, high 6)
+//	due date     overdue 6, due within a day 4, within 3 days 2, within a week 1
+//	unblocking   1 per open task waiting on it, up to 5
+//
+// Ties are broken by due date, then ID.
+func RankActionable(tasks []Task, now time.Time) []Recommendation {
+	var recs []Recommendation
+	for _, t := range tasks {
+		if t.IsDone() || t.IsDeferred(now) || !AllDependenciesMet(tasks, t) {
+			continue
+		}
+		if s := t.State(); s == StatusWaiting || s == StatusBlocked {
+			continue
+		}
+		if done, total := Progress(tasks, t.ID); done < total {
+			continue
+		}
+
+		rec := Recommendation{Task: t}
+		rec.Score = float64(2 * NormalizePriority(t.Priority))
+		switch NormalizePriority(t.Priority) {
+		case int(PriorityHigh):
+			rec.Reasons = append(rec.Reasons, "high priority")
+		case int(PriorityMedium):
+			rec.Reasons = append(rec.Reasons, "medium priority")
+		}
+
+		if t.Due != nil {
+			switch {
+			case t.IsOverdue(now):
+				rec.Score += 6
+				rec.Reasons = append(rec.Reasons, "overdue")
+			case t.IsDueSoon(now, 24*time.Hour):
+				rec.Score += 4
+				rec.Reasons = append(rec.Reasons, "due within a day")
+			case t.IsDueSoon(now, 3*24*time.Hour):
+				rec.Score += 2
+				rec.Reasons = append(rec.Reasons, "due within 3 days")
+			case t.IsDueSoon(now, 7*24*time.Hour):
+				rec.Score += 1
+				rec.Reasons = append(rec.Reasons, "due this week")
+			}
+		}
+
+		rec.Unblocks = len(Blocks(tasks, t.ID))
+		if rec.Unblocks > 0 {
+			bonus := rec.Unblocks
+			if bonus > 5 {
+				bonus = 5
+			}
+			rec.Score += float64(bonus)
+			rec.Reasons = append(rec.Reasons, fmt.Sprintf("unblocks %d task(s)", rec.Unblocks))
+		}
+
+		recs = append(recs, rec)
+	}
+
+	sort.SliceStable(recs, func(i, j int) bool {
+		a, b := recs[i], recs[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if (a.Task.Due == nil) != (b.Task.Due == nil) {
+			return a.Task.Due != nil
+		}
+		if a.Task.Due != nil && !a.Task.Due.Equal(*b.Task.Due) {
+			return a.Task.Due.Before(*b.Task.Due)
+		}
+		return a.Task.ID < b.Task.ID
+	})
+	return recs
+}
@@ -0,0 +1,122 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// graphFixture: 1 ← 2 ← 3 ← 5 and 1 ← 4 ← 5, plus an unrelated 6
+func graphFixture(now time.Time) []Task {
+	tomorrow := now.AddDate(0, 0, 1)
+	return []Task{
+		{ID: 5, Title: "Ship", DependsOn: []int{3, 4}, Due: &tomorrow, Priority: 1},
+		{ID: 4, Title: "Docs", DependsOn: []int{1}, Priority: 1},
+		{ID: 3, Title: "Test", DependsOn: []int{2}, Priority: 1},
+		{ID: 2, Title: "Build", DependsOn: []int{1}, Priority: 3},
+		{ID: 1, Title: "Spec", Priority: 2},
+		{ID: 6, Title: "Laundry", Priority: 1},
+	}
+}
+
+func taskIDs(tasks []Task) []int {
+	result := make([]int, len(tasks))
+	for i, t := range tasks {
+		result[i] = t.ID
+	}
+	return result
+}
+
+func TestTopoOrder(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+
+	order, err := TopoOrder(graphFixture(now))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := taskIDs(order); !reflect.DeepEqual(got, []int{1, 4, 2, 3, 5, 6}) {
+		t.Errorf("Unexpected order %v", got)
+	}
+
+	cyclic := []Task{{ID: 1, DependsOn: []int{2}}, {ID: 2, DependsOn: []int{1}}, {ID: 3, DependsOn: []int{2}}}
+	var cycle *DependencyCycleError
+	if _, err := TopoOrder(cyclic); !errors.As(err, &cycle) {
+		t.Errorf("Expected DependencyCycleError, got %v", err)
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	tasks := graphFixture(now)
+
+	if got := Blocks(tasks, 1); !reflect.DeepEqual(got, []int{4, 2, 5, 3}) {
+		t.Errorf("Expected #1 to block 4, 2, 5, 3, got %v", got)
+	}
+	if got := Blocks(tasks, 6); len(got) != 0 {
+		t.Errorf("Expected #6 to block nothing, got %v", got)
+	}
+}
+
+func TestFindCriticalPath(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	tasks := graphFixture(now)
+
+	cp, err := FindCriticalPath(tasks, 5, now, nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := taskIDs(cp.Tasks); !reflect.DeepEqual(got, []int{1, 2, 3, 5}) {
+		t.Errorf("Expected path 1→2→3→5, got %v", got)
+	}
+	if cp.Duration != 4*DefaultEstimate || !cp.HasDue {
+		t.Errorf("Unexpected duration %s", cp.Duration)
+	}
+
+	// Estimates can reroute the path; done tasks take no time
+	tasks[4].DoneAt = &now
+	long := func(task Task) time.Duration {
+		if task.ID == 4 {
+			return 10 * time.Hour
+		}
+		return time.Hour
+	}
+	cp, _ = FindCriticalPath(tasks, 5, now, long)
+	if got := taskIDs(cp.Tasks); !reflect.DeepEqual(got, []int{4, 5}) {
+		t.Errorf("Expected path 4→5, got %v", got)
+	}
+	if cp.Duration != 11*time.Hour {
+		t.Errorf("Expected 11h, got %s", cp.Duration)
+	}
+	if cp.Slack != 28*time.Hour-time.Nanosecond {
+		t.Errorf("Expected slack until the end of the due date, got %s", cp.Slack)
+	}
+}
+
+func TestRankActionable(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	tasks := graphFixture(now)
+	yesterday := now.AddDate(0, 0, -1)
+	tasks[5].Due = &yesterday
+
+	recs := RankActionable(tasks, now)
+	if len(recs) != 2 {
+		t.Fatalf("Expected only #1 and #6 to be actionable, got %d", len(recs))
+	}
+	// Spec: medium (4) + unblocks 4 (4) = 8; Laundry: low (2) + overdue (6) = 8,
+	// the tie goes to the task with a due date
+	if recs[0].Task.ID != 6 || recs[1].Task.ID != 1 {
+		t.Errorf("Expected #6 then #1, got #%d then #%d", recs[0].Task.ID, recs[1].Task.ID)
+	}
+	if recs[1].Unblocks != 4 || recs[1].Score != 8 {
+		t.Errorf("Unexpected ranking for #1: %+v", recs[1])
+	}
+
+	// A parent with open subtasks is not itself actionable
+	tasks = append(tasks, Task{ID: 7, Title: "Subtask", ParentID: 6})
+	for _, rec := range RankActionable(tasks, now) {
+		if rec.Task.ID == 6 {
+			t.Error("Expected #6 to wait for its subtask")
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Reserved project names used when selecting tasks
+const (
+	ProjectAll  = "all"  // tasks in any project, or none
+	ProjectNone = "none" // tasks that are not in a project
+)
+
+// NormalizeProject validates a project name and returns its canonical
+// (lower-case) form. Names may contain letters, digits, '-', '_' and '.';
+// the empty string means no project.
+func NormalizeProject(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == ProjectAll || name == ProjectNone {
+		return "", fmt.Errorf("project name %q is reserved", name)
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return "", fmt.Errorf("invalid project name %q (use letters, digits, '-', '_' and '.')", name)
+		}
+	}
+	return name, nil
+}
+
+// InProject reports whether the task matches a project selection: empty and
+// "all" match every task, "none" matches tasks that are not in a project
+func (t *Task) InProject(project string) bool {
+	switch project = strings.ToLower(strings.TrimSpace(project)); project {
+	case "", ProjectAll:
+		return true
+	case ProjectNone:
+		return t.Project == ""
+	default:
+		return t.Project == project
+	}
+}
+
+// FilterByProject keeps the tasks matching a project selection (see
+// InProject)
+func FilterByProject(tasks []Task, project string) []Task {
+	result := make([]Task, 0, len(tasks))
+	for _, t := range tasks {
+		if t.InProject(project) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// MoveToProject moves the task with the given ID, and its subtasks, to a
+// project ("" for none). A recurring task's later occurrences follow it.
+func MoveToProject(tasks []Task, id int, project string) error {
+	if _, err := GetByID(tasks, id); err != nil {
+		return err
+	}
+	for i := range tasks {
+		t := &tasks[i]
+		if t.ID != id && !IsDescendant(tasks, t.ID, id) {
+			continue
+		}
+		t.Project = project
+		if t.Pattern != nil {
+			t.Pattern.Project = project
+		}
+	}
+	return nil
+}
+
+// ProjectSummary holds per-project counts
+type ProjectSummary struct {
+	Name           string  `json:"name"` // empty for tasks without a project
+	Total          int     `json:"total"`
+	Completed      int     `json:"completed"`
+	Pending        int     `json:"pending"`
+	Overdue        int     `json:"overdue"`
+	CompletionRate float64 `json:"completion_rate"`
+}
+
+// Projects summarizes every project that has tasks, sorted by name, with
+// tasks outside any project last
+func Projects(tasks []Task, now time.Time) []ProjectSummary {
+	byName := make(map[string][]Task)
+	for _, t := range tasks {
+		byName[t.Project] = append(byName[t.Project], t)
+	}
+
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if (names[i] == "") != (names[j] == "") {
+			return names[j] == ""
+		}
+		return names[i] < names[j]
+	})
+
+	summaries := make([]ProjectSummary, 0, len(names))
+	for _, name := range names {
+		stats := CalculateStatsIn(byName[name], tasks, now)
+		summaries = append(summaries, ProjectSummary{
+			Name:           name,
+			Total:          stats.Total,
+			Completed:      stats.Completed,
+			Pending:        stats.Pending,
+			Overdue:        stats.Overdue,
+			CompletionRate: stats.CompletionRate,
+		})
+	}
+	return summaries
+}
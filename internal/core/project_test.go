@@ -0,0 +1,99 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNormalizeProject(t *testing.T) {
+	if got, err := NormalizeProject(" Work-2025 "); err != nil || got != "work-2025" {
+		t.Errorf("Expected work-2025, got %q, %v", got, err)
+	}
+	for _, bad := range []string{"all", "None", "my project", "a/b"} {
+		if _, err := NormalizeProject(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestFilterByProject(t *testing.T) {
+	tasks := []Task{{ID: 1, Project: "work"}, {ID: 2}, {ID: 3, Project: "home"}}
+
+	cases := map[string][]int{
+		"":     {1, 2, 3},
+		"all":  {1, 2, 3},
+		"none": {2},
+		"Work": {1},
+		"gym":  {},
+	}
+	for project, want := range cases {
+		got := taskIDs(FilterByProject(tasks, project))
+		if len(got) != len(want) {
+			t.Errorf("FilterByProject(%q) = %v, want %v", project, got, want)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("FilterByProject(%q) = %v, want %v", project, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestMoveToProject(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Project: "work"},
+		{ID: 2, ParentID: 1, Project: "work"},
+		{ID: 3, ParentID: 2, Project: "work"},
+		{ID: 4, Project: "work", Repeat: "weekly", Pattern: &Task{Project: "work"}},
+	}
+
+	if err := MoveToProject(tasks, 1, "home"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, task := range tasks[:3] {
+		if task.Project != "home" {
+			t.Errorf("Expected #%d to move with its parent, got %q", task.ID, task.Project)
+		}
+	}
+	if tasks[3].Project != "work" {
+		t.Errorf("Expected #4 to stay, got %q", tasks[3].Project)
+	}
+
+	_ = MoveToProject(tasks, 4, "")
+	if tasks[3].Project != "" || tasks[3].Pattern.Project != "" {
+		t.Error("Expected the series pattern to move with the occurrence")
+	}
+	if err := MoveToProject(tasks, 9, "home"); err == nil {
+		t.Error("Expected an error for an unknown task")
+	}
+}
+
+func TestProjects(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	tasks := []Task{
+		{ID: 1, Project: "work", DoneAt: &now},
+		{ID: 2, Project: "work", DependsOn: []int{3}},
+		{ID: 3},
+		{ID: 4, Project: "home"},
+	}
+
+	projects := Projects(tasks, now)
+	if len(projects) != 3 || projects[0].Name != "home" || projects[1].Name != "work" || projects[2].Name != "" {
+		t.Fatalf("Expected home, work, then no project, got %+v", projects)
+	}
+	if projects[1].Total != 2 || projects[1].CompletionRate != 50 {
+		t.Errorf("Unexpected summary for work: %+v", projects[1])
+	}
+
+	// #2 waits on a task outside the project
+	work := FilterByProject(tasks, "work")
+	if stats := CalculateStatsIn(work, tasks, now); stats.BlockedTasks != 1 {
+		t.Errorf("Expected #2 to count as blocked, got %d", stats.BlockedTasks)
+	}
+	tasks[2].DoneAt = &now
+	if stats := CalculateStatsIn(work, tasks, now); stats.BlockedTasks != 0 {
+		t.Errorf("Expected no blocked tasks once #3 is done, got %d", stats.BlockedTasks)
+	}
+}
@@ -0,0 +1,719 @@
+package core
+
+import (
+	"cmp"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Filter is a parsed filter expression, for example
+//
+//	tag:work and (priority>=2 or due<+3d) and not status:done
+//
+// Terms are "field op value" comparisons or bare words, which match the
+// title or description like SearchTasks. Terms are combined with "and",
+// "or" and "not" and grouped with parentheses; terms written next to each
+// other must all match. Values containing spaces or operators are quoted
+// with double quotes.
+//
+// Fields, the values they take and the operators they accept. ":" is the
+// same as "=" except on the text fields, where it matches a substring.
+//
+//	tag          a tag, "none" or "any"                 : = !=
+//	priority, p  1-3 or low, medium, high               : = != < <= > >=
+//	due          a date (see ParseDate), "none", "any"  : = != < <= > >=
+//	scheduled    likewise                               : = != < <= > >=
+//	created      likewise                               : = != < <= > >=
+//	done         likewise                               : = != < <= > >=
+//	status       open, closed or a status               : = !=
+//	project      a project, "none" or "all"             : = !=
+//	title        text                                   : = !=
+//	desc         text                                   : = !=
+//	text         text in the title or description       :
+//	id           a task ID                              : = != < <= > >=
+//	estimate     a duration such as 2h, "none", "any"   : = != < <= > >=
+//	is           open, closed, overdue, blocked,        : = !=
+//	             deferred, recurring or running
+//
+// Dates without a time of day cover the whole day, so "due:today" matches
+// anything due today and "due<+3d" anything due before the day three days
+// from now.
+type Filter struct {
+	expr   string
+	root   filterNode
+	now    time.Time
+	fields map[string]bool
+}
+
+// FilterError reports where a filter expression is malformed
+type FilterError struct {
+	Expr string
+	Pos  int // byte offset in Expr
+	Msg  string
+}
+
+func (e *FilterError) Error() string {
+	return fmt.Sprintf("invalid filter: %s (at column %d)", e.Msg, e.Pos+1)
+}
+
+// Caret returns the expression with a caret under the error position
+func (e *FilterError) Caret() string {
+	return e.Expr + "\n" + strings.Repeat(" ", e.Pos) + "^"
+}
+
+// ParseFilter parses a filter expression. Relative dates such as "+3d" or
+// "today" are resolved against now.
+func ParseFilter(expr string, now time.Time) (*Filter, error) {
+	p := &filterParser{expr: expr, now: now, fields: make(map[string]bool)}
+	if err := p.tokenize(); err != nil {
+		return nil, err
+	}
+	if p.peek().kind == tokEOF {
+		return nil, p.errorAt(p.peek(), "empty expression")
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		if tok.kind == tokRParen {
+			return nil, p.errorAt(tok, "unexpected ')'")
+		}
+		return nil, p.errorAt(tok, fmt.Sprintf("unexpected %s", tok))
+	}
+	return &Filter{expr: expr, root: root, now: now, fields: p.fields}, nil
+}
+
+// String returns the expression the filter was parsed from
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// Uses reports whether the expression has a term on a field, by its
+// canonical name: "status" also covers is:open and is:closed, "scheduled"
+// covers is:deferred
+func (f *Filter) Uses(field string) bool {
+	return f.fields[field]
+}
+
+// Match reports whether a task matches the filter. all is the full task
+// list, used to tell whether the task is blocked by its dependencies.
+func (f *Filter) Match(t Task, all []Task) bool {
+	return f.root.match(&t, &filterContext{all: all, now: f.now})
+}
+
+// Apply returns the tasks that match the filter
+func (f *Filter) Apply(tasks, all []Task) []Task {
+	result := make([]Task, 0, len(tasks))
+	for _, t := range tasks {
+		if f.Match(t, all) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+type filterContext struct {
+	all []Task
+	now time.Time
+}
+
+type filterNode interface {
+	match(t *Task, c *filterContext) bool
+}
+
+type andNode struct{ left, right filterNode }
+type orNode struct{ left, right filterNode }
+type notNode struct{ inner filterNode }
+type predNode func(t *Task, c *filterContext) bool
+
+func (n andNode) match(t *Task, c *filterContext) bool {
+	return n.left.match(t, c) && n.right.match(t, c)
+}
+
+func (n orNode) match(t *Task, c *filterContext) bool {
+	return n.left.match(t, c) || n.right.match(t, c)
+}
+
+func (n notNode) match(t *Task, c *filterContext) bool {
+	return !n.inner.match(t, c)
+}
+
+func (n predNode) match(t *Task, c *filterContext) bool {
+	return n(t, c)
+}
+
+// Lexing
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return strconv.Quote(t.text)
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+func isOpChar(r byte) bool {
+	return r == ':' || r == '=' || r == '!' || r == '<' || r == '>'
+}
+
+type filterParser struct {
+	expr   string
+	now    time.Time
+	tokens []token
+	next   int
+	fields map[string]bool
+}
+
+func (p *filterParser) errorAt(tok token, msg string) error {
+	return &FilterError{Expr: p.expr, Pos: tok.pos, Msg: msg}
+}
+
+// tokenize splits the expression into tokens. The value after an operator
+// runs to the next space or closing parenthesis, so it may itself contain
+// operator characters ("due<2025-10-31T14:30").
+func (p *filterParser) tokenize() error {
+	s := p.expr
+	afterOp := false
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case unicode.IsSpace(rune(c)):
+			i++
+			afterOp = false
+			continue
+		case c == '(':
+			p.tokens = append(p.tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			p.tokens = append(p.tokens, token{tokRParen, ")", i})
+			i++
+		case c == '"':
+			start := i
+			var b strings.Builder
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				b.WriteByte(s[i])
+			}
+			if i >= len(s) {
+				return &FilterError{Expr: s, Pos: start, Msg: "unterminated string"}
+			}
+			i++
+			p.tokens = append(p.tokens, token{tokString, b.String(), start})
+		case isOpChar(c) && !afterOp:
+			start := i
+			for i < len(s) && isOpChar(s[i]) {
+				i++
+			}
+			op := s[start:i]
+			switch op {
+			case ":", "=", "!=", "<", "<=", ">", ">=":
+			default:
+				return &FilterError{Expr: s, Pos: start, Msg: fmt.Sprintf("unknown operator %q", op)}
+			}
+			p.tokens = append(p.tokens, token{tokOp, op, start})
+			afterOp = true
+			continue
+		default:
+			start := i
+			for i < len(s) && !unicode.IsSpace(rune(s[i])) && s[i] != '(' && s[i] != ')' && s[i] != '"' &&
+				(afterOp || !isOpChar(s[i])) {
+				i++
+			}
+			p.tokens = append(p.tokens, token{tokWord, s[start:i], start})
+		}
+		afterOp = false
+	}
+	p.tokens = append(p.tokens, token{tokEOF, "", len(s)})
+	return nil
+}
+
+// Parsing
+//
+//	or      = and { "or" and }
+//	and     = unary { ["and"] unary }
+//	unary   = "not" unary | primary
+//	primary = "(" or ")" | field op value | word | string
+
+func (p *filterParser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *filterParser) advance() token {
+	tok := p.tokens[p.next]
+	if tok.kind != tokEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *filterParser) isKeyword(tok token, kw string) bool {
+	return tok.kind == tokWord && strings.EqualFold(tok.text, kw)
+}
+
+func (p *filterParser) parseOr() (filterNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isKeyword(p.peek(), "or") {
+		p.advance()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *filterParser) parseAnd() (filterNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		tok := p.peek()
+		if p.isKeyword(tok, "and") {
+			p.advance()
+		} else if tok.kind == tokEOF || tok.kind == tokRParen || p.isKeyword(tok, "or") {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *filterParser) parseUnary() (filterNode, error) {
+	if p.isKeyword(p.peek(), "not") {
+		p.advance()
+		inner, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *filterParser) parsePrimary() (filterNode, error) {
+	tok := p.advance()
+	switch tok.kind {
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.advance(); closing.kind != tokRParen {
+			return nil, p.errorAt(closing, fmt.Sprintf("expected ')' to close the '(' at column %d, found %s", tok.pos+1, closing))
+		}
+		return inner, nil
+	case tokString:
+		return textNode(tok.text), nil
+	case tokWord:
+		if p.peek().kind == tokOp {
+			return p.parseTerm(tok)
+		}
+		for _, kw := range []string{"and", "or", "not"} {
+			if p.isKeyword(tok, kw) {
+				return nil, p.errorAt(tok, fmt.Sprintf("expected a term before or after %q", tok.text))
+			}
+		}
+		return textNode(tok.text), nil
+	case tokOp:
+		return nil, p.errorAt(tok, fmt.Sprintf("expected a field name before %q", tok.text))
+	case tokRParen:
+		return nil, p.errorAt(tok, "unexpected ')'")
+	default:
+		return nil, p.errorAt(tok, "expected a term at end of expression")
+	}
+}
+
+// textNode matches a word in the title or description, like SearchTasks
+func textNode(word string) filterNode {
+	word = strings.ToLower(word)
+	return predNode(func(t *Task, _ *filterContext) bool {
+		return strings.Contains(strings.ToLower(t.Title), word) || strings.Contains(strings.ToLower(t.Description), word)
+	})
+}
+
+// fieldAliases maps the accepted field names to their canonical names
+var fieldAliases = map[string]string{
+	"tag":         "tag",
+	"tags":        "tag",
+	"priority":    "priority",
+	"pri":         "priority",
+	"p":           "priority",
+	"due":         "due",
+	"scheduled":   "scheduled",
+	"created":     "created",
+	"done":        "done",
+	"completed":   "done",
+	"status":      "status",
+	"state":       "status",
+	"project":     "project",
+	"title":       "title",
+	"desc":        "description",
+	"description": "description",
+	"text":        "text",
+	"id":          "id",
+	"estimate":    "estimate",
+	"is":          "is",
+}
+
+const filterFieldNames = "tag, priority, due, scheduled, created, done, status, project, title, desc, text, id, estimate or is"
+
+func (p *filterParser) parseTerm(fieldTok token) (filterNode, error) {
+	opTok := p.advance()
+	valTok := p.advance()
+	if valTok.kind != tokWord && valTok.kind != tokString {
+		return nil, p.errorAt(valTok, fmt.Sprintf("expected a value after %s%s", fieldTok.text, opTok.text))
+	}
+
+	field, ok := fieldAliases[strings.ToLower(fieldTok.text)]
+	if !ok {
+		return nil, p.errorAt(fieldTok, fmt.Sprintf("unknown field %q (use %s)", fieldTok.text, filterFieldNames))
+	}
+	op := opTok.text
+	if op == ":" && field != "title" && field != "description" && field != "text" {
+		op = "="
+	}
+	value := valTok.text
+
+	t := term{p: p, field: field, op: op, opTok: opTok, valTok: valTok, value: value}
+	var node filterNode
+	var err error
+	switch field {
+	case "tag":
+		node, err = t.tag()
+	case "priority":
+		node, err = t.priority()
+	case "due", "scheduled", "created", "done":
+		node, err = t.date()
+	case "status":
+		node, err = t.status()
+	case "project":
+		node, err = t.project()
+	case "title", "description", "text":
+		node, err = t.text()
+	case "id":
+		node, err = t.id()
+	case "estimate":
+		node, err = t.estimate()
+	case "is":
+		node, err = t.is()
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.fields[field] = true
+	return node, nil
+}
+
+// term is one field comparison being parsed
+type term struct {
+	p      *filterParser
+	field  string
+	op     string // ":" only for the text fields, otherwise "=" and the others
+	opTok  token
+	valTok token
+	value  string
+}
+
+func (t term) valueError(msg string) error {
+	return t.p.errorAt(t.valTok, msg)
+}
+
+// equalityOnly rejects ordering operators on fields that have no order
+func (t term) equalityOnly() error {
+	if t.op != "=" && t.op != "!=" {
+		return t.p.errorAt(t.opTok, fmt.Sprintf("operator %q is not supported for %s (use : or !=)", t.op, t.field))
+	}
+	return nil
+}
+
+// equality builds a node for an equality test, negated for "!="
+func (t term) equality(eq func(*Task, *filterContext) bool) filterNode {
+	if t.op == "!=" {
+		return predNode(func(task *Task, c *filterContext) bool { return !eq(task, c) })
+	}
+	return predNode(eq)
+}
+
+// ordered builds a node comparing a task's value with the term's using cmp,
+// which returns -1, 0 or 1. Tasks without a value (ok false) match only "!=".
+func (t term) ordered(cmp func(*Task) (c int, ok bool)) filterNode {
+	op := t.op
+	return predNode(func(task *Task, _ *filterContext) bool {
+		c, ok := cmp(task)
+		if !ok {
+			return op == "!="
+		}
+		switch op {
+		case "=":
+			return c == 0
+		case "!=":
+			return c != 0
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		default:
+			return c >= 0
+		}
+	})
+}
+
+// presence handles the "none" and "any" values of optional fields
+func (t term) presence(has func(*Task) bool) (filterNode, bool, error) {
+	var want bool
+	switch strings.ToLower(t.value) {
+	case "none":
+		want = false
+	case "any":
+		want = true
+	default:
+		return nil, false, nil
+	}
+	if err := t.equalityOnly(); err != nil {
+		return nil, true, err
+	}
+	return t.equality(func(task *Task, _ *filterContext) bool { return has(task) == want }), true, nil
+}
+
+func (t term) tag() (filterNode, error) {
+	if err := t.equalityOnly(); err != nil {
+		return nil, err
+	}
+	if node, ok, err := t.presence(func(task *Task) bool { return len(task.Tags) > 0 }); ok {
+		return node, err
+	}
+	tag := t.value
+	return t.equality(func(task *Task, _ *filterContext) bool { return task.HasTag(tag) }), nil
+}
+
+func (t term) priority() (filterNode, error) {
+	var want int
+	switch strings.ToLower(t.value) {
+	case "low", "l", "1":
+		want = int(PriorityLow)
+	case "medium", "med", "m", "2":
+		want = int(PriorityMedium)
+	case "high", "h", "3":
+		want = int(PriorityHigh)
+	default:
+		return nil, t.valueError(fmt.Sprintf("invalid priority %q (use 1-3, low, medium or high)", t.value))
+	}
+	return t.ordered(func(task *Task) (int, bool) {
+		p := NormalizePriority(task.Priority)
+		return cmp.Compare(p, want), true
+	}), nil
+}
+
+func (t term) date() (filterNode, error) {
+	get := func(task *Task, loc *time.Location) (time.Time, bool) {
+		switch t.field {
+		case "due":
+			if task.Due == nil {
+				return time.Time{}, false
+			}
+			return task.DueIn(loc), true
+		case "scheduled":
+			if task.Scheduled == nil {
+				return time.Time{}, false
+			}
+			return task.AvailableAt(loc), true
+		case "created":
+			return task.CreatedAt, !task.CreatedAt.IsZero()
+		default:
+			if task.DoneAt == nil {
+				return time.Time{}, false
+			}
+			return *task.DoneAt, true
+		}
+	}
+	loc := t.p.now.Location()
+	if node, ok, err := t.presence(func(task *Task) bool { _, has := get(task, loc); return has }); ok {
+		return node, err
+	}
+
+	at, timed, err := ParseDue(t.value, t.p.now)
+	if err != nil {
+		return nil, t.valueError(fmt.Sprintf("invalid date %q for %s (try YYYY-MM-DD, today, +3d or \"next friday\")", t.value, t.field))
+	}
+	// A date without a time covers the whole day: [at, end)
+	end := at
+	if !timed {
+		end = at.AddDate(0, 0, 1)
+	}
+	return t.ordered(func(task *Task) (int, bool) {
+		v, ok := get(task, loc)
+		switch {
+		case !ok:
+			return 0, false
+		case v.Before(at):
+			return -1, true
+		case timed && v.Equal(at), !timed && v.Before(end):
+			return 0, true
+		default:
+			return 1, true
+		}
+	}), nil
+}
+
+func (t term) status() (filterNode, error) {
+	if err := t.equalityOnly(); err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(t.value) {
+	case "open", "pending":
+		return t.equality(func(task *Task, _ *filterContext) bool { return !task.IsDone() }), nil
+	case "closed":
+		return t.equality(func(task *Task, _ *filterContext) bool { return task.IsDone() }), nil
+	}
+	state, err := ParseStatus(t.value)
+	if err != nil {
+		return nil, t.valueError(fmt.Sprintf("invalid status %q (use open, closed, todo, in-progress, waiting, blocked, cancelled or done)", t.value))
+	}
+	if state == StatusBlocked {
+		return t.equality(isBlocked), nil
+	}
+	return t.equality(func(task *Task, _ *filterContext) bool { return task.State() == state }), nil
+}
+
+// isBlocked matches tasks marked blocked and open tasks waiting on
+// dependencies, like FilterByStatusName
+func isBlocked(task *Task, c *filterContext) bool {
+	return task.State() == StatusBlocked || (!task.IsDone() && !AllDependenciesMet(c.all, *task))
+}
+
+func (t term) project() (filterNode, error) {
+	if err := t.equalityOnly(); err != nil {
+		return nil, err
+	}
+	project := strings.ToLower(t.value)
+	if project != ProjectNone && project != ProjectAll {
+		if _, err := NormalizeProject(project); err != nil {
+			return nil, t.valueError(err.Error())
+		}
+	}
+	return t.equality(func(task *Task, _ *filterContext) bool { return task.InProject(project) }), nil
+}
+
+func (t term) text() (filterNode, error) {
+	if t.op != ":" {
+		if t.field == "text" {
+			return nil, t.p.errorAt(t.opTok, fmt.Sprintf("operator %q is not supported for text (use :)", t.op))
+		}
+		if err := t.equalityOnly(); err != nil {
+			return nil, err
+		}
+	}
+	want := strings.ToLower(t.value)
+	get := func(task *Task) []string {
+		switch t.field {
+		case "title":
+			return []string{task.Title}
+		case "description":
+			return []string{task.Description}
+		default:
+			return []string{task.Title, task.Description}
+		}
+	}
+	if t.op == ":" {
+		return predNode(func(task *Task, _ *filterContext) bool {
+			for _, s := range get(task) {
+				if strings.Contains(strings.ToLower(s), want) {
+					return true
+				}
+			}
+			return false
+		}), nil
+	}
+	return t.equality(func(task *Task, _ *filterContext) bool {
+		return strings.EqualFold(strings.TrimSpace(get(task)[0]), want)
+	}), nil
+}
+
+func (t term) id() (filterNode, error) {
+	want, err := strconv.Atoi(t.value)
+	if err != nil || want < 1 {
+		return nil, t.valueError(fmt.Sprintf("invalid task ID %q", t.value))
+	}
+	return t.ordered(func(task *Task) (int, bool) { return cmp.Compare(task.ID, want), true }), nil
+}
+
+func (t term) estimate() (filterNode, error) {
+	if node, ok, err := t.presence(func(task *Task) bool { return task.Estimate > 0 }); ok {
+		return node, err
+	}
+	d, err := time.ParseDuration(strings.ToLower(t.value))
+	if err != nil || d <= 0 {
+		return nil, t.valueError(fmt.Sprintf("invalid estimate %q (use a duration such as 30m or 2h)", t.value))
+	}
+	return t.ordered(func(task *Task) (int, bool) {
+		if task.Estimate <= 0 {
+			return 0, false
+		}
+		return cmp.Compare(int(task.Estimate.Duration()/time.Minute), int(d/time.Minute)), true
+	}), nil
+}
+
+func (t term) is() (filterNode, error) {
+	if err := t.equalityOnly(); err != nil {
+		return nil, err
+	}
+	var eq func(*Task, *filterContext) bool
+	switch strings.ToLower(t.value) {
+	case "open":
+		t.p.fields["status"] = true
+		eq = func(task *Task, _ *filterContext) bool { return !task.IsDone() }
+	case "closed", "done":
+		t.p.fields["status"] = true
+		eq = func(task *Task, _ *filterContext) bool { return task.IsDone() }
+	case "overdue":
+		eq = func(task *Task, c *filterContext) bool { return task.IsOverdue(c.now) }
+	case "blocked":
+		eq = isBlocked
+	case "deferred":
+		t.p.fields["scheduled"] = true
+		eq = func(task *Task, c *filterContext) bool { return task.IsDeferred(c.now) }
+	case "recurring":
+		eq = func(task *Task, _ *filterContext) bool { return task.Repeat != "" }
+	case "running":
+		eq = func(task *Task, _ *filterContext) bool { return task.TimerRunning() }
+	default:
+		return nil, t.valueError(fmt.Sprintf("unknown condition %q (use open, closed, overdue, blocked, deferred, recurring or running)", t.value))
+	}
+	return t.equality(eq), nil
+}
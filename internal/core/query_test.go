@@ -0,0 +1,108 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestFilterMatch(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC) // a Wednesday
+	today := time.Date(2025, 10, 22, 0, 0, 0, 0, time.UTC)
+	friday := time.Date(2025, 10, 24, 0, 0, 0, 0, time.UTC)
+	nextWeek := time.Date(2025, 10, 29, 0, 0, 0, 0, time.UTC)
+	yesterday := time.Date(2025, 10, 21, 0, 0, 0, 0, time.UTC)
+	tasks := []Task{
+		{ID: 1, Title: "Write report", Priority: 3, Due: &friday, Tags: []string{"Work"}, Project: "acme"},
+		{ID: 2, Title: "Review PR", Priority: 1, Due: &today, Tags: []string{"work"}, Estimate: Estimate(time.Hour)},
+		{ID: 3, Title: "Buy milk", Priority: 2, Due: &nextWeek, Tags: []string{"home"}},
+		{ID: 4, Title: "Old report", Priority: 2, Due: &yesterday, Tags: []string{"work"}, DoneAt: &now, Status: StatusDone},
+		{ID: 5, Title: "Deploy", Description: "after the report", DependsOn: []int{1}, Repeat: "weekly"},
+		{ID: 6, Title: "Pay rent", Due: &yesterday},
+	}
+
+	cases := map[string][]int{
+		"tag:work and (priority>=2 or due<+3d) and not status:done": {1, 2},
+		"tag:work priority:high":                                    {1},
+		"tag:work or tag:home":                                      {1, 2, 3, 4},
+		"tag:none":                                                  {5, 6},
+		"tag!=work":                                                 {3, 5, 6},
+		"p>medium":                                                  {1},
+		"due:today":                                                 {2},
+		"due<=friday and due>=today":                                {1, 2},
+		"due:none":                                                  {5},
+		"due<today":                                                 {4, 6},
+		"status:open":                                               {1, 2, 3, 5, 6},
+		"status:blocked":                                            {5},
+		"done:any":                                                  {4},
+		"project:acme":                                              {1},
+		"project:none and id<=3":                                    {2, 3},
+		"report":                                                    {1, 4, 5},
+		"title:report":                                              {1, 4},
+		`title="write REPORT"`:                                      {1},
+		`"review pr" OR milk`:                                       {2, 3},
+		"estimate>=30m":                                             {2},
+		"is:overdue":                                                {6},
+		"is:recurring or is:blocked":                                {5},
+		"not (tag:work or tag:home) and not is:overdue": {5},
+	}
+	for expr, want := range cases {
+		f, err := ParseFilter(expr, now)
+		if err != nil {
+			t.Errorf("ParseFilter(%q): unexpected error: %v", expr, err)
+			continue
+		}
+		got := taskIDs(f.Apply(tasks, tasks))
+		if len(got) != len(want) {
+			t.Errorf("%s: got %v, want %v", expr, got, want)
+			continue
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: got %v, want %v", expr, got, want)
+				break
+			}
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	cases := map[string]int{ // expression: column of the error
+		"":                     1,
+		"size:big":             1,
+		"tag:work and":         13,
+		"(tag:work":            10,
+		"tag:work)":            9,
+		"priority:urgent":      10,
+		"due<someday":          5,
+		"tag<work":             4,
+		"status:":              8,
+		`title:"unterminated`:  7,
+		"tag:work or or tag:x": 13,
+		"priority=>2":          9,
+		"not":                  4,
+		"estimate>lots":        10,
+	}
+	for expr, col := range cases {
+		_, err := ParseFilter(expr, now)
+		var ferr *FilterError
+		if !errors.As(err, &ferr) {
+			t.Errorf("ParseFilter(%q): expected a FilterError, got %v", expr, err)
+			continue
+		}
+		if ferr.Pos+1 != col {
+			t.Errorf("ParseFilter(%q): error at column %d, want %d (%v)", expr, ferr.Pos+1, col, err)
+		}
+	}
+}
+
+func TestFilterUses(t *testing.T) {
+	f, err := ParseFilter("tag:work or is:closed", time.Now())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !f.Uses("tag") || !f.Uses("status") || f.Uses("due") {
+		t.Errorf("Unexpected fields used by %q: %v", f, f.fields)
+	}
+}
@@ -0,0 +1,644 @@
+package core
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence is a parsed repeat rule, modelled on RFC 5545 RRULEs.
+//
+// Rules are stored in Task.Repeat in canonical form (see String): the plain
+// words "daily", "weekly", "monthly" and "yearly" for the simple cases and an
+// RRULE-style "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE" string otherwise.
+type Recurrence struct {
+	Freq     RepeatRule
+	Interval int            // every Interval periods; at least 1
+	ByDay    []time.Weekday // weekly: days of the week; monthly: the weekday of Nth
+	Nth      int            // monthly: 1..5 for the nth ByDay[0] of the month, -1 for the last
+	MonthDay int            // monthly: day of the month, -1 for the last day
+	Day      int            // plain monthly and yearly: the day of the month a shorter month moved the current occurrence from
+	Count    int            // occurrences left including the current one; 0 means unlimited
+	Until    *time.Time     // no occurrences after this date
+}
+
+// maxRecurrenceSteps bounds the search for the next occurrence so that rules
+// which can never match (e.g. the 31st every 12 months from April) terminate
+const maxRecurrenceSteps = 1000
+
+var (
+	weekdayCodes = []string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+	ordinalWords = map[string]int{
+		"first": 1, "1st": 1, "second": 2, "2nd": 2, "third": 3, "3rd": 3,
+		"fourth": 4, "4th": 4, "fifth": 5, "5th": 5, "last": -1,
+	}
+
+	everyPattern    = regexp.MustCompile(`^every\s+(\d+|other)\s+(day|week|month|year)s?$`)
+	monthDayPattern = regexp.MustCompile(`^(\d{1,2})(st|nd|rd|th)?$`)
+	countPattern    = regexp.MustCompile(`\s+(?:for\s+)?(\d+)\s+times$`)
+	untilPattern    = regexp.MustCompile(`\s+until\s+(\S+)$`)
+)
+
+// ParseRecurrence parses a repeat rule. Besides RRULE syntax
+// ("FREQ=MONTHLY;BYDAY=2TU;COUNT=6") it understands:
+//
+//	daily, weekly, monthly, yearly
+//	every 2 weeks, every other day, every 3 months
+//	mon,wed,fri / every monday and friday / weekdays / weekends
+//	every 2 weeks on mon,thu
+//	2nd tuesday, last friday (of each month)
+//	15th, last day, end of month (each month)
+//
+// optionally followed by "until YYYY-MM-DD" and/or "for N times".
+func ParseRecurrence(rule string) (Recurrence, error) {
+	s := strings.ToLower(strings.Join(strings.Fields(rule), " "))
+	if s == "" {
+		return Recurrence{}, fmt.Errorf("empty repeat rule")
+	}
+
+	var r Recurrence
+	var err error
+	if strings.HasPrefix(s, "rrule:") || strings.HasPrefix(s, "freq=") {
+		r, err = parseRRule(strings.TrimPrefix(s, "rrule:"))
+	} else {
+		r, err = parseNaturalRule(s)
+	}
+	if err != nil {
+		return Recurrence{}, fmt.Errorf("invalid repeat rule %q: %w", rule, err)
+	}
+
+	if r.Interval == 0 {
+		r.Interval = 1
+	}
+	if err := r.validate(); err != nil {
+		return Recurrence{}, fmt.Errorf("invalid repeat rule %q: %w", rule, err)
+	}
+	return r, nil
+}
+
+func (r Recurrence) validate() error {
+	switch r.Freq {
+	case RepeatDaily, RepeatWeekly, RepeatMonthly, RepeatYearly:
+	default:
+		return fmt.Errorf("unknown frequency %q", r.Freq)
+	}
+	if r.Interval < 1 {
+		return fmt.Errorf("interval must be at least 1")
+	}
+	if r.Count < 0 {
+		return fmt.Errorf("count must be positive")
+	}
+	if r.Nth != 0 && (r.Freq != RepeatMonthly || len(r.ByDay) != 1) {
+		return fmt.Errorf("an nth weekday needs a monthly rule with one weekday")
+	}
+	if r.Nth < -1 || r.Nth > 5 {
+		return fmt.Errorf("nth weekday must be 1-5 or last")
+	}
+	if r.MonthDay != 0 && r.Freq != RepeatMonthly {
+		return fmt.Errorf("a day of the month needs a monthly rule")
+	}
+	if r.MonthDay < -1 || r.MonthDay > 31 {
+		return fmt.Errorf("day of the month must be 1-31 or last")
+	}
+	if r.Day != 0 && ((r.Freq != RepeatMonthly && r.Freq != RepeatYearly) || r.MonthDay != 0 || r.Nth != 0) {
+		return fmt.Errorf("X-MONTHDAY needs a plain monthly or yearly rule")
+	}
+	if r.Day < 0 || r.Day > 31 {
+		return fmt.Errorf("X-MONTHDAY must be 1-31")
+	}
+	if len(r.ByDay) > 0 && r.Freq != RepeatWeekly && r.Nth == 0 {
+		return fmt.Errorf("weekdays need a weekly rule")
+	}
+	return nil
+}
+
+// parseRRule parses the KEY=VALUE;... form (already lower-cased)
+func parseRRule(s string) (Recurrence, error) {
+	var r Recurrence
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return r, fmt.Errorf("expected KEY=VALUE, got %q", part)
+		}
+
+		var err error
+		switch key {
+		case "freq":
+			r.Freq = RepeatRule(value)
+		case "interval":
+			r.Interval, err = strconv.Atoi(value)
+			if err == nil && r.Interval < 1 {
+				err = fmt.Errorf("interval must be at least 1")
+			}
+		case "count":
+			r.Count, err = strconv.Atoi(value)
+			if err == nil && r.Count < 1 {
+				err = fmt.Errorf("count must be positive")
+			}
+		case "until":
+			var t time.Time
+			t, err = parseUntil(value)
+			r.Until = &t
+		case "bymonthday":
+			r.MonthDay, err = strconv.Atoi(value)
+			if err == nil && r.MonthDay == 0 {
+				err = fmt.Errorf("bymonthday must not be 0")
+			}
+		case "x-monthday":
+			r.Day, err = strconv.Atoi(value)
+		case "byday":
+			for _, code := range strings.Split(value, ",") {
+				nth, wd, perr := parseByDay(code)
+				if perr != nil {
+					return r, perr
+				}
+				if nth != 0 {
+					r.Nth = nth
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		default:
+			return r, fmt.Errorf("unsupported field %q", strings.ToUpper(key))
+		}
+		if err != nil {
+			return r, fmt.Errorf("bad %s: %w", strings.ToUpper(key), err)
+		}
+	}
+	return r, nil
+}
+
+// parseByDay parses "mo" or "2tu" / "-1fr"
+func parseByDay(code string) (int, time.Weekday, error) {
+	code = strings.TrimSpace(code)
+	if len(code) < 2 {
+		return 0, 0, fmt.Errorf("bad BYDAY %q", code)
+	}
+	prefix, day := code[:len(code)-2], strings.ToUpper(code[len(code)-2:])
+
+	wd := -1
+	for i, c := range weekdayCodes {
+		if c == day {
+			wd = i
+		}
+	}
+	if wd < 0 {
+		return 0, 0, fmt.Errorf("bad BYDAY %q", code)
+	}
+
+	nth := 0
+	if prefix != "" {
+		n, err := strconv.Atoi(strings.TrimPrefix(prefix, "+"))
+		if err != nil || n == 0 || n < -1 || n > 5 {
+			return 0, 0, fmt.Errorf("bad BYDAY %q", code)
+		}
+		nth = n
+	}
+	return nth, time.Weekday(wd), nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102", "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	if len(value) > 8 {
+		// Allow RFC 5545 date-times such as 20251231T235959Z; only the date is used
+		return parseUntil(value[:8])
+	}
+	return time.Time{}, fmt.Errorf("expected YYYY-MM-DD")
+}
+
+// parseNaturalRule parses the human-friendly forms (already lower-cased)
+func parseNaturalRule(s string) (Recurrence, error) {
+	var r Recurrence
+
+	// End conditions come last and may appear in either order
+	for {
+		if m := countPattern.FindStringSubmatch(s); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			if n < 1 {
+				return r, fmt.Errorf("count must be positive")
+			}
+			r.Count = n
+			s = strings.TrimSpace(s[:len(s)-len(m[0])])
+			continue
+		}
+		if m := untilPattern.FindStringSubmatch(s); m != nil {
+			t, err := parseUntil(m[1])
+			if err != nil {
+				return r, fmt.Errorf("bad until date: %w", err)
+			}
+			r.Until = &t
+			s = strings.TrimSpace(s[:len(s)-len(m[0])])
+			continue
+		}
+		break
+	}
+
+	s = strings.TrimSuffix(s, " of the month")
+	s = strings.TrimSuffix(s, " of each month")
+	s = strings.TrimSuffix(s, " of every month")
+	s = strings.TrimSuffix(s, " of month")
+
+	switch s {
+	case "daily", "every day":
+		r.Freq = RepeatDaily
+		return r, nil
+	case "weekly", "every week":
+		r.Freq = RepeatWeekly
+		return r, nil
+	case "monthly", "every month":
+		r.Freq = RepeatMonthly
+		return r, nil
+	case "yearly", "annually", "every year":
+		r.Freq = RepeatYearly
+		return r, nil
+	case "last day", "end of month", "every last day":
+		r.Freq = RepeatMonthly
+		r.MonthDay = -1
+		return r, nil
+	case "weekdays", "every weekday":
+		r.Freq = RepeatWeekly
+		r.ByDay = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+		return r, nil
+	case "weekends", "every weekend":
+		r.Freq = RepeatWeekly
+		r.ByDay = []time.Weekday{time.Saturday, time.Sunday}
+		return r, nil
+	}
+
+	// "every N weeks" optionally followed by "on <days>"
+	body, days, hasDays := strings.Cut(s, " on ")
+	if m := everyPattern.FindStringSubmatch(body); m != nil {
+		r.Interval = 2
+		if m[1] != "other" {
+			r.Interval, _ = strconv.Atoi(m[1])
+			if r.Interval < 1 {
+				return r, fmt.Errorf("interval must be at least 1")
+			}
+		}
+		r.Freq = map[string]RepeatRule{
+			"day": RepeatDaily, "week": RepeatWeekly, "month": RepeatMonthly, "year": RepeatYearly,
+		}[m[2]]
+		if !hasDays {
+			return r, nil
+		}
+		s = days
+	} else if hasDays {
+		return r, fmt.Errorf("unrecognized rule")
+	}
+
+	s = strings.TrimPrefix(s, "every ")
+	s = strings.TrimPrefix(s, "the ")
+
+	// "2nd tuesday", "last friday"
+	if fields := strings.Fields(s); len(fields) == 2 {
+		if nth, ok := ordinalWords[fields[0]]; ok {
+			if wd, ok := weekdayNames[fields[1]]; ok {
+				if r.Freq != "" && r.Freq != RepeatMonthly {
+					return r, fmt.Errorf("an nth weekday needs a monthly rule")
+				}
+				r.Freq = RepeatMonthly
+				r.Nth = nth
+				r.ByDay = []time.Weekday{wd}
+				return r, nil
+			}
+			if fields[0] == "last" && fields[1] == "day" {
+				r.Freq = RepeatMonthly
+				r.MonthDay = -1
+				return r, nil
+			}
+		}
+	}
+
+	// "15th"
+	if m := monthDayPattern.FindStringSubmatch(s); m != nil {
+		day, _ := strconv.Atoi(m[1])
+		if day < 1 || day > 31 {
+			return r, fmt.Errorf("day of the month must be 1-31")
+		}
+		if r.Freq != "" && r.Freq != RepeatMonthly {
+			return r, fmt.Errorf("a day of the month needs a monthly rule")
+		}
+		r.Freq = RepeatMonthly
+		r.MonthDay = day
+		return r, nil
+	}
+
+	// "mon,wed,fri", "monday and friday"
+	s = strings.ReplaceAll(s, " and ", ",")
+	s = strings.ReplaceAll(s, " ", ",")
+	for _, name := range strings.Split(s, ",") {
+		if name == "" {
+			continue
+		}
+		wd, ok := weekdayNames[name]
+		if !ok {
+			return r, fmt.Errorf("unrecognized rule")
+		}
+		r.ByDay = append(r.ByDay, wd)
+	}
+	if len(r.ByDay) == 0 {
+		return r, fmt.Errorf("unrecognized rule")
+	}
+	if r.Freq != "" && r.Freq != RepeatWeekly {
+		return r, fmt.Errorf("weekdays need a weekly rule")
+	}
+	r.Freq = RepeatWeekly
+	return r, nil
+}
+
+// isSimple reports whether the rule is just its frequency
+func (r Recurrence) isSimple() bool {
+	return r.Interval <= 1 && len(r.ByDay) == 0 && r.Nth == 0 && r.MonthDay == 0 && r.Day == 0 && r.Count == 0 && r.Until == nil
+}
+
+// String returns the canonical form stored in Task.Repeat
+func (r Recurrence) String() string {
+	if r.isSimple() {
+		return string(r.Freq)
+	}
+
+	parts := []string{"FREQ=" + strings.ToUpper(string(r.Freq))}
+	if r.Interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, 0, len(r.ByDay))
+		for _, wd := range sortedWeekdays(r.ByDay) {
+			code := weekdayCodes[wd]
+			if r.Nth != 0 {
+				code = strconv.Itoa(r.Nth) + code
+			}
+			codes = append(codes, code)
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if r.MonthDay != 0 {
+		parts = append(parts, fmt.Sprintf("BYMONTHDAY=%d", r.MonthDay))
+	}
+	if r.Day != 0 {
+		parts = append(parts, fmt.Sprintf("X-MONTHDAY=%d", r.Day))
+	}
+	if r.Count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.Count))
+	}
+	if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.Format("20060102"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Describe returns a human-readable summary such as "every 2 weeks on Mon, Thu"
+func (r Recurrence) Describe() string {
+	units := map[RepeatRule]string{RepeatDaily: "day", RepeatWeekly: "week", RepeatMonthly: "month", RepeatYearly: "year"}
+
+	var sb strings.Builder
+	switch {
+	case r.Nth != 0 && len(r.ByDay) == 1:
+		sb.WriteString(fmt.Sprintf("%s %s of every ", ordinalName(r.Nth), r.ByDay[0]))
+		if r.Interval > 1 {
+			sb.WriteString(fmt.Sprintf("%d months", r.Interval))
+		} else {
+			sb.WriteString("month")
+		}
+	case r.Interval > 1:
+		sb.WriteString(fmt.Sprintf("every %d %ss", r.Interval, units[r.Freq]))
+	default:
+		sb.WriteString("every " + units[r.Freq])
+	}
+
+	if len(r.ByDay) > 0 && r.Nth == 0 {
+		names := make([]string, 0, len(r.ByDay))
+		for _, wd := range sortedWeekdays(r.ByDay) {
+			names = append(names, wd.String()[:3])
+		}
+		sb.WriteString(" on " + strings.Join(names, ", "))
+	}
+	switch {
+	case r.MonthDay == -1:
+		sb.WriteString(" on the last day")
+	case r.MonthDay > 0:
+		sb.WriteString(fmt.Sprintf(" on day %d", r.MonthDay))
+	}
+	if r.Until != nil {
+		sb.WriteString(" until " + r.Until.Format("2006-01-02"))
+	}
+	if r.Count > 0 {
+		sb.WriteString(fmt.Sprintf(" (%d left)", r.Count))
+	}
+	return sb.String()
+}
+
+func ordinalName(n int) string {
+	switch n {
+	case -1:
+		return "last"
+	case 1:
+		return "1st"
+	case 2:
+		return "2nd"
+	case 3:
+		return "3rd"
+	default:
+		return fmt.Sprintf("%dth", n)
+	}
+}
+
+// sortedWeekdays returns the days Monday-first, as they read in a week
+func sortedWeekdays(days []time.Weekday) []time.Weekday {
+	out := append([]time.Weekday{}, days...)
+	sort.Slice(out, func(i, j int) bool {
+		return (out[i]+6)%7 < (out[j]+6)%7
+	})
+	return out
+}
+
+// Next returns the first occurrence strictly after current, keeping
+// current's time of day and location. It reports false once the rule's
+// UNTIL date has passed. COUNT is not consulted; see Advance.
+func (r Recurrence) Next(current time.Time) (time.Time, bool) {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	var next time.Time
+	var ok bool
+	switch r.Freq {
+	case RepeatDaily:
+		next, ok = current.AddDate(0, 0, interval), true
+	case RepeatWeekly:
+		next, ok = r.nextWeekly(current, interval)
+	case RepeatMonthly:
+		next, ok = r.nextMonthly(current, interval)
+	case RepeatYearly:
+		next, ok = addMonthsClamped(current, 12*interval, r.dayOf(current)), true
+	}
+	if !ok {
+		return time.Time{}, false
+	}
+
+	if r.Until != nil {
+		y, m, d := r.Until.Date()
+		endOfUntil := time.Date(y, m, d+1, 0, 0, 0, 0, next.Location())
+		if !next.Before(endOfUntil) {
+			return time.Time{}, false
+		}
+	}
+	return next, true
+}
+
+// Advance returns the rule to store on the occurrence after the current
+// one, with COUNT decremented, and false if the current one was the last
+func (r Recurrence) Advance() (Recurrence, bool) {
+	if r.Count == 0 {
+		return r, true
+	}
+	if r.Count <= 1 {
+		return r, false
+	}
+	r.Count--
+	return r, true
+}
+
+// dayOf returns the day of the month that plain monthly and yearly rules
+// keep: current's, unless a shorter month moved current from r.Day
+func (r Recurrence) dayOf(current time.Time) int {
+	if day := current.Day(); r.Day <= day || day != daysIn(current) {
+		return day
+	}
+	return r.Day
+}
+
+// keepDay returns the rule to store on next, the occurrence after current,
+// remembering the day of the month when next had to fall earlier in a
+// shorter month (Jan 31 -> Feb 28), so that later ones go back to it
+// (Mar 31) rather than drifting
+func (r Recurrence) keepDay(current, next time.Time) Recurrence {
+	if (r.Freq != RepeatMonthly && r.Freq != RepeatYearly) || r.MonthDay != 0 || r.Nth != 0 {
+		return r
+	}
+	day := r.dayOf(current)
+	r.Day = 0
+	if next.Day() != day {
+		r.Day = day
+	}
+	return r
+}
+
+func (r Recurrence) nextWeekly(current time.Time, interval int) (time.Time, bool) {
+	if len(r.ByDay) == 0 {
+		return current.AddDate(0, 0, 7*interval), true
+	}
+
+	days := make(map[time.Weekday]bool, len(r.ByDay))
+	for _, wd := range r.ByDay {
+		days[wd] = true
+	}
+
+	// Weeks start on Monday (the RFC 5545 default); only every
+	// interval-th week counted from current's week is eligible
+	startWeek := startOfWeek(current)
+	for i := 1; i <= 7*interval+7; i++ {
+		candidate := current.AddDate(0, 0, i)
+		if !days[candidate.Weekday()] {
+			continue
+		}
+		weeks := int(startOfWeek(candidate).Sub(startWeek).Hours()+12) / (24 * 7)
+		if weeks%interval == 0 {
+			return candidate, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func startOfWeek(t time.Time) time.Time {
+	offset := (int(t.Weekday()) + 6) % 7
+	return time.Date(t.Year(), t.Month(), t.Day()-offset, 0, 0, 0, 0, t.Location())
+}
+
+func (r Recurrence) nextMonthly(current time.Time, interval int) (time.Time, bool) {
+	if r.MonthDay == 0 && r.Nth == 0 {
+		return addMonthsClamped(current, interval, r.dayOf(current)), true
+	}
+
+	hour, min, sec := current.Clock()
+	loc := current.Location()
+	for k := 0; k < maxRecurrenceSteps; k++ {
+		first := time.Date(current.Year(), current.Month()+time.Month(k*interval), 1, hour, min, sec, 0, loc)
+		days := daysIn(first)
+
+		var day int
+		switch {
+		case r.MonthDay == -1:
+			day = days
+		case r.MonthDay > 0:
+			if r.MonthDay > days {
+				continue // months without that day are skipped, as in RFC 5545
+			}
+			day = r.MonthDay
+		default:
+			day = nthWeekday(first, r.ByDay[0], r.Nth)
+			if day == 0 {
+				continue
+			}
+		}
+
+		candidate := time.Date(first.Year(), first.Month(), day, hour, min, sec, 0, loc)
+		if candidate.After(current) {
+			return candidate, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// nthWeekday returns the day of the month of the nth weekday wd in first's
+// month (n = -1 for the last one), or 0 if the month has no such day
+func nthWeekday(first time.Time, wd time.Weekday, n int) int {
+	days := daysIn(first)
+	if n == -1 {
+		last := time.Date(first.Year(), first.Month(), days, 0, 0, 0, 0, first.Location())
+		return days - (int(last.Weekday())-int(wd)+7)%7
+	}
+	day := 1 + (int(wd)-int(first.Weekday())+7)%7 + 7*(n-1)
+	if day > days {
+		return 0
+	}
+	return day
+}
+
+func daysIn(t time.Time) int {
+	return time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+}
+
+// addMonthsClamped adds months to t and moves it to the given day of the
+// month, clamping the day to the end of shorter months (Jan 31 + 1 month =
+// Feb 28) instead of overflowing like AddDate
+func addMonthsClamped(t time.Time, months, day int) time.Time {
+	hour, min, sec := t.Clock()
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, hour, min, sec, t.Nanosecond(), t.Location())
+	if d := daysIn(first); day > d {
+		day = d
+	}
+	return time.Date(first.Year(), first.Month(), day, hour, min, sec, t.Nanosecond(), t.Location())
+}
+
+// DescribeRepeat returns a readable form of a stored repeat rule, or the rule
+// itself if it cannot be parsed
+func DescribeRepeat(repeat string) string {
+	r, err := ParseRecurrence(repeat)
+	if err != nil {
+		return repeat
+	}
+	return r.Describe()
+}
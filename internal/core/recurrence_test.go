@@ -0,0 +1,234 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRecurrenceCanonical(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"daily", "daily"},
+		{"Weekly", "weekly"},
+		{"every month", "monthly"},
+		{"annually", "yearly"},
+		{"every 2 weeks", "FREQ=WEEKLY;INTERVAL=2"},
+		{"every other day", "FREQ=DAILY;INTERVAL=2"},
+		{"mon,wed,fri", "FREQ=WEEKLY;BYDAY=MO,WE,FR"},
+		{"every monday and friday", "FREQ=WEEKLY;BYDAY=MO,FR"},
+		{"weekdays", "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"},
+		{"every 2 weeks on thu,mon", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"},
+		{"2nd tuesday", "FREQ=MONTHLY;BYDAY=2TU"},
+		{"last friday of the month", "FREQ=MONTHLY;BYDAY=-1FR"},
+		{"last day of month", "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{"15th", "FREQ=MONTHLY;BYMONTHDAY=15"},
+		{"weekly for 3 times", "FREQ=WEEKLY;COUNT=3"},
+		{"daily until 2025-12-31", "FREQ=DAILY;UNTIL=20251231"},
+		{"FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1;COUNT=4", "FREQ=MONTHLY;INTERVAL=3;BYMONTHDAY=1;COUNT=4"},
+		{"RRULE:FREQ=WEEKLY;BYDAY=SA,SU", "FREQ=WEEKLY;BYDAY=SA,SU"},
+		{"FREQ=MONTHLY;X-MONTHDAY=31", "FREQ=MONTHLY;X-MONTHDAY=31"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			rule, err := ParseRecurrence(tt.input)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if rule.String() != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, rule.String())
+			}
+			// The canonical form must parse back to itself
+			again, err := ParseRecurrence(rule.String())
+			if err != nil || again.String() != tt.expected {
+				t.Errorf("Canonical form %q did not round-trip: %q, %v", rule.String(), again.String(), err)
+			}
+		})
+	}
+}
+
+func TestParseRecurrenceInvalid(t *testing.T) {
+	for _, input := range []string{"", "fortnightly-ish", "every 0 days", "6th monday", "32nd", "FREQ=HOURLY", "FREQ=WEEKLY;BYDAY=XX", "daily for 0 times", "daily until someday", "FREQ=WEEKLY;X-MONTHDAY=3", "FREQ=MONTHLY;BYMONTHDAY=5;X-MONTHDAY=31"} {
+		if _, err := ParseRecurrence(input); err == nil {
+			t.Errorf("Expected error for %q", input)
+		}
+	}
+}
+
+func TestRecurrenceNext(t *testing.T) {
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 9, 30, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		rule     string
+		from     time.Time
+		expected []time.Time
+	}{
+		// 2025-10-22 is a Wednesday
+		{"daily", at(2025, 10, 22), []time.Time{at(2025, 10, 23), at(2025, 10, 24)}},
+		{"every 2 weeks", at(2025, 10, 22), []time.Time{at(2025, 11, 5), at(2025, 11, 19)}},
+		{"mon,wed,fri", at(2025, 10, 22), []time.Time{at(2025, 10, 24), at(2025, 10, 27), at(2025, 10, 29)}},
+		{"every 2 weeks on mon,thu", at(2025, 10, 22), []time.Time{at(2025, 10, 23), at(2025, 11, 3), at(2025, 11, 6), at(2025, 11, 17)}},
+		{"2nd tuesday", at(2025, 10, 22), []time.Time{at(2025, 11, 11), at(2025, 12, 9)}},
+		{"last friday", at(2025, 10, 22), []time.Time{at(2025, 10, 31), at(2025, 11, 28)}},
+		{"last day", at(2025, 1, 31), []time.Time{at(2025, 2, 28), at(2025, 3, 31)}},
+		{"31st", at(2025, 1, 31), []time.Time{at(2025, 3, 31), at(2025, 5, 31)}},
+		{"monthly", at(2025, 1, 31), []time.Time{at(2025, 2, 28)}},
+		{"yearly", at(2024, 2, 29), []time.Time{at(2025, 2, 28)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			rule, err := ParseRecurrence(tt.rule)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			current := tt.from
+			for _, want := range tt.expected {
+				next, ok := rule.Next(current)
+				if !ok {
+					t.Fatalf("Expected an occurrence after %s", current)
+				}
+				if !next.Equal(want) {
+					t.Fatalf("After %s expected %s, got %s", current, want, next)
+				}
+				current = next
+			}
+		})
+	}
+}
+
+func TestRecurrenceKeepsDayOfMonth(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	// A day that a shorter month moves earlier comes back in the months
+	// after it instead of drifting
+	tests := []struct {
+		rule     string
+		from     time.Time
+		expected []time.Time
+	}{
+		{"monthly", date(2025, 1, 31), []time.Time{date(2025, 2, 28), date(2025, 3, 31), date(2025, 4, 30), date(2025, 5, 31)}},
+		{"every 3 months", date(2024, 11, 30), []time.Time{date(2025, 2, 28), date(2025, 5, 30)}},
+		{"yearly", date(2024, 2, 29), []time.Time{date(2025, 2, 28), date(2026, 2, 28), date(2027, 2, 28), date(2028, 2, 29)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rule, func(t *testing.T) {
+			repeat, _ := NormalizeRepeat(tt.rule)
+			tasks := []Task{{ID: 1, Title: "Pay rent", Due: &tt.from, Repeat: repeat}}
+			for i, want := range tt.expected {
+				var err error
+				tasks, err = MarkDoneAt(tasks, tasks, i+1, want)
+				if err != nil {
+					t.Fatalf("Unexpected error: %v", err)
+				}
+				next := tasks[len(tasks)-1]
+				if next.Due == nil || !next.Due.Equal(want) {
+					t.Fatalf("Occurrence %d: expected %s, got %v", i+2, want.Format("2006-01-02"), next.Due)
+				}
+			}
+			if tasks[len(tasks)-1].Repeat != repeat {
+				t.Errorf("Expected the rule back to %q, got %q", repeat, tasks[len(tasks)-1].Repeat)
+			}
+		})
+	}
+}
+
+func TestRecurrenceEndConditions(t *testing.T) {
+	due := time.Date(2025, 10, 22, 0, 0, 0, 0, time.UTC)
+
+	// UNTIL is inclusive of its date
+	rule, _ := ParseRecurrence("daily until 2025-10-23")
+	if _, ok := rule.Next(due); !ok {
+		t.Error("Expected an occurrence on the UNTIL date")
+	}
+	if _, ok := rule.Next(due.AddDate(0, 0, 1)); ok {
+		t.Error("Expected no occurrence after the UNTIL date")
+	}
+
+	// COUNT is carried forward on each spawned occurrence
+	tasks := []Task{{ID: 1, Title: "Standup", Due: &due, Repeat: "FREQ=DAILY;COUNT=2"}}
+	tasks, err := MarkDoneAt(tasks, tasks, 1, due)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tasks) != 2 || tasks[1].Repeat != "FREQ=DAILY;COUNT=1" {
+		t.Fatalf("Expected a final occurrence with COUNT=1, got %+v", tasks)
+	}
+	tasks, err = MarkDoneAt(tasks, tasks, 2, due)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tasks) != 2 {
+		t.Errorf("Expected no occurrence after the last one, got %d tasks", len(tasks))
+	}
+}
+
+func TestRecurrenceFromCompletion(t *testing.T) {
+	due := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	done := time.Date(2025, 10, 22, 18, 0, 0, 0, time.UTC)
+
+	// Completed three weeks late: from due the next one is already overdue,
+	// from completion it is three days after the day it was done.
+	tests := []struct {
+		name     string
+		task     Task
+		expected time.Time
+	}{
+		{"from due", Task{Due: &due, Repeat: "every 3 days"}, time.Date(2025, 10, 4, 0, 0, 0, 0, time.UTC)},
+		{"from completion", Task{Due: &due, Repeat: "every 3 days", RepeatFrom: RepeatFromCompletion}, time.Date(2025, 10, 25, 0, 0, 0, 0, time.UTC)},
+		{"from completion without due", Task{Repeat: "every 3 days", RepeatFrom: RepeatFromCompletion}, time.Date(2025, 10, 25, 0, 0, 0, 0, time.UTC)},
+		{"skip missed", Task{Due: &due, Repeat: "every 3 days", SkipMissed: true}, time.Date(2025, 10, 22, 0, 0, 0, 0, time.UTC)},
+		{"skip missed weekly", Task{Due: &due, Repeat: "weekly", SkipMissed: true}, time.Date(2025, 10, 22, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.task.ID, tt.task.Title = 1, "Water plants"
+			next := createNextRecurrenceAt(tt.task, done)
+			if next == nil || next.Due == nil {
+				t.Fatal("Expected a next occurrence")
+			}
+			if !next.Due.Equal(tt.expected) {
+				t.Errorf("Expected %s, got %s", tt.expected, next.Due)
+			}
+			if next.RepeatFrom != tt.task.RepeatFrom || next.SkipMissed != tt.task.SkipMissed {
+				t.Error("Expected the recurrence policy to carry over")
+			}
+		})
+	}
+
+	// A timed task keeps its time of day; skipped occurrences use up COUNT
+	at := time.Date(2025, 10, 1, 9, 0, 0, 0, time.UTC)
+	task := Task{ID: 1, Title: "Standup", Due: &at, Timed: true, Repeat: "FREQ=DAILY;COUNT=30", SkipMissed: true}
+	next := createNextRecurrenceAt(task, done)
+	if next == nil || !next.Due.Equal(time.Date(2025, 10, 23, 9, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected 2025-10-23 09:00, got %+v", next)
+	}
+	if next.Repeat != "FREQ=DAILY;COUNT=8" {
+		t.Errorf("Expected COUNT=8 after skipping, got %q", next.Repeat)
+	}
+
+	// Skipping past the end of the series ends it
+	task.Repeat = "FREQ=DAILY;COUNT=5"
+	if next := createNextRecurrenceAt(task, done); next != nil {
+		t.Errorf("Expected the series to end, got %+v", next)
+	}
+}
+
+func TestNormalizeRepeatFrom(t *testing.T) {
+	for input, expected := range map[string]RepeatAnchor{"": "", "due": "", "Completion": RepeatFromCompletion, "done": RepeatFromCompletion} {
+		if got, err := NormalizeRepeatFrom(input); err != nil || got != expected {
+			t.Errorf("NormalizeRepeatFrom(%q) = %q, %v", input, got, err)
+		}
+	}
+	if _, err := NormalizeRepeatFrom("start"); err == nil {
+		t.Error("Expected error for unknown anchor")
+	}
+}
@@ -0,0 +1,99 @@
+package core
+
+import (
+	"time"
+)
+
+// AvailableAt returns when a scheduled task becomes available as seen from
+// loc: the stored instant for timed schedules, or the start of the scheduled
+// date in loc. The task must have a scheduled date.
+func (t *Task) AvailableAt(loc *time.Location) time.Time {
+	if t.ScheduledTimed {
+		return t.Scheduled.In(loc)
+	}
+	y, m, d := t.Scheduled.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// ScheduledString formats the scheduled date for display in loc
+func (t *Task) ScheduledString(loc *time.Location) string {
+	if t.Scheduled == nil {
+		return ""
+	}
+	if t.ScheduledTimed {
+		return t.Scheduled.In(loc).Format("2006-01-02 15:04 MST")
+	}
+	return t.Scheduled.Format("2006-01-02")
+}
+
+// IsDeferred reports whether an open task is scheduled to start later than
+// now. Deferred tasks are hidden from the default list and are not ready to
+// work on, whatever their dependencies.
+func (t *Task) IsDeferred(now time.Time) bool {
+	if t.IsDone() || t.Scheduled == nil {
+		return false
+	}
+	return now.Before(t.AvailableAt(now.Location()))
+}
+
+// FilterDeferred drops open tasks that are scheduled to start after now
+func FilterDeferred(tasks []Task, now time.Time) []Task {
+	result := make([]Task, 0, len(tasks))
+	for _, t := range tasks {
+		if !t.IsDeferred(now) {
+			result = append(result, t)
+		}
+	}
+	return result
+}
+
+// FilterByDueOrScheduledRange is FilterByDueRange that also keeps tasks
+// scheduled within the range, so a day view shows what starts that day as
+// well as what is due
+func FilterByDueOrScheduledRange(tasks []Task, before, after *time.Time) []Task {
+	if before == nil && after == nil {
+		return tasks
+	}
+
+	inRange := func(at time.Time) bool {
+		if before != nil && at.After(*before) {
+			return false
+		}
+		return after == nil || !at.Before(*after)
+	}
+	loc := time.UTC
+	if before != nil {
+		loc = before.Location()
+	} else if after != nil {
+		loc = after.Location()
+	}
+
+	result := make([]Task, 0)
+	for _, task := range tasks {
+		if (task.Due != nil && inRange(task.DueIn(loc))) ||
+			(task.Scheduled != nil && inRange(task.AvailableAt(loc))) {
+			result = append(result, task)
+		}
+	}
+	return result
+}
+
+// nextScheduled keeps the lead time between a recurring task's scheduled and
+// due dates for its next occurrence. All-day schedules keep the number of
+// days; timed ones the exact duration.
+func nextScheduled(task Task, nextDue time.Time) *time.Time {
+	if task.Scheduled == nil || task.Due == nil {
+		return nil
+	}
+	if task.ScheduledTimed {
+		s := nextDue.Add(-task.Due.Sub(*task.Scheduled))
+		return &s
+	}
+
+	dy, dm, dd := task.Due.Date()
+	sy, sm, sd := task.Scheduled.Date()
+	days := int(time.Date(dy, dm, dd, 0, 0, 0, 0, time.UTC).Sub(time.Date(sy, sm, sd, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+	ny, nm, nd := nextDue.Date()
+	s := time.Date(ny, nm, nd-days, 0, 0, 0, 0, nextDue.Location())
+	return &s
+}
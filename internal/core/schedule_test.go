@@ -0,0 +1,80 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIsDeferred(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*3600)
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, loc)
+	today := time.Date(2025, 10, 22, 0, 0, 0, 0, time.UTC)
+	tomorrow := today.AddDate(0, 0, 1)
+	noon := time.Date(2025, 10, 22, 12, 0, 0, 0, loc)
+
+	cases := []struct {
+		name string
+		task Task
+		want bool
+	}{
+		{"unscheduled", Task{}, false},
+		{"scheduled today", Task{Scheduled: &today}, false},
+		{"scheduled tomorrow", Task{Scheduled: &tomorrow}, true},
+		{"later today", Task{Scheduled: &noon, ScheduledTimed: true}, true},
+		{"done", Task{Scheduled: &tomorrow, DoneAt: &now}, false},
+	}
+	for _, c := range cases {
+		if got := c.task.IsDeferred(now); got != c.want {
+			t.Errorf("%s: IsDeferred = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestDeferredTasksAreNotReady(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	later := now.AddDate(0, 0, 3)
+	tasks := []Task{{ID: 1, Scheduled: &later}, {ID: 2}}
+
+	if got := taskIDs(FilterByDependencies(tasks, true, now)); len(got) != 1 || got[0] != 2 {
+		t.Errorf("Expected only #2 ready, got %v", got)
+	}
+	if recs := RankActionable(tasks, now); len(recs) != 1 || recs[0].Task.ID != 2 {
+		t.Errorf("Expected only #2 recommended, got %d", len(recs))
+	}
+	if got := FilterDeferred(tasks, later); len(got) != 2 {
+		t.Errorf("Expected #1 visible once its date arrives, got %v", taskIDs(got))
+	}
+}
+
+func TestFilterByDueOrScheduledRange(t *testing.T) {
+	start := time.Date(2025, 10, 22, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 0, 1).Add(-time.Nanosecond)
+	next := start.AddDate(0, 0, 5)
+	tasks := []Task{
+		{ID: 1, Due: &start},
+		{ID: 2, Scheduled: &start, Due: &next},
+		{ID: 3, Scheduled: &next},
+		{ID: 4},
+	}
+
+	got := taskIDs(FilterByDueOrScheduledRange(tasks, &end, &start))
+	if len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("Expected #1 and #2, got %v", got)
+	}
+}
+
+func TestRecurrenceKeepsScheduleLead(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	due := time.Date(2025, 10, 24, 0, 0, 0, 0, time.UTC)
+	scheduled := time.Date(2025, 10, 21, 0, 0, 0, 0, time.UTC)
+	tasks := []Task{{ID: 1, Title: "Report", Due: &due, Scheduled: &scheduled, Repeat: "weekly"}}
+
+	tasks, err := MarkDoneAt(tasks, tasks, 1, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	next := tasks[1]
+	if next.Scheduled == nil || next.Scheduled.Format("2006-01-02") != "2025-10-28" {
+		t.Errorf("Expected the next occurrence scheduled 3 days before its due date, got %v", next.Scheduled)
+	}
+}
@@ -0,0 +1,519 @@
+package core
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// SearchField is a part of a task that the search index covers
+type SearchField uint8
+
+const (
+	SearchTitle SearchField = iota
+	SearchDescription
+	SearchTags
+	SearchNotes // notes on time entries
+	numSearchFields
+)
+
+var searchFieldNames = [numSearchFields]string{"title", "description", "tags", "notes"}
+
+func (f SearchField) String() string {
+	return searchFieldNames[f]
+}
+
+// searchFieldWeights makes a match in the title count for more than one in
+// the description or in time entry notes
+var searchFieldWeights = [numSearchFields]float64{3, 1, 2, 0.5}
+
+// Ranking parameters: BM25 term frequency saturation and length
+// normalization, and how much less a fuzzy or prefix match counts than an
+// exact one
+const (
+	bm25K1        = 1.2
+	bm25B         = 0.75
+	phraseBoost   = 1.5
+	prefixWeight  = 0.8
+	fuzzyWeight1  = 0.6 // one edit away
+	fuzzyWeight2  = 0.35
+	maxPrefixTerm = 200 // expansions of a prefix query that are scored
+)
+
+// SearchResult is a task matching a search, with its relevance score and
+// the fields it matched in
+type SearchResult struct {
+	Task   Task     `json:"task"`
+	Score  float64  `json:"score"`
+	Fields []string `json:"fields"`
+}
+
+// posting records where a term occurs in one field of one task
+type posting struct {
+	doc       int32
+	field     SearchField
+	positions []int32
+}
+
+// SearchIndex is an inverted index over the title, description, tags and
+// time entry notes of a set of tasks. Build it with NewSearchIndex; it is
+// read-only afterwards and safe for concurrent searches.
+type SearchIndex struct {
+	tasks    []Task
+	postings map[string][]posting
+	vocab    []string   // sorted, for prefix lookups
+	byLen    [][][]rune // words with letters, by length in runes, for fuzzy lookups
+	fieldLen [][numSearchFields]int32
+	avgLen   [numSearchFields]float64
+}
+
+// NewSearchIndex indexes tasks
+func NewSearchIndex(tasks []Task) *SearchIndex {
+	ix := &SearchIndex{
+		tasks:    tasks,
+		postings: make(map[string][]posting),
+		fieldLen: make([][numSearchFields]int32, len(tasks)),
+	}
+
+	var total [numSearchFields]int64
+	for doc := range tasks {
+		t := &tasks[doc]
+		notes := make([]string, 0, len(t.TimeEntries))
+		for _, e := range t.TimeEntries {
+			notes = append(notes, e.Note)
+		}
+		texts := [numSearchFields]string{
+			SearchTitle:       t.Title,
+			SearchDescription: t.Description,
+			SearchTags:        strings.Join(t.Tags, " "),
+			SearchNotes:       strings.Join(notes, " "),
+		}
+		for field, text := range texts {
+			tokens := Tokenize(text)
+			ix.fieldLen[doc][field] = int32(len(tokens))
+			total[field] += int64(len(tokens))
+			for pos, tok := range tokens {
+				ps := ix.postings[tok]
+				if n := len(ps); n > 0 && ps[n-1].doc == int32(doc) && ps[n-1].field == SearchField(field) {
+					ps[n-1].positions = append(ps[n-1].positions, int32(pos))
+					continue
+				}
+				ix.postings[tok] = append(ps, posting{doc: int32(doc), field: SearchField(field), positions: []int32{int32(pos)}})
+			}
+		}
+	}
+
+	for field := range total {
+		if len(tasks) > 0 {
+			ix.avgLen[field] = float64(total[field]) / float64(len(tasks))
+		}
+	}
+	ix.vocab = make([]string, 0, len(ix.postings))
+	for term := range ix.postings {
+		ix.vocab = append(ix.vocab, term)
+	}
+	sort.Strings(ix.vocab)
+	for _, term := range ix.vocab {
+		if !hasLetter(term) {
+			continue // typos in numbers are not corrected
+		}
+		r := []rune(term)
+		for len(ix.byLen) <= len(r) {
+			ix.byLen = append(ix.byLen, nil)
+		}
+		ix.byLen[len(r)] = append(ix.byLen[len(r)], r)
+	}
+	return ix
+}
+
+// Len returns the number of indexed tasks
+func (ix *SearchIndex) Len() int {
+	return len(ix.tasks)
+}
+
+// Tokenize splits text into lower-case words of letters and digits
+func Tokenize(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// SearchQuery is a parsed search. Every clause must match, except excluded
+// ones, which must not.
+type SearchQuery struct {
+	Clauses []SearchClause
+}
+
+// SearchClause is one part of a search query
+type SearchClause struct {
+	Terms   []string // one word, or the words of a phrase
+	Exact   bool     // quoted: no typos allowed
+	Prefix  bool     // the word, or a phrase's last word, is a prefix ("rep*", "e-mai*")
+	Exclude bool     // tasks matching the clause are left out ("-draft")
+}
+
+// ParseSearchQuery parses a search: words, which also match words a typo or
+// two away; "quoted phrases", whose words must appear in order; prefixes
+// ending in "*"; and any of these preceded by "-" to exclude matches.
+func ParseSearchQuery(query string) (SearchQuery, error) {
+	var q SearchQuery
+	s := strings.TrimSpace(query)
+	for len(s) > 0 {
+		var c SearchClause
+		if s[0] == '-' {
+			c.Exclude = true
+			s = s[1:]
+		}
+
+		if strings.HasPrefix(s, `"`) {
+			end := strings.IndexByte(s[1:], '"')
+			if end < 0 {
+				return q, fmt.Errorf("unterminated phrase in search %q", query)
+			}
+			c.Terms = Tokenize(s[1 : end+1])
+			c.Exact = true
+			s = s[end+2:]
+		} else {
+			word, rest, _ := strings.Cut(s, " ")
+			s = rest
+			if strings.HasSuffix(word, "*") {
+				c.Prefix = true
+				word = strings.TrimRight(word, "*")
+			}
+			// "e-mail" is the phrase "e mail"; a prefix applies to the last word
+			c.Terms = Tokenize(word)
+		}
+
+		s = strings.TrimSpace(s)
+		if len(c.Terms) > 0 {
+			q.Clauses = append(q.Clauses, c)
+		}
+	}
+
+	hasPositive := false
+	for _, c := range q.Clauses {
+		if !c.Exclude {
+			hasPositive = true
+		}
+	}
+	if !hasPositive {
+		return q, fmt.Errorf("search %q has no words to look for", query)
+	}
+	return q, nil
+}
+
+// Search returns the tasks matching query, most relevant first; ties go to
+// open tasks, then newer ones. A limit of 0 returns every match.
+func (ix *SearchIndex) Search(query SearchQuery, limit int) []SearchResult {
+	return ix.SearchMatching(query, limit, nil)
+}
+
+// SearchMatching is like Search but leaves out the tasks for which keep
+// returns false, so that one index can serve searches over parts of the
+// task list. A nil keep keeps every task.
+func (ix *SearchIndex) SearchMatching(query SearchQuery, limit int, keep func(Task) bool) []SearchResult {
+	type hit struct {
+		score  float64
+		fields uint8 // bit set of SearchField
+	}
+	var hits map[int32]*hit
+	excluded := make(map[int32]bool)
+
+	for _, c := range query.Clauses {
+		matches := ix.matchClause(c)
+		if c.Exclude {
+			for doc := range matches {
+				excluded[doc] = true
+			}
+			continue
+		}
+
+		if hits == nil {
+			hits = make(map[int32]*hit, len(matches))
+			for doc, m := range matches {
+				hits[doc] = &hit{score: m.score, fields: m.fields}
+			}
+			continue
+		}
+		// Every clause must match: keep the tasks matched so far that match this one too
+		for doc, h := range hits {
+			m, ok := matches[doc]
+			if !ok {
+				delete(hits, doc)
+				continue
+			}
+			h.score += m.score
+			h.fields |= m.fields
+		}
+	}
+
+	results := make([]SearchResult, 0, len(hits))
+	for doc, h := range hits {
+		if excluded[doc] || (keep != nil && !keep(ix.tasks[doc])) {
+			continue
+		}
+		var fields []string
+		for f := SearchField(0); f < numSearchFields; f++ {
+			if h.fields&(1<<f) != 0 {
+				fields = append(fields, f.String())
+			}
+		}
+		results = append(results, SearchResult{Task: ix.tasks[doc], Score: math.Round(h.score*1000) / 1000, Fields: fields})
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		a, b := &results[i], &results[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		if a.Task.IsDone() != b.Task.IsDone() {
+			return !a.Task.IsDone()
+		}
+		if !a.Task.CreatedAt.Equal(b.Task.CreatedAt) {
+			return a.Task.CreatedAt.After(b.Task.CreatedAt)
+		}
+		return a.Task.ID < b.Task.ID
+	})
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
+
+// clauseMatch is how well one task matches one clause
+type clauseMatch struct {
+	score  float64
+	fields uint8
+}
+
+// matchClause scores the tasks matching a clause
+func (ix *SearchIndex) matchClause(c SearchClause) map[int32]clauseMatch {
+	if len(c.Terms) > 1 {
+		return ix.matchPhrase(c.Terms, c.Prefix)
+	}
+
+	// A term counts in every field it occurs in, but of the words a query
+	// word expands to only the best match counts
+	matches := make(map[int32]clauseMatch)
+	for term, weight := range ix.expand(c.Terms[0], c.Prefix, c.Exact) {
+		scores := make(map[int32]float64)
+		ix.scoreTerm(term, weight, func(p posting, score float64) {
+			scores[p.doc] += score
+			m := matches[p.doc]
+			m.fields |= 1 << p.field
+			matches[p.doc] = m
+		})
+		for doc, score := range scores {
+			m := matches[doc]
+			m.score = math.Max(m.score, score)
+			matches[doc] = m
+		}
+	}
+	return matches
+}
+
+// matchPhrase scores the tasks in which the terms appear next to each other,
+// in order, in the same field. With prefix, the last term is a prefix.
+func (ix *SearchIndex) matchPhrase(terms []string, prefix bool) map[int32]clauseMatch {
+	type key struct {
+		doc   int32
+		field SearchField
+	}
+	// The indexed terms each position of the phrase matches, with their weights
+	steps := make([]map[string]float64, len(terms))
+	for i, term := range terms {
+		steps[i] = map[string]float64{term: 1}
+	}
+	if prefix {
+		steps[len(terms)-1] = ix.expand(terms[len(terms)-1], true, true)
+	}
+
+	// Positions at which the phrase so far ends, per task and field
+	ends := make(map[key][]int32)
+	for _, p := range ix.postings[terms[0]] {
+		ends[key{p.doc, p.field}] = p.positions
+	}
+	for _, step := range steps[1:] {
+		next := make(map[key][]int32)
+		for term := range step {
+			for _, p := range ix.postings[term] {
+				k := key{p.doc, p.field}
+				prev, ok := ends[k]
+				if !ok {
+					continue
+				}
+				for _, pos := range p.positions {
+					if containsPos(prev, pos-1) {
+						next[k] = append(next[k], pos)
+					}
+				}
+			}
+		}
+		for k := range next {
+			slices.Sort(next[k])
+		}
+		ends = next
+	}
+
+	matches := make(map[int32]clauseMatch)
+	for k := range ends {
+		m := matches[k.doc]
+		m.fields |= 1 << k.field
+		matches[k.doc] = m
+	}
+	for _, step := range steps {
+		for term, weight := range step {
+			ix.scoreTerm(term, phraseBoost*weight, func(p posting, score float64) {
+				if m, ok := matches[p.doc]; ok && m.fields&(1<<p.field) != 0 {
+					m.score += score
+					matches[p.doc] = m
+				}
+			})
+		}
+	}
+	return matches
+}
+
+func containsPos(positions []int32, pos int32) bool {
+	i := sort.Search(len(positions), func(i int) bool { return positions[i] >= pos })
+	return i < len(positions) && positions[i] == pos
+}
+
+// scoreTerm calls fn with the BM25 score of each field a term occurs in,
+// scaled by weight
+func (ix *SearchIndex) scoreTerm(term string, weight float64, fn func(p posting, score float64)) {
+	ps := ix.postings[term]
+	if len(ps) == 0 {
+		return
+	}
+
+	docs := 0
+	last := int32(-1)
+	for _, p := range ps {
+		if p.doc != last {
+			docs++
+			last = p.doc
+		}
+	}
+	n := float64(len(ix.tasks))
+	idf := math.Log(1 + (n-float64(docs)+0.5)/(float64(docs)+0.5))
+
+	for _, p := range ps {
+		tf := float64(len(p.positions))
+		norm := 1.0
+		if avg := ix.avgLen[p.field]; avg > 0 {
+			norm = 1 - bm25B + bm25B*float64(ix.fieldLen[p.doc][p.field])/avg
+		}
+		score := weight * searchFieldWeights[p.field] * idf * tf * (bm25K1 + 1) / (tf + bm25K1*norm)
+		fn(p, score)
+	}
+}
+
+// expand returns the indexed terms a query word matches, with how much a
+// match on each counts: the word itself, words it is a prefix of, or words
+// a typo or two away unless the word is exact
+func (ix *SearchIndex) expand(word string, prefix, exact bool) map[string]float64 {
+	terms := make(map[string]float64)
+	if prefix {
+		i := sort.SearchStrings(ix.vocab, word)
+		for ; i < len(ix.vocab) && strings.HasPrefix(ix.vocab[i], word) && len(terms) < maxPrefixTerm; i++ {
+			if ix.vocab[i] == word {
+				terms[word] = 1
+			} else {
+				terms[ix.vocab[i]] = prefixWeight
+			}
+		}
+		return terms
+	}
+
+	if _, ok := ix.postings[word]; ok {
+		terms[word] = 1
+	}
+	maxEdits := fuzzyEdits(word)
+	if exact || maxEdits == 0 || !hasLetter(word) {
+		return terms
+	}
+	wr := []rune(word)
+	rows := make([]int, 3*(len(wr)+maxEdits+1))
+	for n := max(len(wr)-maxEdits, 1); n <= len(wr)+maxEdits && n < len(ix.byLen); n++ {
+		for _, v := range ix.byLen[n] {
+			switch d := editDistance(wr, v, maxEdits, rows); {
+			case d > maxEdits || d == 0:
+			case d == 1:
+				terms[string(v)] = fuzzyWeight1
+			default:
+				terms[string(v)] = fuzzyWeight2
+			}
+		}
+	}
+	return terms
+}
+
+func hasLetter(s string) bool {
+	return strings.IndexFunc(s, unicode.IsLetter) >= 0
+}
+
+// fuzzyEdits is how many typos a word may contain: none for short words,
+// where a single edit gives too many false matches
+func fuzzyEdits(word string) int {
+	switch n := len([]rune(word)); {
+	case n < 4:
+		return 0
+	case n < 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// editDistance returns the optimal string alignment distance between a and
+// b (insertions, deletions, substitutions and transpositions of adjacent
+// letters), or max+1 if it exceeds max. rows is scratch space for three rows
+// of the table, reallocated if shorter than 3*(len(b)+1).
+func editDistance(a, b []rune, max int, rows []int) int {
+	if abs(len(a)-len(b)) > max {
+		return max + 1
+	}
+	if n := 3 * (len(b) + 1); len(rows) < n {
+		rows = make([]int, n)
+	}
+	prev2 := rows[:len(b)+1]
+	prev := rows[len(b)+1 : 2*(len(b)+1)]
+	cur := rows[2*(len(b)+1) : 3*(len(b)+1)]
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		rowMin := cur[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, cur[j])
+		}
+		if rowMin > max {
+			return max + 1
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	if prev[len(b)] > max {
+		return max + 1
+	}
+	return prev[len(b)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,128 @@
+package core
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func searchIDs(results []SearchResult) []int {
+	ids := make([]int, len(results))
+	for i, r := range results {
+		ids[i] = r.Task.ID
+	}
+	return ids
+}
+
+func TestSearchIndex(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	tasks := []Task{
+		{ID: 1, Title: "Write quarterly report", Tags: []string{"work"}, CreatedAt: now},
+		{ID: 2, Title: "Review budget", Description: "numbers for the quarterly report", CreatedAt: now},
+		{ID: 3, Title: "Buy milk", Tags: []string{"errands"}, CreatedAt: now},
+		{ID: 4, Title: "Report draft", Description: "first draft", DoneAt: &now, CreatedAt: now},
+		{ID: 5, Title: "Call plumber", TimeEntries: []TimeEntry{{Start: now, End: &now, Note: "discussed the boiler report"}}, CreatedAt: now},
+		{ID: 6, Title: "Repair bike", CreatedAt: now},
+		{ID: 7, Title: "Answer e-mails", CreatedAt: now},
+	}
+	ix := NewSearchIndex(tasks)
+
+	cases := map[string][]int{
+		"report":             {4, 1, 2, 5}, // title matches first, shorter titles first, notes last
+		"quarterly report":   {1, 2},
+		`"quarterly report"`: {1, 2},
+		`"report quarterly"`: {},
+		"reprot":             {4, 1, 2, 5},    // transposed letters
+		"quartely":           {1, 2},          // a missing letter
+		"rep*":               {6, 4, 1, 2, 5}, // "repair" is rarer than "report"
+		"report -draft":      {1, 2, 5},
+		"work":               {1},
+		"boiler":             {5},
+		"milk errands":       {3},
+		"mlk":                {},  // too short to correct
+		"e-mai*":             {7}, // a prefix on the last word of a phrase
+		"mails-e*":           {},
+	}
+	for query, want := range cases {
+		q, err := ParseSearchQuery(query)
+		if err != nil {
+			t.Errorf("ParseSearchQuery(%q): unexpected error: %v", query, err)
+			continue
+		}
+		got := searchIDs(ix.Search(q, 0))
+		if fmt.Sprint(got) != fmt.Sprint(want) {
+			t.Errorf("%s: got %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestSearchRanksExactAboveFuzzy(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Title: "Fix the form"},
+		{ID: 2, Title: "Fix the from address"},
+	}
+	q, _ := ParseSearchQuery("form")
+	results := NewSearchIndex(tasks).Search(q, 0)
+	if len(results) != 2 || results[0].Task.ID != 1 || results[0].Score <= results[1].Score {
+		t.Errorf("Expected the exact match first, got %+v", results)
+	}
+	if results[0].Fields[0] != "title" {
+		t.Errorf("Expected a title match, got %v", results[0].Fields)
+	}
+}
+
+func TestParseSearchQueryErrors(t *testing.T) {
+	for _, bad := range []string{"", "   ", "-draft", `"unterminated`} {
+		if _, err := ParseSearchQuery(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		want int
+	}{
+		{"report", "report", 0},
+		{"report", "reprot", 1},
+		{"report", "repot", 1},
+		{"report", "reports", 1},
+		{"report", "rapport", 2},
+		{"report", "banana", 3}, // beyond the limit
+	}
+	for _, c := range cases {
+		if got := editDistance([]rune(c.a), []rune(c.b), 2, nil); got != c.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", c.a, c.b, got, c.want)
+		}
+	}
+}
+
+func BenchmarkSearch(b *testing.B) {
+	words := []string{"report", "review", "budget", "meeting", "invoice", "client", "deploy", "release", "design", "draft"}
+	now := time.Now()
+	tasks := make([]Task, 30000)
+	for i := range tasks {
+		tasks[i] = Task{
+			ID:          i + 1,
+			Title:       fmt.Sprintf("%s %s %d", words[i%len(words)], words[(i/7)%len(words)], i),
+			Description: fmt.Sprintf("notes about %s for customer %d", words[(i/3)%len(words)], i%500),
+			Tags:        []string{words[(i/11)%len(words)]},
+			DoneAt:      &now,
+		}
+	}
+
+	b.Run("index", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewSearchIndex(tasks)
+		}
+	})
+
+	ix := NewSearchIndex(tasks)
+	q, _ := ParseSearchQuery(`reviw "client" budg*`)
+	b.Run("query", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			ix.Search(q, 20)
+		}
+	})
+}
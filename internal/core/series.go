@@ -0,0 +1,162 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// EditScope selects which occurrences of a recurring task an edit applies to
+type EditScope string
+
+const (
+	ScopeThis   EditScope = "this"   // only the edited occurrence (default)
+	ScopeFuture EditScope = "future" // the edited occurrence and all later ones
+)
+
+// ParseEditScope validates an edit scope; empty means ScopeThis
+func ParseEditScope(s string) (EditScope, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", string(ScopeThis):
+		return ScopeThis, nil
+	case string(ScopeFuture), "all":
+		return ScopeFuture, nil
+	default:
+		return "", fmt.Errorf("invalid scope %q (use this or future)", s)
+	}
+}
+
+// SeriesKey returns the ID identifying the series the task belongs to: the
+// ID of its first occurrence
+func (t *Task) SeriesKey() int {
+	if t.SeriesID != 0 {
+		return t.SeriesID
+	}
+	return t.ID
+}
+
+// InSeries reports whether the task is, or was, an occurrence of a
+// recurring task
+func (t *Task) InSeries() bool {
+	return t.Repeat != "" || t.SeriesID != 0
+}
+
+// Detach keeps the current series fields of a recurring occurrence in
+// Pattern before the occurrence is edited on its own, so that later
+// occurrences are still generated from the unedited series
+func (t *Task) Detach() {
+	if t.Repeat == "" || t.Pattern != nil {
+		return
+	}
+	pattern := *t
+	pattern.DoneAt = nil
+	t.Pattern = &pattern
+}
+
+// SeriesOf returns every occurrence in the series of the task with the given
+// ID, oldest first
+func SeriesOf(tasks []Task, id int) ([]Task, error) {
+	task, err := GetByID(tasks, id)
+	if err != nil {
+		return nil, err
+	}
+	if !task.InSeries() {
+		return nil, fmt.Errorf("task %d is not recurring", id)
+	}
+
+	key := task.SeriesKey()
+	var series []Task
+	for _, t := range tasks {
+		if t.SeriesID == key || t.ID == key {
+			series = append(series, t)
+		}
+	}
+
+	// Occurrences are spawned in order, so IDs follow the series
+	sort.Slice(series, func(i, j int) bool { return series[i].ID < series[j].ID })
+	return series, nil
+}
+
+// SkipOccurrence closes an open occurrence without completing it and
+// schedules the next one. It returns the updated tasks and the new
+// occurrence, which is nil when the series has ended.
+func SkipOccurrence(tasks []Task, id int, now time.Time) ([]Task, *Task, error) {
+	for i := range tasks {
+		if tasks[i].ID != id {
+			continue
+		}
+		if tasks[i].Repeat == "" {
+			return tasks, nil, fmt.Errorf("task %d is not recurring", id)
+		}
+		if tasks[i].IsDone() {
+			return tasks, nil, fmt.Errorf("task already completed")
+		}
+
+		tasks[i].transition(StatusCancelled, now)
+		tasks[i].DoneAt = &now
+		tasks[i].Skipped = true
+
+		n := len(tasks)
+		tasks = scheduleNext(tasks, i, now)
+		if len(tasks) == n {
+			return tasks, nil, nil
+		}
+		return tasks, &tasks[n], nil
+	}
+
+	return tasks, nil, fmt.Errorf("task %d not found", id)
+}
+
+// StopSeries ends the series of the task with the given ID. Open occurrences
+// are kept but will not repeat again.
+func StopSeries(tasks []Task, id int) ([]Task, error) {
+	task, err := GetByID(tasks, id)
+	if err != nil {
+		return tasks, err
+	}
+	if task.Repeat == "" && task.SeriesID == 0 {
+		return tasks, fmt.Errorf("task %d is not recurring", id)
+	}
+
+	key := task.SeriesKey()
+	for i := range tasks {
+		t := &tasks[i]
+		if t.IsDone() || (t.SeriesID != key && t.ID != key) {
+			continue
+		}
+		t.Repeat = ""
+		t.RepeatFrom = ""
+		t.SkipMissed = false
+		t.Pattern = nil
+	}
+
+	return tasks, nil
+}
+
+// scheduleNext appends the next occurrence of the just-closed task at index
+// i, if its rule has one, linking it into the task's series
+func scheduleNext(tasks []Task, i int, now time.Time) []Task {
+	if tasks[i].Repeat == "" {
+		return tasks
+	}
+
+	// An occurrence that was edited on its own continues the series pattern
+	source := tasks[i]
+	if source.Pattern != nil {
+		source = *source.Pattern
+	}
+
+	next := createNextRecurrenceAt(source, now)
+	if next == nil {
+		return tasks
+	}
+
+	key := tasks[i].SeriesKey()
+	tasks[i].SeriesID = key
+	next.ID = NextID(tasks)
+	next.SeriesID = key
+	next.PreviousID = tasks[i].ID
+
+	return append(tasks, *next)
+}
@@ -0,0 +1,105 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecurrenceAllocatesFreshID(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	due := time.Date(2025, 10, 22, 0, 0, 0, 0, time.UTC)
+
+	// The recurring task is not the newest one
+	tasks := []Task{
+		{ID: 1, Title: "Standup", Due: &due, Repeat: "daily"},
+		{ID: 2, Title: "Other"},
+		{ID: 3, Title: "Another"},
+	}
+
+	tasks, err := MarkDoneAt(tasks, tasks, 1, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tasks) != 4 {
+		t.Fatalf("Expected 4 tasks, got %d", len(tasks))
+	}
+
+	next := tasks[3]
+	if next.ID != 4 {
+		t.Errorf("Expected new occurrence to get ID 4, got %d", next.ID)
+	}
+	if next.SeriesID != 1 || next.PreviousID != 1 || tasks[0].SeriesID != 1 {
+		t.Errorf("Expected occurrences linked into series 1, got %+v / %+v", tasks[0], next)
+	}
+}
+
+func TestDedupeIDs(t *testing.T) {
+	tasks := []Task{{ID: 1}, {ID: 2}, {ID: 2}, {ID: 3}}
+
+	if !DedupeIDs(tasks) {
+		t.Fatal("Expected duplicates to be repaired")
+	}
+	if tasks[1].ID != 2 || tasks[2].ID != 4 {
+		t.Errorf("Expected the later duplicate to be renumbered, got %d and %d", tasks[1].ID, tasks[2].ID)
+	}
+	if DedupeIDs(tasks) {
+		t.Error("Expected no changes on a clean list")
+	}
+}
+
+func TestSeriesSkipAndStop(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	due := time.Date(2025, 10, 22, 0, 0, 0, 0, time.UTC)
+	tasks := []Task{{ID: 1, Title: "Gym", Due: &due, Repeat: "daily"}}
+
+	tasks, _ = MarkDoneAt(tasks, tasks, 1, now)
+	tasks, next, err := SkipOccurrence(tasks, 2, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if next == nil || next.ID != 3 || !next.Due.Equal(due.AddDate(0, 0, 2)) {
+		t.Fatalf("Expected occurrence #3 two days later, got %+v", next)
+	}
+
+	series, err := SeriesOf(tasks, 3)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(series) != 3 || !series[1].Skipped || series[1].DoneAt == nil {
+		t.Fatalf("Expected three occurrences with the second skipped, got %+v", series)
+	}
+
+	tasks, err = StopSeries(tasks, 1)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	tasks, _ = MarkDoneAt(tasks, tasks, 3, now)
+	if len(tasks) != 3 {
+		t.Errorf("Expected no occurrence after stopping the series, got %d tasks", len(tasks))
+	}
+
+	if _, _, err := SkipOccurrence(tasks, 3, now); err == nil {
+		t.Error("Expected error skipping a stopped occurrence")
+	}
+}
+
+func TestDetachedOccurrence(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	due := time.Date(2025, 10, 22, 0, 0, 0, 0, time.UTC)
+	tasks := []Task{{ID: 1, Title: "Review", Due: &due, Repeat: "weekly", Priority: 1}}
+
+	// Move just this occurrence and raise its priority
+	tasks[0].Detach()
+	moved := due.AddDate(0, 0, 2)
+	tasks[0].Due = &moved
+	tasks[0].Priority = 3
+
+	tasks, _ = MarkDoneAt(tasks, tasks, 1, now)
+	next := tasks[1]
+	if !next.Due.Equal(due.AddDate(0, 0, 7)) {
+		t.Errorf("Expected the series schedule to be kept, got %s", next.Due)
+	}
+	if next.Priority != 1 || next.Pattern != nil {
+		t.Errorf("Expected the next occurrence to come from the pattern, got %+v", next)
+	}
+}
@@ -1,8 +1,11 @@
 package core
 
 import (
+	"cmp"
+	"fmt"
 	"sort"
 	"strings"
+	"time"
 )
 
 // SortKey represents the field to sort by
@@ -14,135 +17,239 @@ const (
 	SortByCreated  SortKey = "created"
 	SortByStatus   SortKey = "status"
 	SortByTitle    SortKey = "title"
+	SortByUrgency  SortKey = "urgency"
 )
 
-// SortedWith returns a filtered and sorted copy of tasks
-func SortedWith(tasks []Task, showAll bool, grep, sortKey string) []Task {
-	// First filter by status
-	result := FilterByStatus(tasks, showAll)
-
-	// Apply search filter
-	if grep != "" {
-		result = SearchTasks(result, grep)
-	}
+// SortKeys lists the valid sort keys
+var SortKeys = []SortKey{SortByDue, SortByPriority, SortByCreated, SortByStatus, SortByTitle, SortByUrgency}
 
-	// Sort
-	SortTasks(result, SortKey(sortKey))
+// descByDefault lists the keys sorted in descending order unless a direction
+// is given: highest priority, newest and most urgent first
+var descByDefault = map[SortKey]bool{
+	SortByPriority: true,
+	SortByCreated:  true,
+	SortByUrgency:  true,
+}
 
-	return result
+// SortField is one key of a sort order with its direction
+type SortField struct {
+	Key  SortKey
+	Desc bool
 }
 
-// SortTasks sorts tasks in place according to the specified key
-func SortTasks(tasks []Task, key SortKey) {
-	switch key {
-	case SortByDue:
-		sortByDue(tasks)
-	case SortByPriority:
-		sortByPriority(tasks)
-	case SortByCreated:
-		sortByCreated(tasks)
-	case SortByStatus:
-		sortByStatus(tasks)
-	case SortByTitle:
-		sortByTitle(tasks)
-	default:
-		sortByDue(tasks) // default
+// SortOrder is a parsed sort expression: the keys to sort by, most
+// significant first
+type SortOrder []SortField
+
+// DefaultSortOrder sorts by due date
+var DefaultSortOrder = SortOrder{{Key: SortByDue}}
+
+// ParseSort parses a sort expression such as "priority:desc,due:asc,title":
+// comma-separated keys, each optionally followed by ":asc" or ":desc". Keys
+// without a direction use their natural one (see descByDefault). An empty
+// expression sorts by due date.
+func ParseSort(expr string) (SortOrder, error) {
+	if strings.TrimSpace(expr) == "" {
+		return DefaultSortOrder, nil
 	}
-}
 
-// sortByDue sorts by due date (nil dates at the end), then by priority
-func sortByDue(tasks []Task) {
-	sort.SliceStable(tasks, func(i, j int) bool {
-		// Both have due dates
-		if tasks[i].Due != nil && tasks[j].Due != nil {
-			if !tasks[i].Due.Equal(*tasks[j].Due) {
-				return tasks[i].Due.Before(*tasks[j].Due)
+	var order SortOrder
+	seen := make(map[SortKey]bool)
+	for _, part := range strings.Split(expr, ",") {
+		name, dir, hasDir := strings.Cut(strings.ToLower(strings.TrimSpace(part)), ":")
+		key := SortKey(strings.TrimSpace(name))
+		if !isSortKey(key) {
+			return nil, fmt.Errorf("unknown sort key %q (use %s)", name, joinSortKeys())
+		}
+		if seen[key] {
+			return nil, fmt.Errorf("sort key %q given twice", key)
+		}
+		seen[key] = true
+
+		field := SortField{Key: key, Desc: descByDefault[key]}
+		if hasDir {
+			switch strings.TrimSpace(dir) {
+			case "asc":
+				field.Desc = false
+			case "desc":
+				field.Desc = true
+			default:
+				return nil, fmt.Errorf("invalid sort direction %q for %s (use asc or desc)", dir, key)
 			}
-			// Same due date, sort by priority
-			return tasks[i].Priority > tasks[j].Priority
 		}
+		order = append(order, field)
+	}
+	return order, nil
+}
 
-		// Only i has due date
-		if tasks[i].Due != nil {
-			return true
+// String formats the order as a sort expression with explicit directions
+func (o SortOrder) String() string {
+	parts := make([]string, len(o))
+	for i, f := range o {
+		dir := "asc"
+		if f.Desc {
+			dir = "desc"
 		}
+		parts[i] = string(f.Key) + ":" + dir
+	}
+	return strings.Join(parts, ",")
+}
 
-		// Only j has due date
-		if tasks[j].Due != nil {
-			return false
+func isSortKey(key SortKey) bool {
+	for _, k := range SortKeys {
+		if k == key {
+			return true
 		}
+	}
+	return false
+}
 
-		// Neither has due date, sort by priority
-		return tasks[i].Priority > tasks[j].Priority
-	})
+func joinSortKeys() string {
+	names := make([]string, len(SortKeys))
+	for i, k := range SortKeys {
+		names[i] = string(k)
+	}
+	return strings.Join(names, ", ")
 }
 
-// sortByPriority sorts by priority (high to low), then by due date
-func sortByPriority(tasks []Task) {
-	sort.SliceStable(tasks, func(i, j int) bool {
-		if tasks[i].Priority != tasks[j].Priority {
-			return tasks[i].Priority > tasks[j].Priority
-		}
+// SortedWith returns a filtered and sorted copy of tasks. An invalid sort
+// expression sorts by due date.
+func SortedWith(tasks []Task, showAll bool, grep, sortExpr string) []Task {
+	order, err := ParseSort(sortExpr)
+	if err != nil {
+		order = DefaultSortOrder
+	}
+	return SortedWithAt(tasks, showAll, grep, order, defaultScorer(tasks))
+}
 
-		// Same priority, sort by due date
-		if tasks[i].Due != nil && tasks[j].Due != nil {
-			return tasks[i].Due.Before(*tasks[j].Due)
-		}
+// SortedWithAt is SortedWith with a parsed sort order and the scorer used
+// for the urgency key
+func SortedWithAt(tasks []Task, showAll bool, grep string, order SortOrder, scorer UrgencyScorer) []Task {
+	// First filter by status
+	result := FilterByStatus(tasks, showAll)
 
-		if tasks[i].Due != nil {
-			return true
-		}
+	// Apply search filter
+	if grep != "" {
+		result = SearchTasks(result, grep)
+	}
 
-		return false
-	})
+	// Sort
+	SortTasksBy(result, order, scorer)
+
+	return result
 }
 
-// sortByCreated sorts by creation date (newest first)
-func sortByCreated(tasks []Task) {
-	sort.SliceStable(tasks, func(i, j int) bool {
-		return tasks[i].CreatedAt.After(tasks[j].CreatedAt)
-	})
+// SortTasks sorts tasks in place according to the specified key, in its
+// natural direction; unknown keys sort by due date. The urgency key scores
+// tasks at the current time with the default weights.
+func SortTasks(tasks []Task, key SortKey) {
+	SortTasksAt(tasks, key, defaultScorer(tasks))
 }
 
-// sortByStatus sorts by completion status (pending first, then completed)
-func sortByStatus(tasks []Task) {
-	sort.SliceStable(tasks, func(i, j int) bool {
-		iDone := tasks[i].IsDone()
-		jDone := tasks[j].IsDone()
+// SortTasksAt is SortTasks with the scorer used for the urgency key
+func SortTasksAt(tasks []Task, key SortKey, scorer UrgencyScorer) {
+	if !isSortKey(key) {
+		key = SortByDue
+	}
+	SortTasksBy(tasks, SortOrder{{Key: key, Desc: descByDefault[key]}}, scorer)
+}
 
-		if iDone != jDone {
-			return !iDone // pending tasks first
-		}
+// MultiSort allows sorting by multiple criteria, each in its natural
+// direction; the first key is the most significant
+func MultiSort(tasks []Task, keys []SortKey) {
+	if len(keys) == 0 {
+		return
+	}
 
-		// Same status, sort by due date
-		if tasks[i].Due != nil && tasks[j].Due != nil {
-			return tasks[i].Due.Before(*tasks[j].Due)
+	order := make(SortOrder, 0, len(keys))
+	for _, k := range keys {
+		if isSortKey(k) {
+			order = append(order, SortField{Key: k, Desc: descByDefault[k]})
 		}
+	}
+	SortTasksBy(tasks, order, defaultScorer(tasks))
+}
 
-		if tasks[i].Due != nil {
-			return true
+// SortTasksBy sorts tasks in place by each field of order in turn. Ties
+// left by the order are broken by due date, then by priority (highest
+// first); tasks without a due date sort after dated ones in either
+// direction.
+func SortTasksBy(tasks []Task, order SortOrder, scorer UrgencyScorer) {
+	order = order.withTieBreakers()
+
+	var scores map[int]float64
+	for _, f := range order {
+		if f.Key == SortByUrgency {
+			scores = make(map[int]float64, len(tasks))
+			for _, t := range tasks {
+				scores[t.ID] = scorer.Score(t).Score
+			}
+			break
 		}
+	}
 
-		return false
-	})
-}
-
-// sortByTitle sorts alphabetically by title
-func sortByTitle(tasks []Task) {
 	sort.SliceStable(tasks, func(i, j int) bool {
-		return strings.ToLower(tasks[i].Title) < strings.ToLower(tasks[j].Title)
+		a, b := &tasks[i], &tasks[j]
+		for _, f := range order {
+			if f.Key == SortByDue && (a.Due == nil) != (b.Due == nil) {
+				return a.Due != nil
+			}
+			c := compareBy(f.Key, a, b, scores)
+			if f.Desc {
+				c = -c
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
 	})
 }
 
-// MultiSort allows sorting by multiple criteria
-func MultiSort(tasks []Task, keys []SortKey) {
-	if len(keys) == 0 {
-		return
+// withTieBreakers appends due date and priority to the order unless it
+// already sorts by them
+func (o SortOrder) withTieBreakers() SortOrder {
+	result := append(SortOrder{}, o...)
+	for _, tb := range []SortField{{Key: SortByDue}, {Key: SortByPriority, Desc: true}} {
+		found := false
+		for _, f := range o {
+			if f.Key == tb.Key {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, tb)
+		}
 	}
+	return result
+}
 
-	// Apply sorts in reverse order (last key has least priority)
-	for i := len(keys) - 1; i >= 0; i-- {
-		SortTasks(tasks, keys[i])
+// compareBy compares two tasks by one key in ascending order, returning -1,
+// 0 or 1
+func compareBy(key SortKey, a, b *Task, scores map[int]float64) int {
+	switch key {
+	case SortByDue:
+		if a.Due == nil || b.Due == nil {
+			return 0
+		}
+		return a.Due.Compare(*b.Due)
+	case SortByPriority:
+		return cmp.Compare(a.Priority, b.Priority)
+	case SortByCreated:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	case SortByStatus:
+		// in progress, todo, waiting, blocked, then done and cancelled
+		return cmp.Compare(statusRank(a.State()), statusRank(b.State()))
+	case SortByTitle:
+		return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	case SortByUrgency:
+		return cmp.Compare(scores[a.ID], scores[b.ID])
 	}
+	return 0
 }
 
+// defaultScorer scores tasks now with the default weights
+func defaultScorer(tasks []Task) UrgencyScorer {
+	return UrgencyScorer{Weights: DefaultUrgencyWeights(), All: tasks, Now: time.Now()}
+}
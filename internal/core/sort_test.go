@@ -0,0 +1,68 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSort(t *testing.T) {
+	cases := map[string]string{
+		"":                            "due:asc",
+		"priority":                    "priority:desc",
+		"priority:desc,due:asc,title": "priority:desc,due:asc,title:asc",
+		" Created:ASC , urgency ":     "created:asc,urgency:desc",
+	}
+	for in, want := range cases {
+		order, err := ParseSort(in)
+		if err != nil {
+			t.Errorf("ParseSort(%q): unexpected error: %v", in, err)
+			continue
+		}
+		if got := order.String(); got != want {
+			t.Errorf("ParseSort(%q) = %s, want %s", in, got, want)
+		}
+	}
+
+	for _, bad := range []string{"size", "due:up", "due,due", "priority,"} {
+		if _, err := ParseSort(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestSortTasksBy(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	early := now.AddDate(0, 0, 1)
+	late := now.AddDate(0, 0, 5)
+	tasks := []Task{
+		{ID: 1, Title: "b", Priority: 2, Due: &late, CreatedAt: now},
+		{ID: 2, Title: "a", Priority: 2, CreatedAt: now.Add(time.Hour)},
+		{ID: 3, Title: "c", Priority: 3, Due: &late, CreatedAt: now},
+		{ID: 4, Title: "d", Priority: 2, Due: &early, CreatedAt: now},
+	}
+	scorer := UrgencyScorer{Weights: DefaultUrgencyWeights(), All: tasks, Now: now}
+
+	cases := map[string][]int{
+		// Undated tasks stay last whichever way due dates are sorted
+		"priority:desc,due:asc": {3, 4, 1, 2},
+		"priority:asc,due:desc": {1, 4, 2, 3},
+		"title:desc":            {4, 3, 1, 2},
+		// Ties on creation time fall back to due date, then priority
+		"created:asc": {4, 3, 1, 2},
+	}
+	for expr, want := range cases {
+		order, err := ParseSort(expr)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		sorted := append([]Task{}, tasks...)
+		SortTasksBy(sorted, order, scorer)
+		got := taskIDs(sorted)
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: got %v, want %v", expr, got, want)
+				break
+			}
+		}
+	}
+}
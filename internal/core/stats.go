@@ -2,6 +2,7 @@ package core
 
 import (
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 )
@@ -11,6 +12,9 @@ type Stats struct {
 	Total           int
 	Completed       int
 	Pending         int
+	InProgress      int
+	Waiting         int
+	Cancelled       int
 	Overdue         int
 	CompletionRate  float64
 	ByPriority      map[int]int
@@ -19,13 +23,31 @@ type Stats struct {
 	CompletedToday  int
 	CompletedWeek   int
 	BlockedTasks    int
+	TimeTrackedMS   int64 // time logged on the tasks, including a running timer
+
+	// Estimates
+	EstimatedRemainingMS int64            // estimated work left on open tasks
+	EstimatedWeekMS      int64            // estimated work on open tasks due by the end of this week
+	EstimateByTagMS      map[string]int64 // estimated work left, by tag
+	EstimatedDoneMS      int64            // estimates of the completed tasks that had one and took at least a minute
+	ActualDoneMS         int64            // how long those tasks actually took, see Task.ActualEffort
+	EstimateAccuracy     float64          // EstimatedDoneMS as a percentage of ActualDoneMS; below 100 means work took longer than estimated
 }
 
 // CalculateStats computes statistics from a list of tasks
 func CalculateStats(tasks []Task, now time.Time) Stats {
+	return CalculateStatsIn(tasks, tasks, now)
+}
+
+// CalculateStatsIn computes statistics for the selected tasks. Dependencies
+// are looked up in all, so tasks that depend on tasks outside the selection
+// are only counted as blocked when those are still open.
+func CalculateStatsIn(selected, all []Task, now time.Time) Stats {
 	stats := Stats{
 		ByPriority: make(map[int]int),
 		ByTag:      make(map[string]int),
+
+		EstimateByTagMS: make(map[string]int64),
 	}
 
 	var totalCompletionTime time.Duration
@@ -33,8 +55,18 @@ func CalculateStats(tasks []Task, now time.Time) Stats {
 
 	todayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 	weekStart := todayStart.AddDate(0, 0, -int(todayStart.Weekday()))
+	weekEnd := weekStart.AddDate(0, 0, 7)
+
+	for _, task := range selected {
+		stats.TimeTrackedMS += task.TimeSpent(now).Milliseconds()
+
+		// Cancelled tasks and skipped occurrences were never done and are no
+		// longer pending, so they stay out of the completion rate
+		if task.State() == StatusCancelled {
+			stats.Cancelled++
+			continue
+		}
 
-	for _, task := range tasks {
 		stats.Total++
 
 		// Count by status
@@ -55,16 +87,40 @@ func CalculateStats(tasks []Task, now time.Time) Stats {
 					stats.CompletedWeek++
 				}
 			}
+
+			// Tasks closed within a minute were not really worked on
+			if actual := task.ActualEffort(now); task.Estimate > 0 && actual >= time.Minute {
+				stats.EstimatedDoneMS += task.Estimate.Duration().Milliseconds()
+				stats.ActualDoneMS += actual.Milliseconds()
+			}
 		} else {
 			stats.Pending++
 
+			if task.Estimate > 0 {
+				estimate := task.Estimate.Duration().Milliseconds()
+				stats.EstimatedRemainingMS += estimate
+				if task.Due != nil && task.DueIn(now.Location()).Before(weekEnd) {
+					stats.EstimatedWeekMS += estimate
+				}
+				for _, tag := range task.Tags {
+					stats.EstimateByTagMS[strings.ToLower(tag)] += estimate
+				}
+			}
+
+			switch task.State() {
+			case StatusInProgress:
+				stats.InProgress++
+			case StatusWaiting:
+				stats.Waiting++
+			}
+
 			// Check if overdue
 			if task.IsOverdue(now) {
 				stats.Overdue++
 			}
 
-			// Check if blocked
-			if !AllDependenciesMet(tasks, task) {
+			// Check if blocked, by dependencies or by hand
+			if task.State() == StatusBlocked || !AllDependenciesMet(all, task) {
 				stats.BlockedTasks++
 			}
 		}
@@ -83,6 +139,10 @@ func CalculateStats(tasks []Task, now time.Time) Stats {
 		stats.CompletionRate = float64(stats.Completed) / float64(stats.Total) * 100
 	}
 
+	if stats.ActualDoneMS > 0 {
+		stats.EstimateAccuracy = float64(stats.EstimatedDoneMS) / float64(stats.ActualDoneMS) * 100
+	}
+
 	// Calculate average completion time
 	if completedCount > 0 {
 		stats.AvgCompletionMS = totalCompletionTime.Milliseconds() / int64(completedCount)
@@ -93,8 +153,11 @@ func CalculateStats(tasks []Task, now time.Time) Stats {
 
 // StatsReport generates a human-readable statistics report
 func StatsReport(tasks []Task, now time.Time) string {
-	stats := CalculateStats(tasks, now)
+	return FormatStats(CalculateStats(tasks, now))
+}
 
+// FormatStats renders statistics as a human-readable report
+func FormatStats(stats Stats) string {
 	var sb strings.Builder
 
 	sb.WriteString("Task Statistics\n")
@@ -104,8 +167,11 @@ func StatsReport(tasks []Task, now time.Time) string {
 	sb.WriteString(fmt.Sprintf("Total Tasks:     %d\n", stats.Total))
 	sb.WriteString(fmt.Sprintf("Completed:       %d\n", stats.Completed))
 	sb.WriteString(fmt.Sprintf("Pending:         %d\n", stats.Pending))
+	sb.WriteString(fmt.Sprintf("In Progress:     %d\n", stats.InProgress))
+	sb.WriteString(fmt.Sprintf("Waiting:         %d\n", stats.Waiting))
 	sb.WriteString(fmt.Sprintf("Overdue:         %d\n", stats.Overdue))
 	sb.WriteString(fmt.Sprintf("Blocked:         %d\n", stats.BlockedTasks))
+	sb.WriteString(fmt.Sprintf("Cancelled:       %d\n", stats.Cancelled))
 	sb.WriteString(fmt.Sprintf("Completion Rate: %.1f%%\n\n", stats.CompletionRate))
 
 	// Productivity
@@ -117,8 +183,30 @@ func StatsReport(tasks []Task, now time.Time) string {
 		avgDays := float64(stats.AvgCompletionMS) / (1000 * 60 * 60 * 24)
 		sb.WriteString(fmt.Sprintf("Avg Completion Time: %.1f days\n", avgDays))
 	}
+	if stats.TimeTrackedMS > 0 {
+		tracked := time.Duration(stats.TimeTrackedMS) * time.Millisecond
+		sb.WriteString(fmt.Sprintf("Time Tracked: %s\n", FormatTimeSpent(tracked)))
+	}
 	sb.WriteString("\n")
 
+	// Estimates
+	if stats.EstimatedRemainingMS > 0 || stats.EstimatedDoneMS > 0 {
+		ms := func(n int64) string { return FormatTimeSpent(time.Duration(n) * time.Millisecond) }
+
+		sb.WriteString("Estimates\n")
+		sb.WriteString("---------\n")
+		sb.WriteString(fmt.Sprintf("Remaining Work:  %s\n", ms(stats.EstimatedRemainingMS)))
+		sb.WriteString(fmt.Sprintf("Due This Week:   %s\n", ms(stats.EstimatedWeekMS)))
+		if stats.ActualDoneMS > 0 {
+			sb.WriteString(fmt.Sprintf("Accuracy:        %.0f%% (estimated %s, took %s)\n",
+				stats.EstimateAccuracy, ms(stats.EstimatedDoneMS), ms(stats.ActualDoneMS)))
+		}
+		for _, tag := range sortedKeys(stats.EstimateByTagMS) {
+			sb.WriteString(fmt.Sprintf("#%-15s %s\n", tag, ms(stats.EstimateByTagMS[tag])))
+		}
+		sb.WriteString("\n")
+	}
+
 	// By priority
 	if len(stats.ByPriority) > 0 {
 		sb.WriteString("By Priority\n")
@@ -169,6 +257,16 @@ func StatsReport(tasks []Task, now time.Time) string {
 	return sb.String()
 }
 
+// sortedKeys returns the keys of m in order
+func sortedKeys(m map[string]int64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // GetUpcomingTasks returns tasks due within the specified duration
 func GetUpcomingTasks(tasks []Task, now time.Time, window time.Duration) []Task {
 	result := make([]Task, 0)
@@ -0,0 +1,152 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Status is where a task is in its lifecycle
+type Status string
+
+const (
+	StatusTodo       Status = "todo"
+	StatusInProgress Status = "in-progress"
+	StatusWaiting    Status = "waiting" // on someone or something outside the task list
+	StatusBlocked    Status = "blocked"
+	StatusCancelled  Status = "cancelled"
+	StatusDone       Status = "done"
+)
+
+// Statuses lists every status in lifecycle order
+var Statuses = []Status{StatusInProgress, StatusTodo, StatusWaiting, StatusBlocked, StatusDone, StatusCancelled}
+
+// StatusChange records a transition between two statuses
+type StatusChange struct {
+	From Status    `json:"from"`
+	To   Status    `json:"to"`
+	At   time.Time `json:"at"`
+}
+
+// ParseStatus validates a status name and returns its canonical form
+func ParseStatus(s string) (Status, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "todo", "to-do":
+		return StatusTodo, nil
+	case "in-progress", "inprogress", "in_progress", "started", "doing":
+		return StatusInProgress, nil
+	case "waiting", "wait":
+		return StatusWaiting, nil
+	case "blocked":
+		return StatusBlocked, nil
+	case "cancelled", "canceled":
+		return StatusCancelled, nil
+	case "done", "completed":
+		return StatusDone, nil
+	default:
+		return "", fmt.Errorf("invalid status %q (use todo, in-progress, waiting, blocked, cancelled or done)", s)
+	}
+}
+
+// State returns the task's status. Tasks saved before statuses existed, and
+// tasks closed some other way, are derived from DoneAt; skipped occurrences
+// count as cancelled.
+func (t *Task) State() Status {
+	switch {
+	case t.Status == StatusCancelled || t.Skipped:
+		return StatusCancelled
+	case t.IsDone():
+		return StatusDone
+	case t.Status == "":
+		return StatusTodo
+	default:
+		return t.Status
+	}
+}
+
+// StatusSince returns when the task entered its current status, if known
+func (t *Task) StatusSince() (time.Time, bool) {
+	if len(t.History) == 0 {
+		return time.Time{}, false
+	}
+	return t.History[len(t.History)-1].At, true
+}
+
+// transition moves the task to a new status and records the change. Closing
+// a task stops its timer.
+func (t *Task) transition(to Status, now time.Time) {
+	from := t.State()
+	if to == StatusDone || to == StatusCancelled {
+		t.stopTimer(now)
+	}
+	t.Status = to
+	t.History = append(t.History, StatusChange{From: from, To: to, At: now})
+}
+
+// SetStatus moves the task with the given ID to a new status. Completing a
+// task goes through MarkDoneAt so recurrence and dependencies are handled;
+// cancelling closes the task without scheduling another occurrence, and a
+// cancelled task can be reopened by moving it to any open status. Starting a
+// task requires its dependencies to be met.
+func SetStatus(tasks []Task, id int, to Status, now time.Time) ([]Task, error) {
+	i := -1
+	for j := range tasks {
+		if tasks[j].ID == id {
+			i = j
+			break
+		}
+	}
+	if i < 0 {
+		return tasks, fmt.Errorf("task %d not found", id)
+	}
+	t := &tasks[i]
+	from := t.State()
+
+	switch {
+	case from == to:
+		return tasks, nil
+	case from == StatusCancelled && to == StatusDone:
+		return tasks, fmt.Errorf("task %d is cancelled", id)
+	case to == StatusDone:
+		return MarkDoneAt(tasks, []Task{*t}, 1, now)
+	case from == StatusDone:
+		return tasks, fmt.Errorf("task already completed")
+	case from == StatusCancelled && t.Skipped:
+		return tasks, fmt.Errorf("task %d was skipped and cannot be reopened", id)
+	case to == StatusInProgress && !AllDependenciesMet(tasks, *t):
+		return tasks, fmt.Errorf("cannot start task: dependencies not met")
+	}
+
+	t.transition(to, now)
+	if to == StatusCancelled {
+		t.DoneAt = &now
+		return completeParents(tasks, i, now), nil
+	}
+	t.DoneAt = nil // reopened
+	return tasks, nil
+}
+
+// FilterByState keeps tasks in any of the given states
+func FilterByState(tasks []Task, states ...Status) []Task {
+	result := make([]Task, 0, len(tasks))
+	for _, t := range tasks {
+		state := t.State()
+		for _, s := range states {
+			if state == s {
+				result = append(result, t)
+				break
+			}
+		}
+	}
+	return result
+}
+
+// statusRank orders statuses for sorting: active work first, closed last
+func statusRank(s Status) int {
+	for i, st := range Statuses {
+		if st == s {
+			return i
+		}
+	}
+	return len(Statuses)
+}
@@ -0,0 +1,125 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetStatus(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	tasks := []Task{{ID: 1, Title: "Spec"}, {ID: 2, Title: "Build", DependsOn: []int{1}}}
+
+	if _, err := SetStatus(tasks, 2, StatusInProgress, now); err == nil {
+		t.Error("Expected an error starting a task with unmet dependencies")
+	}
+
+	tasks, err := SetStatus(tasks, 1, StatusInProgress, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tasks[0].State() != StatusInProgress {
+		t.Errorf("Expected in-progress, got %s", tasks[0].State())
+	}
+	if since, ok := tasks[0].StatusSince(); !ok || !since.Equal(now) {
+		t.Errorf("Expected the transition to be recorded at %s, got %s", now, since)
+	}
+
+	// Cancelling closes the task, which unblocks its dependents
+	later := now.Add(time.Hour)
+	tasks, _ = SetStatus(tasks, 1, StatusCancelled, later)
+	if !tasks[0].IsDone() || tasks[0].State() != StatusCancelled {
+		t.Errorf("Expected #1 to be cancelled, got %s", tasks[0].State())
+	}
+	if !AllDependenciesMet(tasks, tasks[1]) {
+		t.Error("Expected a cancelled dependency to count as met")
+	}
+	if _, err := SetStatus(tasks, 1, StatusDone, later); err == nil {
+		t.Error("Expected an error completing a cancelled task")
+	}
+
+	// Reopening clears the close time; the history keeps every step
+	tasks, _ = SetStatus(tasks, 1, StatusTodo, later)
+	if tasks[0].IsDone() || len(tasks[0].History) != 3 {
+		t.Errorf("Expected #1 reopened with 3 transitions, got %+v", tasks[0].History)
+	}
+
+	tasks, _ = SetStatus(tasks, 1, StatusDone, later)
+	if tasks[0].State() != StatusDone {
+		t.Errorf("Expected done, got %s", tasks[0].State())
+	}
+	if _, err := SetStatus(tasks, 1, StatusWaiting, later); err == nil {
+		t.Error("Expected an error changing a completed task")
+	}
+}
+
+func TestTaskState(t *testing.T) {
+	done := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	cases := []struct {
+		task Task
+		want Status
+	}{
+		{Task{}, StatusTodo},
+		{Task{DoneAt: &done}, StatusDone}, // saved before statuses existed
+		{Task{DoneAt: &done, Skipped: true}, StatusCancelled},
+		{Task{Status: StatusWaiting}, StatusWaiting},
+	}
+	for _, c := range cases {
+		if got := c.task.State(); got != c.want {
+			t.Errorf("State() of %+v = %s, want %s", c.task, got, c.want)
+		}
+	}
+}
+
+func TestFilterAndSortByStatus(t *testing.T) {
+	done := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	tasks := []Task{
+		{ID: 1, DoneAt: &done, Status: StatusCancelled},
+		{ID: 2, DoneAt: &done},
+		{ID: 3, Status: StatusBlocked},
+		{ID: 4, DependsOn: []int{5}},
+		{ID: 5, Status: StatusWaiting},
+		{ID: 6, Status: StatusInProgress},
+	}
+
+	blocked, err := FilterByStatusName(tasks, "blocked")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(blocked) != 2 || blocked[0].ID != 3 || blocked[1].ID != 4 {
+		t.Errorf("Expected #3 and #4 blocked, got %v", taskIDs(blocked))
+	}
+	if got, _ := FilterByStatusName(tasks, "canceled"); len(got) != 1 || got[0].ID != 1 {
+		t.Errorf("Expected only #1 cancelled, got %v", taskIDs(got))
+	}
+	if got, _ := FilterByStatusName(tasks, "done"); len(got) != 1 || got[0].ID != 2 {
+		t.Errorf("Expected only #2 done, got %v", taskIDs(got))
+	}
+	if _, err := FilterByStatusName(tasks, "someday"); err == nil {
+		t.Error("Expected an error for an unknown status")
+	}
+
+	SortTasks(tasks, SortByStatus)
+	want := []int{6, 4, 5, 3, 2, 1}
+	for i, id := range taskIDs(tasks) {
+		if id != want[i] {
+			t.Fatalf("Expected order %v, got %v", want, taskIDs(tasks))
+		}
+	}
+}
+
+func TestStatsExcludeCancelled(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	tasks := []Task{
+		{ID: 1, DoneAt: &now, Status: StatusDone},
+		{ID: 2, DoneAt: &now, Status: StatusCancelled},
+		{ID: 3, Status: StatusInProgress},
+	}
+
+	stats := CalculateStats(tasks, now)
+	if stats.Total != 2 || stats.Cancelled != 1 || stats.InProgress != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.CompletionRate != 50 {
+		t.Errorf("Expected a 50%% completion rate, got %.1f", stats.CompletionRate)
+	}
+}
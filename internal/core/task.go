@@ -2,6 +2,8 @@ package core
 
 import (
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -12,12 +14,29 @@ type Task struct {
 	Title       string     `json:"title"`
 	Description string     `json:"description,omitempty"`
 	Due         *time.Time `json:"due,omitempty"`
-	DoneAt      *time.Time `json:"done_at,omitempty"`
+	Timed       bool       `json:"timed,omitempty"` // Due has a time of day; otherwise it is an all-day date
+	Scheduled   *time.Time `json:"scheduled,omitempty"` // hidden until then; see IsDeferred
+	ScheduledTimed bool    `json:"scheduled_timed,omitempty"` // Scheduled has a time of day
+	DoneAt      *time.Time `json:"done_at,omitempty"` // when the task was closed: completed, skipped or cancelled
+	Status      Status     `json:"status,omitempty"`  // lifecycle state, see State
+	History     []StatusChange `json:"history,omitempty"` // status transitions, oldest first
+	TimeEntries []TimeEntry `json:"time_entries,omitempty"` // time spent, oldest first; see TimeSpent
 	CreatedAt   time.Time  `json:"created_at"`
     Priority    int        `json:"priority"` // 1=low, 2=medium, 3=high
+	Estimate    Estimate   `json:"estimate,omitempty"` // expected effort, see ParseEstimate
 	Tags        []string   `json:"tags,omitempty"`
-    Repeat      string     `json:"repeat,omitempty"` // daily, weekly, monthly
+	Project     string     `json:"project,omitempty"` // project the task belongs to, see NormalizeProject
+    Repeat      string     `json:"repeat,omitempty"` // recurrence rule, see ParseRecurrence
+	RepeatFrom  RepeatAnchor `json:"repeat_from,omitempty"` // what the next occurrence is scheduled from
+	SkipMissed  bool       `json:"skip_missed,omitempty"` // never schedule an occurrence in the past
+	SeriesID    int        `json:"series_id,omitempty"`   // ID of the first occurrence of a recurring task
+	PreviousID  int        `json:"previous_id,omitempty"` // occurrence this one was spawned from
+	Skipped     bool       `json:"skipped,omitempty"`     // closed by skipping rather than completing
+	Pattern     *Task      `json:"pattern,omitempty"`     // series fields before this occurrence was edited on its own
 	DependsOn   []int      `json:"depends_on,omitempty"`
+	ParentID    int        `json:"parent_id,omitempty"`     // task this is a subtask of
+	AutoComplete bool      `json:"auto_complete,omitempty"` // complete this task once all its subtasks are done
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"` // when the task was put in the trash; see IsTrashed
 }
 
 // Domain enums (typed aliases) and normalizers
@@ -40,40 +59,102 @@ const (
     RepeatDaily   RepeatRule = "daily"
     RepeatWeekly  RepeatRule = "weekly"
     RepeatMonthly RepeatRule = "monthly"
+    RepeatYearly  RepeatRule = "yearly"
 )
 
-func NormalizeRepeat(r string) string {
-    lr := strings.ToLower(strings.TrimSpace(r))
-    switch lr {
-    case string(RepeatDaily), string(RepeatWeekly), string(RepeatMonthly):
-        return lr
-    case "", "none":
-        return ""
+// RepeatAnchor selects what the next occurrence of a recurring task is
+// computed from
+type RepeatAnchor string
+
+const (
+    RepeatFromDue        RepeatAnchor = "due"        // the previous due date (default)
+    RepeatFromCompletion RepeatAnchor = "completion" // the day the task was completed
+)
+
+// NormalizeRepeatFrom validates a repeat anchor. The default (due) is
+// stored as the empty string.
+func NormalizeRepeatFrom(a string) (RepeatAnchor, error) {
+    switch strings.ToLower(strings.TrimSpace(a)) {
+    case "", string(RepeatFromDue):
+        return "", nil
+    case "completion", "done", "completed":
+        return RepeatFromCompletion, nil
     default:
-        return lr // keep unknowns as-is to avoid destructive changes
+        return "", fmt.Errorf("invalid repeat anchor %q (use due or completion)", a)
     }
 }
 
+// NormalizeRepeat validates a repeat rule and returns its canonical form.
+// Empty and "none" mean no recurrence.
+func NormalizeRepeat(r string) (string, error) {
+    lr := strings.ToLower(strings.TrimSpace(r))
+    if lr == "" || lr == "none" {
+        return "", nil
+    }
+    rule, err := ParseRecurrence(r)
+    if err != nil {
+        return "", err
+    }
+    return rule.String(), nil
+}
+
 // IsDone returns true if the task is completed
 func (t *Task) IsDone() bool {
 	return t.DoneAt != nil
 }
 
-// IsOverdue returns true if the task has a due date in the past and is not done
+// IsAllDay returns true if the task is due on a date rather than at a time
+func (t *Task) IsAllDay() bool {
+	return t.Due != nil && !t.Timed
+}
+
+// DueIn returns when the task falls due as seen from loc: the stored instant
+// for timed tasks, or the start of the due date in loc for all-day tasks.
+// The task must have a due date.
+func (t *Task) DueIn(loc *time.Location) time.Time {
+	if t.Timed {
+		return t.Due.In(loc)
+	}
+	y, m, d := t.Due.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// Deadline returns the last moment before the task is overdue: the due
+// instant for timed tasks, or the end of the due date in loc for all-day tasks
+func (t *Task) Deadline(loc *time.Location) time.Time {
+	if t.Timed {
+		return t.Due.In(loc)
+	}
+	return t.DueIn(loc).AddDate(0, 0, 1).Add(-time.Nanosecond)
+}
+
+// DueString formats the due date for display in loc
+func (t *Task) DueString(loc *time.Location) string {
+	if t.Due == nil {
+		return ""
+	}
+	if t.Timed {
+		return t.Due.In(loc).Format("2006-01-02 15:04 MST")
+	}
+	return t.Due.Format("2006-01-02")
+}
+
+// IsOverdue returns true if the task's deadline has passed and it is not done.
+// Day boundaries for all-day tasks are taken in now's location.
 func (t *Task) IsOverdue(now time.Time) bool {
 	if t.IsDone() || t.Due == nil {
 		return false
 	}
-	return t.Due.Before(now)
+	return t.Deadline(now.Location()).Before(now)
 }
 
-// IsDueSoon returns true if the task is due within the given duration
+// IsDueSoon returns true if the task is due within the given duration.
+// All-day tasks count as due soon for their whole due date.
 func (t *Task) IsDueSoon(now time.Time, window time.Duration) bool {
-	if t.IsDone() || t.Due == nil {
+	if t.IsDone() || t.Due == nil || t.IsOverdue(now) {
 		return false
 	}
-	deadline := now.Add(window)
-	return t.Due.Before(deadline) && !t.Due.Before(now)
+	return t.DueIn(now.Location()).Before(now.Add(window))
 }
 
 // HasTag returns true if the task has the given tag
@@ -87,6 +168,38 @@ func (t *Task) HasTag(tag string) bool {
 	return false
 }
 
+// Clone returns a deep copy of the task, sharing no pointers or slices with it
+func (t Task) Clone() Task {
+	c := t
+	c.Due = cloneTime(t.Due)
+	c.Scheduled = cloneTime(t.Scheduled)
+	c.DoneAt = cloneTime(t.DoneAt)
+	c.DeletedAt = cloneTime(t.DeletedAt)
+	c.History = slices.Clone(t.History)
+	c.Tags = slices.Clone(t.Tags)
+	c.DependsOn = slices.Clone(t.DependsOn)
+	if t.TimeEntries != nil {
+		c.TimeEntries = make([]TimeEntry, len(t.TimeEntries))
+		for i, e := range t.TimeEntries {
+			e.End = cloneTime(e.End)
+			c.TimeEntries[i] = e
+		}
+	}
+	if t.Pattern != nil {
+		p := t.Pattern.Clone()
+		c.Pattern = &p
+	}
+	return c
+}
+
+func cloneTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	c := *t
+	return &c
+}
+
 // Add creates a new task with the given title and returns the updated task list
 func Add(tasks []Task, title string, due *time.Time) []Task {
     return AddAt(tasks, title, due, time.Now())
@@ -94,15 +207,8 @@ func Add(tasks []Task, title string, due *time.Time) []Task {
 
 // AddAt is like Add but uses the provided timestamp for CreatedAt (for testability)
 func AddAt(tasks []Task, title string, due *time.Time, now time.Time) []Task {
-	nextID := 1
-	for _, t := range tasks {
-		if t.ID >= nextID {
-			nextID = t.ID + 1
-		}
-	}
-
     task := Task{
-		ID:        nextID,
+		ID:        NextID(tasks),
 		Title:     title,
 		Due:       due,
         CreatedAt: now,
@@ -112,6 +218,42 @@ func AddAt(tasks []Task, title string, due *time.Time, now time.Time) []Task {
 	return append(tasks, task)
 }
 
+// NextID returns the ID for a new task: one more than the highest ID in use
+func NextID(tasks []Task) int {
+	next := 1
+	for _, t := range tasks {
+		if t.ID >= next {
+			next = t.ID + 1
+		}
+	}
+	return next
+}
+
+// DedupeIDs gives every task after the first with a given ID a fresh ID.
+// Older versions could assign an occurrence of a recurring task the ID of an
+// existing task; this repairs such files. It reports whether anything changed.
+func DedupeIDs(tasks []Task) bool {
+	seen := make(map[int]bool, len(tasks))
+	changed := false
+	for i := range tasks {
+		if seen[tasks[i].ID] {
+			tasks[i].ID = NextID(tasks)
+			changed = true
+		}
+		seen[tasks[i].ID] = true
+	}
+	return changed
+}
+
+// TaskNotFoundError is returned when no task has the given ID
+type TaskNotFoundError struct {
+	ID int
+}
+
+func (e *TaskNotFoundError) Error() string {
+	return fmt.Sprintf("task %d not found", e.ID)
+}
+
 // GetByID finds a task by its ID
 func GetByID(tasks []Task, id int) (*Task, error) {
 	for i := range tasks {
@@ -119,7 +261,7 @@ func GetByID(tasks []Task, id int) (*Task, error) {
 			return &tasks[i], nil
 		}
 	}
-	return nil, fmt.Errorf("task %d not found", id)
+	return nil, &TaskNotFoundError{ID: id}
 }
 
 // Update replaces a task with the same ID
@@ -182,17 +324,12 @@ func MarkDoneAt(tasks []Task, visible []Task, idx int, now time.Time) ([]Task, e
                 return tasks, fmt.Errorf("cannot complete task: dependencies not met")
             }
 
+            tasks[i].transition(StatusDone, now)
             tasks[i].DoneAt = &now
 
-            // Handle recurring tasks
-            if tasks[i].Repeat != "" {
-                nextTask := createNextRecurrenceAt(tasks[i], now)
-                if nextTask != nil {
-                    tasks = append(tasks, *nextTask)
-                }
-            }
-
-            return tasks, nil
+            // Handle recurring tasks and parents waiting on their subtasks
+            tasks = scheduleNext(tasks, i, now)
+            return completeParents(tasks, i, now), nil
         }
     }
 
@@ -215,51 +352,98 @@ func createNextRecurrence(task Task) *Task {
     return createNextRecurrenceAt(task, time.Now())
 }
 
-// createNextRecurrenceAt is like createNextRecurrence but uses the provided time
+// createNextRecurrenceAt is like createNextRecurrence but uses the provided
+// time as the completion time. The next due date is computed in now's
+// location so that wall-clock times and day boundaries follow the user's
+// time zone.
 func createNextRecurrenceAt(task Task, now time.Time) *Task {
-	if task.Due == nil {
+	loc := now.Location()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+
+	// Pick the anchor the rule is applied to
+	var current time.Time
+	switch {
+	case task.RepeatFrom == RepeatFromCompletion:
+		// The completion day, at the task's usual time of day if it has one
+		current = today
+		if task.Due != nil && task.Timed {
+			due := task.Due.In(loc)
+			current = time.Date(today.Year(), today.Month(), today.Day(), due.Hour(), due.Minute(), due.Second(), 0, loc)
+		}
+	case task.Due != nil:
+		current = task.DueIn(loc)
+	default:
 		return nil
 	}
 
-	nextDue := calculateNextDue(*task.Due, task.Repeat)
+	// With SkipMissed, occurrences that are already overdue are skipped
+	var notBefore time.Time
+	if task.SkipMissed {
+		notBefore = today
+		if task.Timed {
+			notBefore = now
+		}
+	}
+
+	nextDue, repeat := calculateNextDue(current, task.Repeat, notBefore)
 	if nextDue == nil {
 		return nil
 	}
 
-	// Find the highest ID
-	nextID := task.ID + 1
-
+    // The caller assigns the ID and series links
     nextTask := Task{
-		ID:          nextID,
 		Title:       task.Title,
 		Description: task.Description,
 		Due:         nextDue,
+		Timed:       task.Timed && task.Due != nil,
+		Scheduled:   nextScheduled(task, *nextDue),
+		ScheduledTimed: task.ScheduledTimed,
         CreatedAt:   now,
 		Priority:    task.Priority,
+		Estimate:    task.Estimate,
 		Tags:        append([]string{}, task.Tags...),
-		Repeat:      task.Repeat,
+		Project:     task.Project,
+		Repeat:      repeat,
+		RepeatFrom:  task.RepeatFrom,
+		SkipMissed:  task.SkipMissed,
 		DependsOn:   append([]int{}, task.DependsOn...),
+		ParentID:    task.ParentID,
+		AutoComplete: task.AutoComplete,
 	}
 
 	return &nextTask
 }
 
-// calculateNextDue calculates the next due date based on repeat rule
-func calculateNextDue(current time.Time, repeat string) *time.Time {
-	var next time.Time
+// calculateNextDue calculates the next due date based on repeat rule and
+// returns the rule to carry forward (with its remaining COUNT reduced).
+// Occurrences before notBefore are skipped, each using up one COUNT; pass the
+// zero time to keep them. It returns nil when the rule is invalid or has run out.
+func calculateNextDue(current time.Time, repeat string, notBefore time.Time) (*time.Time, string) {
+	rule, err := ParseRecurrence(repeat)
+	if err != nil {
+		return nil, ""
+	}
 
-	switch strings.ToLower(repeat) {
-	case "daily":
-		next = current.AddDate(0, 0, 1)
-	case "weekly":
-		next = current.AddDate(0, 0, 7)
-	case "monthly":
-		next = current.AddDate(0, 1, 0)
-	default:
-		return nil
+	for i := 0; i < maxRecurrenceSteps; i++ {
+		nextRule, more := rule.Advance()
+		if !more {
+			return nil, ""
+		}
+
+		next, ok := rule.Next(current)
+		if !ok {
+			return nil, ""
+		}
+		nextRule = nextRule.keepDay(current, next)
+
+		if next.Before(notBefore) {
+			rule, current = nextRule, next
+			continue
+		}
+		return &next, nextRule.String()
 	}
 
-	return &next
+	return nil, ""
 }
 
 // ParseTags parses a comma-separated string into a slice of tags
@@ -305,6 +489,57 @@ func ParseIDs(idStr string) []int {
 	return ids
 }
 
+// maxIDRange is the most IDs a range such as "3-9" may cover, so that a
+// typo like "1-1000000000" fails instead of allocating the whole range
+const maxIDRange = 10000
+
+// ParseIDList parses a task ID selection such as "3", "3,7" or "3,7-9".
+// Unlike ParseIDs it rejects malformed entries instead of skipping them,
+// since the result is used to pick which tasks a command acts on.
+func ParseIDList(idStr string) ([]int, error) {
+	ids := make([]int, 0)
+	seen := make(map[int]bool)
+
+	for _, p := range strings.Split(idStr, ",") {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+
+		lo, hi := p, p
+		if i := strings.Index(p, "-"); i > 0 {
+			lo, hi = strings.TrimSpace(p[:i]), strings.TrimSpace(p[i+1:])
+		}
+
+		start, err := strconv.Atoi(lo)
+		if err != nil || start < 1 {
+			return nil, fmt.Errorf("invalid task ID %q", p)
+		}
+		end, err := strconv.Atoi(hi)
+		if err != nil || end < 1 {
+			return nil, fmt.Errorf("invalid task ID %q", p)
+		}
+		if end < start {
+			return nil, fmt.Errorf("invalid ID range %q", p)
+		}
+		if end-start >= maxIDRange {
+			return nil, fmt.Errorf("ID range %q covers more than %d IDs", p, maxIDRange)
+		}
+
+		for id := start; id <= end; id++ {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("no task IDs given")
+	}
+	return ids, nil
+}
+
 // Atoi1 converts a 1-indexed string to 0-indexed int, but returns the 1-indexed value
 func Atoi1(s string) (int, error) {
 	var idx int
@@ -172,6 +172,47 @@ func TestParseIDs(t *testing.T) {
 	}
 }
 
+func TestParseIDList(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []int
+		wantErr  bool
+	}{
+		{"3", []int{3}, false},
+		{"3,7", []int{3, 7}, false},
+		{"3,7-9", []int{3, 7, 8, 9}, false},
+		{" 2 - 4 , 3 ", []int{2, 3, 4}, false},
+		{"", nil, true},
+		{"0", nil, true},
+		{"9-7", nil, true},
+		{"1,abc", nil, true},
+		{"1-1000000000", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := ParseIDList(tt.input)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Expected error for %q, got %v", tt.input, result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(result) != len(tt.expected) {
+				t.Fatalf("Expected %v, got %v", tt.expected, result)
+			}
+			for i, id := range result {
+				if id != tt.expected[i] {
+					t.Errorf("Expected ID %d, got %d", tt.expected[i], id)
+				}
+			}
+		})
+	}
+}
+
 func TestMarkDone(t *testing.T) {
 	tasks := []Task{
 		{ID: 1, Title: "Task 1"},
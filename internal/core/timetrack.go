@@ -0,0 +1,146 @@
+package core
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeEntry is a span of time spent on a task, either recorded by a timer or
+// logged by hand
+type TimeEntry struct {
+	Start time.Time  `json:"start"`
+	End   *time.Time `json:"end,omitempty"` // nil while the timer is running
+	Note  string     `json:"note,omitempty"`
+}
+
+// Duration returns the length of the entry, counting a running timer up to
+// now
+func (e TimeEntry) Duration(now time.Time) time.Duration {
+	if e.End == nil {
+		return now.Sub(e.Start)
+	}
+	return e.End.Sub(e.Start)
+}
+
+// TimeSummary reports the time spent on a task
+type TimeSummary struct {
+	TaskID  int         `json:"task_id"`
+	TotalMS int64       `json:"total_ms"`
+	Running bool        `json:"running"`
+	Entries []TimeEntry `json:"entries"`
+}
+
+// TimeSpent returns the total time logged on the task, including a running
+// timer
+func (t *Task) TimeSpent(now time.Time) time.Duration {
+	var total time.Duration
+	for _, e := range t.TimeEntries {
+		total += e.Duration(now)
+	}
+	return total
+}
+
+// TimerRunning reports whether a timer is running on the task
+func (t *Task) TimerRunning() bool {
+	n := len(t.TimeEntries)
+	return n > 0 && t.TimeEntries[n-1].End == nil
+}
+
+// stopTimer ends the task's running timer, if any
+func (t *Task) stopTimer(now time.Time) {
+	if t.TimerRunning() {
+		t.TimeEntries[len(t.TimeEntries)-1].End = &now
+	}
+}
+
+// TimeSummary returns the task's time entries and total
+func (t *Task) TimeSummary(now time.Time) TimeSummary {
+	entries := t.TimeEntries
+	if entries == nil {
+		entries = []TimeEntry{}
+	}
+	return TimeSummary{
+		TaskID:  t.ID,
+		TotalMS: t.TimeSpent(now).Milliseconds(),
+		Running: t.TimerRunning(),
+		Entries: entries,
+	}
+}
+
+// RunningTimer returns the task whose timer is running, if any. At most one
+// timer runs at a time.
+func RunningTimer(tasks []Task) (*Task, bool) {
+	for i := range tasks {
+		if tasks[i].TimerRunning() {
+			return &tasks[i], true
+		}
+	}
+	return nil, false
+}
+
+// StartTimer starts a timer on an open task. Only one timer may run at a
+// time, across all tasks.
+func StartTimer(tasks []Task, id int, now time.Time) error {
+	task, err := GetByID(tasks, id)
+	if err != nil {
+		return err
+	}
+	if task.IsDone() {
+		return fmt.Errorf("task %d is closed", id)
+	}
+	if running, ok := RunningTimer(tasks); ok {
+		return fmt.Errorf("timer already running on task %d", running.ID)
+	}
+	task.TimeEntries = append(task.TimeEntries, TimeEntry{Start: now})
+	return nil
+}
+
+// StopTimer stops the running timer and returns the task it ran on
+func StopTimer(tasks []Task, now time.Time) (*Task, error) {
+	task, ok := RunningTimer(tasks)
+	if !ok {
+		return nil, fmt.Errorf("no timer running")
+	}
+	task.stopTimer(now)
+	return task, nil
+}
+
+// LogTime records d spent on a task, ending now
+func LogTime(tasks []Task, id int, d time.Duration, note string, now time.Time) error {
+	task, err := GetByID(tasks, id)
+	if err != nil {
+		return err
+	}
+	if d <= 0 {
+		return fmt.Errorf("duration must be positive")
+	}
+	entry := TimeEntry{Start: now.Add(-d), End: &now, Note: note}
+	if task.TimerRunning() {
+		// Keep the running entry last
+		n := len(task.TimeEntries)
+		running := task.TimeEntries[n-1]
+		task.TimeEntries = append(task.TimeEntries[:n-1], entry, running)
+		return nil
+	}
+	task.TimeEntries = append(task.TimeEntries, entry)
+	return nil
+}
+
+// ParseTimeSpent parses a logged duration such as "45m", "1h30m" or "1.5h"
+func ParseTimeSpent(s string) (time.Duration, error) {
+	d, err := time.ParseDuration(strings.ReplaceAll(strings.TrimSpace(s), " ", ""))
+	if err != nil || d <= 0 {
+		return 0, fmt.Errorf("invalid duration %q (e.g. 45m, 1h30m)", s)
+	}
+	return d, nil
+}
+
+// FormatTimeSpent formats a duration to the minute, e.g. "1h 05m" or "45m"
+func FormatTimeSpent(d time.Duration) string {
+	m := int(d.Round(time.Minute) / time.Minute)
+	if m < 60 {
+		return fmt.Sprintf("%dm", m)
+	}
+	return fmt.Sprintf("%dh %02dm", m/60, m%60)
+}
@@ -0,0 +1,92 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTimer(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	tasks := []Task{{ID: 1, Title: "Report"}, {ID: 2, Title: "Invoice"}}
+
+	if err := StartTimer(tasks, 1, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := StartTimer(tasks, 2, now); err == nil {
+		t.Error("Expected an error starting a second timer")
+	}
+	if running, ok := RunningTimer(tasks); !ok || running.ID != 1 {
+		t.Fatal("Expected the timer to run on #1")
+	}
+	if got := tasks[0].TimeSpent(now.Add(10 * time.Minute)); got != 10*time.Minute {
+		t.Errorf("Expected a running timer to count, got %s", got)
+	}
+
+	// Logged time goes before the running entry
+	if err := LogTime(tasks, 1, 45*time.Minute, "call", now.Add(30*time.Minute)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !tasks[0].TimerRunning() {
+		t.Error("Expected the timer to keep running after logging time")
+	}
+
+	task, err := StopTimer(tasks, now.Add(time.Hour))
+	if err != nil || task.ID != 1 {
+		t.Fatalf("Expected to stop the timer on #1, got %v", err)
+	}
+	if got := tasks[0].TimeSpent(now.Add(2 * time.Hour)); got != 105*time.Minute {
+		t.Errorf("Expected 1h45m in total, got %s", got)
+	}
+	if _, err := StopTimer(tasks, now); err == nil {
+		t.Error("Expected an error with no timer running")
+	}
+}
+
+func TestClosingStopsTimer(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	later := now.Add(20 * time.Minute)
+	tasks := []Task{{ID: 1, Title: "Report"}}
+
+	_ = StartTimer(tasks, 1, now)
+	tasks, err := SetStatus(tasks, 1, StatusDone, later)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tasks[0].TimerRunning() || tasks[0].TimeSpent(later.Add(time.Hour)) != 20*time.Minute {
+		t.Errorf("Expected the timer stopped at completion, got %+v", tasks[0].TimeEntries)
+	}
+	if err := StartTimer(tasks, 1, later); err == nil {
+		t.Error("Expected an error timing a closed task")
+	}
+
+	stats := CalculateStats(tasks, later)
+	if stats.TimeTrackedMS != (20 * time.Minute).Milliseconds() {
+		t.Errorf("Expected 20m tracked, got %dms", stats.TimeTrackedMS)
+	}
+}
+
+func TestParseAndFormatTimeSpent(t *testing.T) {
+	cases := map[string]time.Duration{
+		"45m":    45 * time.Minute,
+		"1h30m":  90 * time.Minute,
+		"1.5h":   90 * time.Minute,
+		"2h 15m": 135 * time.Minute,
+	}
+	for in, want := range cases {
+		if got, err := ParseTimeSpent(in); err != nil || got != want {
+			t.Errorf("ParseTimeSpent(%q) = %s, %v; want %s", in, got, err, want)
+		}
+	}
+	for _, bad := range []string{"", "soon", "-5m", "0"} {
+		if _, err := ParseTimeSpent(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+
+	if got := FormatTimeSpent(65 * time.Minute); got != "1h 05m" {
+		t.Errorf("Expected 1h 05m, got %q", got)
+	}
+	if got := FormatTimeSpent(45 * time.Minute); got != "45m" {
+		t.Errorf("Expected 45m, got %q", got)
+	}
+}
@@ -0,0 +1,160 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// DefaultTrashRetention is how long tasks stay in the trash before they are
+// purged for good
+const DefaultTrashRetention = 30 * 24 * time.Hour
+
+// TrashedError is returned when a task is looked up that is in the trash
+type TrashedError struct {
+	ID int
+}
+
+func (e *TrashedError) Error() string {
+	return fmt.Sprintf("task %d is in the trash", e.ID)
+}
+
+// NotInTrashError is returned when a task to restore is not in the trash
+type NotInTrashError struct {
+	ID int
+}
+
+func (e *NotInTrashError) Error() string {
+	return fmt.Sprintf("task %d is not in the trash", e.ID)
+}
+
+// IsTrashed reports whether the task has been deleted to the trash
+func (t Task) IsTrashed() bool {
+	return t.DeletedAt != nil
+}
+
+// LiveTasks returns the tasks that are not in the trash
+func LiveTasks(tasks []Task) []Task {
+	live := make([]Task, 0, len(tasks))
+	for _, t := range tasks {
+		if !t.IsTrashed() {
+			live = append(live, t)
+		}
+	}
+	return live
+}
+
+// TrashedTasks returns the tasks in the trash, most recently deleted first
+func TrashedTasks(tasks []Task) []Task {
+	var trashed []Task
+	for _, t := range tasks {
+		if t.IsTrashed() {
+			trashed = append(trashed, t)
+		}
+	}
+	sort.SliceStable(trashed, func(i, j int) bool { return trashed[i].DeletedAt.After(*trashed[j].DeletedAt) })
+	return trashed
+}
+
+// GetLiveByID is GetByID for tasks that are not in the trash; a trashed task
+// gives a TrashedError
+func GetLiveByID(tasks []Task, id int) (*Task, error) {
+	t, err := GetByID(tasks, id)
+	if err != nil {
+		return nil, err
+	}
+	if t.IsTrashed() {
+		return nil, &TrashedError{ID: id}
+	}
+	return t, nil
+}
+
+// TrashWithPolicy moves the task with the given ID to the trash, handling
+// the tasks that depend on it according to policy. Under RemoveKeep they go
+// on depending on it, and stay blocked until it is restored; their IDs are
+// returned so they can be warned about. It returns the trashed tasks, the
+// requested one first. A running timer on a trashed task is stopped.
+func TrashWithPolicy(tasks []Task, id int, policy RemovePolicy, now time.Time) ([]Task, []int, error) {
+	if _, err := GetLiveByID(tasks, id); err != nil {
+		return nil, nil, err
+	}
+
+	var kept []int
+	if policy == RemoveKeep {
+		kept = Dependents(tasks, id)
+	}
+	order, err := removalOrder(tasks, id, policy)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	trashed := make([]Task, 0, len(order))
+	for _, tid := range order {
+		t, _ := GetByID(tasks, tid)
+		t.stopTimer(now)
+		t.DeletedAt = &now
+		trashed = append(trashed, *t)
+	}
+	return trashed, kept, nil
+}
+
+// Restore takes the task with the given ID out of the trash
+func Restore(tasks []Task, id int) (*Task, error) {
+	t, err := GetByID(tasks, id)
+	if err != nil {
+		return nil, err
+	}
+	if !t.IsTrashed() {
+		return nil, &NotInTrashError{ID: id}
+	}
+	t.DeletedAt = nil
+	return t, nil
+}
+
+// PurgeTrash removes for good the tasks that were put in the trash at or
+// before cutoff. References to them are dropped from the remaining tasks,
+// and their subtasks move up to the nearest ancestor that is kept. It
+// returns the remaining tasks and the purged ones.
+func PurgeTrash(tasks []Task, cutoff time.Time) ([]Task, []Task) {
+	var purged []Task
+	gone := make(map[int]bool)
+	for _, t := range tasks {
+		if t.IsTrashed() && !t.DeletedAt.After(cutoff) {
+			purged = append(purged, t)
+			gone[t.ID] = true
+		}
+	}
+	if len(purged) == 0 {
+		return tasks, nil
+	}
+
+	result := make([]Task, 0, len(tasks)-len(purged))
+	for _, t := range tasks {
+		if !gone[t.ID] {
+			result = append(result, t)
+		}
+	}
+	dropReferences(result, gone)
+	LiftSubtasks(result, purged)
+	return result, purged
+}
+
+// LiftSubtasks moves the subtasks of removed tasks up to the nearest ancestor
+// that is not removed
+func LiftSubtasks(tasks []Task, removed []Task) {
+	parentOf := make(map[int]int, len(removed))
+	for _, r := range removed {
+		parentOf[r.ID] = r.ParentID
+	}
+	for i := range tasks {
+		p := tasks[i].ParentID
+		for n := 0; n < len(removed); n++ {
+			up, ok := parentOf[p]
+			if !ok {
+				break
+			}
+			p = up
+		}
+		tasks[i].ParentID = p
+	}
+}
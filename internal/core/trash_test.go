@@ -0,0 +1,77 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTrashAndRestore(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	tasks := []Task{
+		{ID: 1, Title: "Design"},
+		{ID: 2, Title: "Build", DependsOn: []int{1}},
+		{ID: 3, Title: "Ship", DependsOn: []int{2}},
+		{ID: 4, Title: "Sketch", ParentID: 1},
+	}
+
+	// Dependents keep waiting on a trashed task, and are reported
+	trashed, kept, err := TrashWithPolicy(tasks, 1, RemoveKeep, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trashed) != 1 || !reflect.DeepEqual(kept, []int{2}) {
+		t.Errorf("Expected #1 trashed and #2 reported, got %v and %v", taskIDs(trashed), kept)
+	}
+	if got := taskIDs(LiveTasks(tasks)); !reflect.DeepEqual(got, []int{2, 3, 4}) {
+		t.Errorf("Expected #1 out of the live tasks, got %v", got)
+	}
+	if AllDependenciesMet(tasks, tasks[1]) {
+		t.Error("Expected #2 to stay blocked while #1 is in the trash")
+	}
+	var inTrash *TrashedError
+	if _, err := GetLiveByID(tasks, 1); !errors.As(err, &inTrash) {
+		t.Errorf("Expected a TrashedError, got %v", err)
+	}
+	if NextID(tasks) != 5 {
+		t.Errorf("Expected trashed IDs to stay taken, got next ID %d", NextID(tasks))
+	}
+
+	// Cascading trashes open dependents too; trashed tasks no longer count
+	if trashed, _, err = TrashWithPolicy(tasks, 2, RemoveCascade, now.Add(time.Hour)); err != nil || len(trashed) != 2 {
+		t.Fatalf("Expected #2 and #3 trashed, got %v, %v", taskIDs(trashed), err)
+	}
+	if got := taskIDs(TrashedTasks(tasks)); !reflect.DeepEqual(got, []int{2, 3, 1}) {
+		t.Errorf("Expected the most recently trashed first, got %v", got)
+	}
+
+	if _, err := Restore(tasks, 2); err != nil || tasks[1].IsTrashed() {
+		t.Errorf("Expected #2 restored, got %v", err)
+	}
+	if _, err := Restore(tasks, 2); err == nil {
+		t.Error("Expected an error restoring a task that is not in the trash")
+	}
+}
+
+func TestPurgeTrash(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	old, recent := now.Add(-40*24*time.Hour), now.Add(-time.Hour)
+	tasks := []Task{
+		{ID: 1, Title: "Design", DeletedAt: &old},
+		{ID: 2, Title: "Build", DependsOn: []int{1, 3}},
+		{ID: 3, Title: "Review", DeletedAt: &recent},
+		{ID: 4, Title: "Sketch", ParentID: 1},
+	}
+
+	kept, purged := PurgeTrash(tasks, now.Add(-DefaultTrashRetention))
+	if got := taskIDs(purged); !reflect.DeepEqual(got, []int{1}) {
+		t.Fatalf("Expected only #1 purged, got %v", got)
+	}
+	if got := taskIDs(kept); !reflect.DeepEqual(got, []int{2, 3, 4}) {
+		t.Errorf("Expected #2-#4 kept, got %v", got)
+	}
+	if !reflect.DeepEqual(kept[0].DependsOn, []int{3}) || kept[2].ParentID != 0 {
+		t.Errorf("Expected references to #1 dropped, got %+v", kept)
+	}
+}
@@ -0,0 +1,132 @@
+package core
+
+import (
+	"fmt"
+	"time"
+)
+
+// TreeNode is a task together with its subtasks
+type TreeNode struct {
+	Task     Task
+	Children []*TreeNode
+}
+
+// ChildrenOf returns the direct subtasks of the task with the given ID
+func ChildrenOf(tasks []Task, id int) []Task {
+	var children []Task
+	for _, t := range tasks {
+		if t.ParentID == id {
+			children = append(children, t)
+		}
+	}
+	return children
+}
+
+// Progress returns how many of the task's direct subtasks are done.
+// Cancelled subtasks are not counted; a task without subtasks has a total
+// of zero.
+func Progress(tasks []Task, id int) (done, total int) {
+	for _, t := range ChildrenOf(tasks, id) {
+		if t.State() == StatusCancelled {
+			continue
+		}
+		total++
+		if t.IsDone() {
+			done++
+		}
+	}
+	return done, total
+}
+
+// IsDescendant reports whether the task with the given ID is below ancestor
+// in the task tree
+func IsDescendant(tasks []Task, id, ancestor int) bool {
+	seen := make(map[int]bool)
+	for id != 0 && !seen[id] {
+		seen[id] = true
+		task, err := GetByID(tasks, id)
+		if err != nil {
+			return false
+		}
+		if task.ParentID == ancestor {
+			return true
+		}
+		id = task.ParentID
+	}
+	return false
+}
+
+// SetParent makes the task with the given ID a subtask of parentID, or a
+// top-level task when parentID is 0
+func SetParent(tasks []Task, id, parentID int) error {
+	task, err := GetByID(tasks, id)
+	if err != nil {
+		return err
+	}
+
+	if parentID != 0 {
+		if parentID == id {
+			return fmt.Errorf("task %d cannot be its own parent", id)
+		}
+		if _, err := GetByID(tasks, parentID); err != nil {
+			return fmt.Errorf("parent %w", err)
+		}
+		if IsDescendant(tasks, parentID, id) {
+			return fmt.Errorf("cannot move task %d under its own subtask %d", id, parentID)
+		}
+	}
+
+	task.ParentID = parentID
+	return nil
+}
+
+// BuildTree arranges tasks into trees, keeping their order among siblings.
+// Tasks whose parent is not in the list are roots.
+func BuildTree(tasks []Task) []*TreeNode {
+	nodes := make(map[int]*TreeNode, len(tasks))
+	for _, t := range tasks {
+		nodes[t.ID] = &TreeNode{Task: t}
+	}
+
+	var roots []*TreeNode
+	for _, t := range tasks {
+		node := nodes[t.ID]
+		parent, ok := nodes[t.ParentID]
+		if !ok || t.ParentID == t.ID || IsDescendant(tasks, t.ParentID, t.ID) {
+			roots = append(roots, node)
+			continue
+		}
+		parent.Children = append(parent.Children, node)
+	}
+
+	return roots
+}
+
+// completeParents marks the ancestors of the task at index i done once all
+// their subtasks are, for parents that opted in with AutoComplete
+func completeParents(tasks []Task, i int, now time.Time) []Task {
+	seen := make(map[int]bool)
+	for parentID := tasks[i].ParentID; parentID != 0 && !seen[parentID]; {
+		seen[parentID] = true
+
+		p := -1
+		for j := range tasks {
+			if tasks[j].ID == parentID {
+				p = j
+				break
+			}
+		}
+		if p < 0 || !tasks[p].AutoComplete || tasks[p].IsDone() || tasks[p].IsTrashed() || !AllDependenciesMet(tasks, tasks[p]) {
+			return tasks
+		}
+		if done, total := Progress(LiveTasks(tasks), parentID); done < total {
+			return tasks
+		}
+
+		tasks[p].transition(StatusDone, now)
+		tasks[p].DoneAt = &now
+		parentID = tasks[p].ParentID
+		tasks = scheduleNext(tasks, p, now)
+	}
+	return tasks
+}
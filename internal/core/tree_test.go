@@ -0,0 +1,82 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetParent(t *testing.T) {
+	tasks := []Task{{ID: 1}, {ID: 2, ParentID: 1}, {ID: 3, ParentID: 2}, {ID: 4}}
+
+	if err := SetParent(tasks, 4, 3); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !IsDescendant(tasks, 4, 1) {
+		t.Error("Expected task 4 to be below task 1")
+	}
+
+	for _, tt := range []struct{ id, parent int }{{1, 1}, {1, 3}, {1, 4}, {2, 99}} {
+		if err := SetParent(tasks, tt.id, tt.parent); err == nil {
+			t.Errorf("Expected error moving %d under %d", tt.id, tt.parent)
+		}
+	}
+
+	if err := SetParent(tasks, 3, 0); err != nil || tasks[2].ParentID != 0 {
+		t.Errorf("Expected task 3 to become top-level, got %+v, %v", tasks[2], err)
+	}
+}
+
+func TestBuildTree(t *testing.T) {
+	tasks := []Task{
+		{ID: 1, Title: "Launch"},
+		{ID: 2, Title: "Design", ParentID: 1},
+		{ID: 3, Title: "Build", ParentID: 1},
+		{ID: 4, Title: "Tests", ParentID: 3},
+		{ID: 5, Title: "Orphan", ParentID: 42},
+	}
+
+	roots := BuildTree(tasks)
+	if len(roots) != 2 || roots[0].Task.ID != 1 || roots[1].Task.ID != 5 {
+		t.Fatalf("Expected roots #1 and #5, got %d roots", len(roots))
+	}
+	children := roots[0].Children
+	if len(children) != 2 || children[0].Task.ID != 2 || children[1].Task.ID != 3 {
+		t.Fatalf("Expected #2 and #3 under #1 in order")
+	}
+	if len(children[1].Children) != 1 || children[1].Children[0].Task.ID != 4 {
+		t.Error("Expected #4 under #3")
+	}
+}
+
+func TestAutoCompleteParent(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	tasks := []Task{
+		{ID: 1, Title: "Project", AutoComplete: true},
+		{ID: 2, Title: "Phase", ParentID: 1, AutoComplete: true},
+		{ID: 3, Title: "Step A", ParentID: 2},
+		{ID: 4, Title: "Step B", ParentID: 2},
+		{ID: 5, Title: "Manual", ParentID: 4},
+	}
+
+	tasks, _ = MarkDoneAt(tasks, tasks, 3, now)
+	if done, total := Progress(tasks, 2); done != 1 || total != 2 {
+		t.Errorf("Expected progress 1/2, got %d/%d", done, total)
+	}
+	if tasks[1].IsDone() {
+		t.Fatal("Parent completed before all subtasks were done")
+	}
+
+	// Completing the last subtask completes the whole chain of opted-in parents
+	tasks, _ = MarkDoneAt(tasks, tasks, 4, now)
+	if !tasks[1].IsDone() || !tasks[0].IsDone() {
+		t.Errorf("Expected #2 and #1 to be completed automatically")
+	}
+
+	// Parents without AutoComplete stay open
+	tasks[3].AutoComplete = false
+	tasks[3].DoneAt = nil
+	tasks, _ = MarkDoneAt(tasks, tasks, 5, now)
+	if tasks[3].IsDone() {
+		t.Error("Expected #4 to stay open without AutoComplete")
+	}
+}
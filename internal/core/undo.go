@@ -0,0 +1,299 @@
+package core
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// UndoLimit is how many operations the undo history keeps
+const UndoLimit = 100
+
+// ErrNothingToUndo and ErrNothingToRedo are returned when the history has
+// no operation to undo or redo
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// TaskChange is the state of one task before and after an operation.
+// Before is nil for a task the operation created, After for one it removed.
+type TaskChange struct {
+	ID     int   `json:"id"`
+	Index  int   `json:"index"` // position in the task list: after the operation for created tasks, before it otherwise
+	Before *Task `json:"before,omitempty"`
+	After  *Task `json:"after,omitempty"`
+}
+
+// Operation is a recorded change to the task list that can be undone and
+// redone
+type Operation struct {
+	Seq     int          `json:"seq"`
+	Action  string       `json:"action"` // what was done, e.g. "add" or "done"
+	At      time.Time    `json:"at"`
+	Changes []TaskChange `json:"changes"`
+	Undone  bool         `json:"undone,omitempty"`
+}
+
+// UndoConflictError is returned when a task an operation changed has been
+// changed again since, so undoing or redoing it would lose that change
+type UndoConflictError struct {
+	Op     Operation
+	TaskID int
+	Redo   bool
+}
+
+func (e *UndoConflictError) Error() string {
+	verb := "undo"
+	if e.Redo {
+		verb = "redo"
+	}
+	return fmt.Sprintf("cannot %s %s: task %d has changed since", verb, e.Op.Summary(), e.TaskID)
+}
+
+// Summary describes the operation in a few words, e.g. `done #3 "Water plants" (+1 task)`
+func (op Operation) Summary() string {
+	if len(op.Changes) == 0 {
+		return op.Action
+	}
+	c := op.Changes[0]
+	t := c.After
+	if t == nil {
+		t = c.Before
+	}
+	s := fmt.Sprintf("%s #%d %q", op.Action, c.ID, t.Title)
+	switch n := len(op.Changes) - 1; n {
+	case 0:
+	case 1:
+		s += " (+1 task)"
+	default:
+		s += fmt.Sprintf(" (+%d tasks)", n)
+	}
+	return s
+}
+
+// Describe lists what the operation did to each task, one line per task
+func (op Operation) Describe() []string {
+	lines := make([]string, 0, len(op.Changes))
+	for _, c := range op.Changes {
+		switch {
+		case c.Before == nil:
+			lines = append(lines, fmt.Sprintf("added #%d %q", c.ID, c.After.Title))
+		case c.After == nil:
+			lines = append(lines, fmt.Sprintf("removed #%d %q", c.ID, c.Before.Title))
+		default:
+			lines = append(lines, fmt.Sprintf("changed #%d %q: %s", c.ID, c.After.Title, strings.Join(ChangedFields(*c.Before, *c.After), ", ")))
+		}
+	}
+	return lines
+}
+
+// Inverse returns the operation that undoes op, to describe it; its
+// changes' Index fields are not meaningful
+func (op Operation) Inverse() Operation {
+	inv := op
+	inv.Changes = make([]TaskChange, len(op.Changes))
+	for i, c := range op.Changes {
+		c.Before, c.After = c.After, c.Before
+		inv.Changes[i] = c
+	}
+	return inv
+}
+
+// ChangedFields returns the JSON names of the fields that differ between two
+// versions of a task
+func ChangedFields(before, after Task) []string {
+	var fields []string
+	bv, av := reflect.ValueOf(before), reflect.ValueOf(after)
+	for i := 0; i < bv.NumField(); i++ {
+		if !reflect.DeepEqual(bv.Field(i).Interface(), av.Field(i).Interface()) {
+			name, _, _ := strings.Cut(bv.Type().Field(i).Tag.Get("json"), ",")
+			fields = append(fields, name)
+		}
+	}
+	return fields
+}
+
+// DiffTasks returns the changes that turn before into after, matching tasks
+// by ID
+func DiffTasks(before, after []Task) []TaskChange {
+	afterIdx := make(map[int]int, len(after))
+	for i, t := range after {
+		afterIdx[t.ID] = i
+	}
+
+	var changes []TaskChange
+	seen := make(map[int]bool, len(before))
+	for i := range before {
+		b := &before[i]
+		seen[b.ID] = true
+		j, ok := afterIdx[b.ID]
+		if !ok {
+			bc := b.Clone()
+			changes = append(changes, TaskChange{ID: b.ID, Index: i, Before: &bc})
+			continue
+		}
+		if !reflect.DeepEqual(*b, after[j]) {
+			bc, ac := b.Clone(), after[j].Clone()
+			changes = append(changes, TaskChange{ID: b.ID, Index: i, Before: &bc, After: &ac})
+		}
+	}
+	for j := range after {
+		if !seen[after[j].ID] {
+			ac := after[j].Clone()
+			changes = append(changes, TaskChange{ID: ac.ID, Index: j, After: &ac})
+		}
+	}
+	return changes
+}
+
+// RecordOperation adds op to the history, giving it the next sequence
+// number. Operations that were undone can no longer be redone afterwards,
+// and only the last limit operations are kept.
+func RecordOperation(history []Operation, op Operation, limit int) []Operation {
+	kept := make([]Operation, 0, len(history)+1)
+	for _, h := range history {
+		if !h.Undone {
+			kept = append(kept, h)
+		}
+	}
+	op.Seq = 1
+	if len(history) > 0 {
+		op.Seq = history[len(history)-1].Seq + 1
+	}
+	op.Undone = false
+	kept = append(kept, op)
+	if limit > 0 && len(kept) > limit {
+		kept = kept[len(kept)-limit:]
+	}
+	return kept
+}
+
+// Undo reverts the most recent operation in history that has not been undone
+// and returns the tasks, the updated history and the operation. It fails with
+// an UndoConflictError if a task the operation changed has been changed
+// since. Operations whose changes never reached the tasks, as when saving
+// them failed, are dropped from the history, so that Redo cannot apply them.
+func Undo(tasks []Task, history []Operation) ([]Task, []Operation, Operation, error) {
+	history = slices.Clone(history)
+	for i := len(history) - 1; i >= 0; i-- {
+		op := &history[i]
+		if op.Undone {
+			continue
+		}
+		if inState(tasks, op.Changes, true) {
+			history = slices.Delete(history, i, i+1)
+			continue
+		}
+		result, err := applyChanges(tasks, *op, true)
+		if err != nil {
+			return tasks, history, *op, err
+		}
+		op.Undone = true
+		return result, history, *op, nil
+	}
+	return tasks, history, Operation{}, ErrNothingToUndo
+}
+
+// Redo applies again the operation undone last
+func Redo(tasks []Task, history []Operation) ([]Task, []Operation, Operation, error) {
+	history = slices.Clone(history)
+	for i := range history {
+		op := &history[i]
+		if !op.Undone {
+			continue
+		}
+		op.Undone = false
+		if inState(tasks, op.Changes, false) {
+			return tasks, history, *op, nil
+		}
+		result, err := applyChanges(tasks, *op, false)
+		if err != nil {
+			return tasks, history, *op, err
+		}
+		return result, history, *op, nil
+	}
+	return tasks, history, Operation{}, ErrNothingToRedo
+}
+
+// inState reports whether every task changed by changes is already in its
+// state before them (before is true) or after them
+func inState(tasks []Task, changes []TaskChange, before bool) bool {
+	for _, c := range changes {
+		want := c.After
+		if before {
+			want = c.Before
+		}
+		current, _ := GetByID(tasks, c.ID)
+		if !sameTask(current, want) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameTask compares tasks by their JSON form, which is what survives saving
+// them
+func sameTask(a, b *Task) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	aj, err1 := json.Marshal(a)
+	bj, err2 := json.Marshal(b)
+	return err1 == nil && err2 == nil && bytes.Equal(aj, bj)
+}
+
+// applyChanges moves each task an operation changed from one of its states
+// to the other: back to Before when undoing, forward to After when redoing
+func applyChanges(tasks []Task, op Operation, undo bool) ([]Task, error) {
+	type insert struct {
+		index int
+		task  Task
+	}
+	var inserts []insert
+	removed := make(map[int]bool)
+	replaced := make(map[int]Task)
+
+	for _, c := range op.Changes {
+		from, to := c.Before, c.After
+		if undo {
+			from, to = c.After, c.Before
+		}
+		current, _ := GetByID(tasks, c.ID)
+		if !sameTask(current, from) {
+			return tasks, &UndoConflictError{Op: op, TaskID: c.ID, Redo: !undo}
+		}
+		switch {
+		case to == nil:
+			removed[c.ID] = true
+		case from == nil:
+			inserts = append(inserts, insert{c.Index, to.Clone()})
+		default:
+			replaced[c.ID] = to.Clone()
+		}
+	}
+
+	result := make([]Task, 0, len(tasks)+len(inserts))
+	for _, t := range tasks {
+		if removed[t.ID] {
+			continue
+		}
+		if r, ok := replaced[t.ID]; ok {
+			t = r
+		}
+		result = append(result, t)
+	}
+	// Put tasks back where they were, lowest position first
+	sort.Slice(inserts, func(i, j int) bool { return inserts[i].index < inserts[j].index })
+	for _, in := range inserts {
+		i := min(max(in.index, 0), len(result))
+		result = append(result[:i], append([]Task{in.task}, result[i:]...)...)
+	}
+	return result, nil
+}
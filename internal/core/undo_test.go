@@ -0,0 +1,145 @@
+package core
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// record runs fn on a copy of tasks and records what it changed
+func record(t *testing.T, tasks []Task, history []Operation, action string, fn func([]Task) ([]Task, error)) ([]Task, []Operation) {
+	t.Helper()
+
+	before := make([]Task, len(tasks))
+	for i := range tasks {
+		before[i] = tasks[i].Clone()
+	}
+	after, err := fn(tasks)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", action, err)
+	}
+	return after, RecordOperation(history, Operation{Action: action, Changes: DiffTasks(before, after)}, UndoLimit)
+}
+
+func TestUndoRedo(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	due := now.Add(24 * time.Hour)
+	tasks := []Task{
+		{ID: 1, Title: "Spec", Priority: 1},
+		{ID: 2, Title: "Water plants", Due: &due, Repeat: "daily", Priority: 1},
+		{ID: 3, Title: "Ship", Priority: 1},
+	}
+	var history []Operation
+
+	// Completing a recurring task also spawns its next occurrence
+	tasks, history = record(t, tasks, history, "done", func(tasks []Task) ([]Task, error) {
+		return MarkDoneAt(tasks, tasks[1:2], 1, now)
+	})
+	if len(tasks) != 4 || len(history[0].Changes) != 2 {
+		t.Fatalf("Expected the done task and a new occurrence, got %d tasks and %+v", len(tasks), history[0].Changes)
+	}
+	tasks, history = record(t, tasks, history, "remove", func(tasks []Task) ([]Task, error) {
+		return Remove(tasks, tasks, 1)
+	})
+	if got := taskIDs(tasks); !reflect.DeepEqual(got, []int{2, 3, 4}) {
+		t.Fatalf("Expected #1 to be removed, got %v", got)
+	}
+
+	// Undoing the removal puts the task back in its place
+	tasks, history, op, err := Undo(tasks, history)
+	if err != nil || op.Action != "remove" {
+		t.Fatalf("Undo: got %s, %v", op.Summary(), err)
+	}
+	if got := taskIDs(tasks); !reflect.DeepEqual(got, []int{1, 2, 3, 4}) {
+		t.Errorf("Expected #1 restored first, got %v", got)
+	}
+
+	// Undoing the completion reopens the task and removes the new occurrence
+	tasks, history, op, err = Undo(tasks, history)
+	if err != nil || op.Action != "done" {
+		t.Fatalf("Undo: got %s, %v", op.Summary(), err)
+	}
+	if got := taskIDs(tasks); !reflect.DeepEqual(got, []int{1, 2, 3}) || tasks[1].IsDone() {
+		t.Errorf("Expected #2 open and no new occurrence, got %v", tasks)
+	}
+	if _, _, _, err := Undo(tasks, history); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("Expected ErrNothingToUndo, got %v", err)
+	}
+
+	// Redo replays them in order
+	tasks, history, op, err = Redo(tasks, history)
+	if err != nil || op.Action != "done" || !tasks[1].IsDone() || len(tasks) != 4 {
+		t.Fatalf("Redo: got %s, %v", op.Summary(), err)
+	}
+
+	// A new operation drops what is left to redo
+	tasks, history = record(t, tasks, history, "edit", func(tasks []Task) ([]Task, error) {
+		tasks[2].Title = "Ship it"
+		return tasks, nil
+	})
+	if _, _, _, err := Redo(tasks, history); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("Expected ErrNothingToRedo, got %v", err)
+	}
+	if len(history) != 2 || history[1].Seq != 3 {
+		t.Errorf("Expected the edit recorded as operation 3 after the done, got %+v", history)
+	}
+	if got := history[1].Describe(); len(got) != 1 || got[0] != `changed #3 "Ship it": title` {
+		t.Errorf("Unexpected description %q", got)
+	}
+}
+
+func TestUndoConflict(t *testing.T) {
+	tasks := []Task{{ID: 1, Title: "Spec"}}
+	var history []Operation
+	tasks, history = record(t, tasks, history, "edit", func(tasks []Task) ([]Task, error) {
+		tasks[0].Title = "Write spec"
+		return tasks, nil
+	})
+
+	// Changed again without being recorded, e.g. by an older version
+	tasks[0].Priority = 3
+	var conflict *UndoConflictError
+	if _, _, _, err := Undo(tasks, history); !errors.As(err, &conflict) || conflict.TaskID != 1 {
+		t.Errorf("Expected an UndoConflictError for #1, got %v", err)
+	}
+
+	// An operation that never reached the tasks is passed over
+	tasks[0] = Task{ID: 1, Title: "Spec"}
+	if _, _, _, err := Undo(tasks, history); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("Expected ErrNothingToUndo, got %v", err)
+	}
+}
+
+func TestUndoDropsFailedOperations(t *testing.T) {
+	var history []Operation
+	tasks, history := record(t, nil, history, "add", func(tasks []Task) ([]Task, error) {
+		return append(tasks, Task{ID: 1, Title: "B"}), nil
+	})
+	// Recorded, but saving the removal failed
+	_, history = record(t, tasks, history, "remove", func(tasks []Task) ([]Task, error) {
+		return Remove(tasks, tasks, 1)
+	})
+
+	tasks, history, op, err := Undo(tasks, history)
+	if err != nil || op.Action != "add" || len(tasks) != 0 {
+		t.Fatalf("Undo: expected the add undone, got %s, %v, %v", op.Summary(), tasks, err)
+	}
+	tasks, history, op, err = Redo(tasks, history)
+	if err != nil || op.Action != "add" || len(tasks) != 1 {
+		t.Fatalf("Redo: expected the add redone, got %s, %v, %v", op.Summary(), tasks, err)
+	}
+	if _, _, _, err := Redo(tasks, history); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("Expected the failed removal to be gone, got %v", err)
+	}
+}
+
+func TestRecordOperationLimit(t *testing.T) {
+	var history []Operation
+	for i := 0; i < 5; i++ {
+		history = RecordOperation(history, Operation{Action: "add"}, 3)
+	}
+	if len(history) != 3 || history[0].Seq != 3 || history[2].Seq != 5 {
+		t.Errorf("Expected operations 3-5, got %+v", history)
+	}
+}
@@ -0,0 +1,117 @@
+package core
+
+import (
+	"math"
+	"strings"
+	"time"
+)
+
+// UrgencyWeights configures the urgency score. Each weight is the most a
+// factor can add, or the amount per unit where noted.
+type UrgencyWeights struct {
+	Priority float64            `json:"priority"`       // per priority level above low
+	Due      float64            `json:"due"`            // for a task due now or overdue, scaled down to 0 two weeks ahead
+	Overdue  float64            `json:"overdue"`        // per day overdue, up to two weeks
+	Blocking float64            `json:"blocking"`       // per open task waiting on it, up to 5
+	Tags     map[string]float64 `json:"tags,omitempty"` // per tag the task has
+	Age      float64            `json:"age"`            // for a task a year old, scaled by its age
+}
+
+// Limits on the urgency factors
+const (
+	urgencyDueHorizon  = 14 * 24 * time.Hour
+	urgencyMaxOverdue  = 14.0 // days
+	urgencyMaxBlocking = 5
+	urgencyAgeHorizon  = 365 * 24 * time.Hour
+)
+
+// DefaultUrgencyWeights returns the weights used unless configured otherwise
+func DefaultUrgencyWeights() UrgencyWeights {
+	return UrgencyWeights{
+		Priority: 3,
+		Due:      8,
+		Overdue:  0.5,
+		Blocking: 1,
+		Age:      2,
+	}
+}
+
+// UrgencyFactor is one part of an urgency score
+type UrgencyFactor struct {
+	Name  string  `json:"name"` // priority, due, overdue, blocking, tag:<name> or age
+	Score float64 `json:"score"`
+}
+
+// Urgency is how pressing a task is, with the factors that make up the score
+type Urgency struct {
+	Score   float64         `json:"score"`
+	Factors []UrgencyFactor `json:"factors,omitempty"`
+}
+
+// ScoredTask is a task together with its urgency
+type ScoredTask struct {
+	Task
+	Urgency Urgency `json:"urgency"`
+}
+
+// UrgencyScorer computes urgency scores at a point in time. Tasks waiting on
+// a scored task are looked up in All.
+type UrgencyScorer struct {
+	Weights UrgencyWeights
+	All     []Task
+	Now     time.Time
+}
+
+// Score returns the urgency of a task; closed tasks score 0
+func (s UrgencyScorer) Score(t Task) Urgency {
+	var u Urgency
+	if t.IsDone() {
+		return u
+	}
+	add := func(name string, score float64) {
+		if score = math.Round(score*100) / 100; score != 0 {
+			u.Factors = append(u.Factors, UrgencyFactor{Name: name, Score: score})
+			u.Score += score
+		}
+	}
+	w := s.Weights
+
+	add("priority", w.Priority*float64(NormalizePriority(t.Priority)-int(PriorityLow)))
+
+	if t.Due != nil {
+		left := t.Deadline(s.Now.Location()).Sub(s.Now)
+		add("due", w.Due*clamp01(1-float64(left)/float64(urgencyDueHorizon)))
+		if t.IsOverdue(s.Now) {
+			days := math.Min(float64(-left)/float64(24*time.Hour), urgencyMaxOverdue)
+			add("overdue", w.Overdue*days)
+		}
+	}
+
+	if w.Blocking != 0 {
+		add("blocking", w.Blocking*math.Min(float64(len(Blocks(s.All, t.ID))), urgencyMaxBlocking))
+	}
+
+	for _, tag := range t.Tags {
+		add("tag:"+strings.ToLower(tag), w.Tags[strings.ToLower(tag)])
+	}
+
+	if !t.CreatedAt.IsZero() {
+		add("age", w.Age*clamp01(float64(s.Now.Sub(t.CreatedAt))/float64(urgencyAgeHorizon)))
+	}
+
+	u.Score = math.Round(u.Score*100) / 100
+	return u
+}
+
+// ScoreAll pairs each task with its urgency
+func (s UrgencyScorer) ScoreAll(tasks []Task) []ScoredTask {
+	scored := make([]ScoredTask, len(tasks))
+	for i, t := range tasks {
+		scored[i] = ScoredTask{Task: t, Urgency: s.Score(t)}
+	}
+	return scored
+}
+
+func clamp01(x float64) float64 {
+	return math.Max(0, math.Min(1, x))
+}
@@ -0,0 +1,82 @@
+package core
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUrgencyScore(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	weekAgo := now.AddDate(0, 0, -7)
+	tasks := []Task{
+		{ID: 1, Priority: 3, CreatedAt: now},
+		{ID: 2, Due: &weekAgo, Timed: true, CreatedAt: now},
+		{ID: 3, CreatedAt: now, Tags: []string{"Urgent"}},
+		{ID: 4, DependsOn: []int{3}, CreatedAt: now.AddDate(-2, 0, 0)},
+		{ID: 5, Priority: 3, DoneAt: &now},
+	}
+	w := DefaultUrgencyWeights()
+	w.Tags = map[string]float64{"urgent": 4}
+	scorer := UrgencyScorer{Weights: w, All: tasks, Now: now}
+
+	cases := []struct {
+		id      int
+		want    float64
+		factors []string
+	}{
+		{1, 6, []string{"priority"}},
+		{2, 11.5, []string{"due", "overdue"}},
+		{3, 5, []string{"blocking", "tag:urgent"}},
+		{4, 2, []string{"age"}},
+		{5, 0, nil},
+	}
+	for _, c := range cases {
+		task, _ := GetByID(tasks, c.id)
+		u := scorer.Score(*task)
+		if u.Score != c.want {
+			t.Errorf("#%d: score %.2f, want %.2f (%+v)", c.id, u.Score, c.want, u.Factors)
+		}
+		if len(u.Factors) != len(c.factors) {
+			t.Errorf("#%d: factors %+v, want %v", c.id, u.Factors, c.factors)
+			continue
+		}
+		for i, name := range c.factors {
+			if u.Factors[i].Name != name {
+				t.Errorf("#%d: factor %d is %q, want %q", c.id, i, u.Factors[i].Name, name)
+			}
+		}
+	}
+}
+
+func TestUrgencyDueProximity(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	soon := now.Add(24 * time.Hour)
+	later := now.AddDate(0, 0, 10)
+	farOff := now.AddDate(0, 1, 0)
+	scorer := UrgencyScorer{Weights: DefaultUrgencyWeights(), Now: now}
+
+	a := scorer.Score(Task{Due: &soon, Timed: true}).Score
+	b := scorer.Score(Task{Due: &later, Timed: true}).Score
+	c := scorer.Score(Task{Due: &farOff, Timed: true}).Score
+	if !(a > b && b > c && c == 0) {
+		t.Errorf("Expected urgency to fall with distance to the due date, got %.2f, %.2f, %.2f", a, b, c)
+	}
+}
+
+func TestSortByUrgency(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	tomorrow := now.Add(24 * time.Hour)
+	tasks := []Task{
+		{ID: 1, CreatedAt: now},
+		{ID: 2, Priority: 2, CreatedAt: now},
+		{ID: 3, Due: &tomorrow, Timed: true, CreatedAt: now},
+	}
+
+	SortTasksAt(tasks, SortByUrgency, UrgencyScorer{Weights: DefaultUrgencyWeights(), All: tasks, Now: now})
+	want := []int{3, 2, 1}
+	for i, id := range taskIDs(tasks) {
+		if id != want[i] {
+			t.Fatalf("Expected order %v, got %v", want, taskIDs(tasks))
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package core
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// View is a named combination of list filters, sort and display options,
+// saved so the same list can be shown again from the CLI or the HTTP API.
+// Dates are kept as typed and resolved each time the view is run, so
+// "today" and "+3d" stay relative.
+type View struct {
+	Name     string `json:"name"`
+	Filter   string `json:"filter,omitempty"` // filter expression, see ParseFilter
+	Grep     string `json:"grep,omitempty"`
+	Tags     string `json:"tags,omitempty"`     // comma = OR, plus = AND, see FilterByTags
+	Sort     string `json:"sort,omitempty"`     // sort expression, see ParseSort
+	Status   string `json:"status,omitempty"`   // see FilterByStatusName
+	Project  string `json:"project,omitempty"`  // a project, "all" or "none"; empty for the default project
+	All      bool   `json:"all,omitempty"`      // include closed tasks
+	Deferred bool   `json:"deferred,omitempty"` // include tasks scheduled to start later
+	Today    bool   `json:"today,omitempty"`    // only tasks due or scheduled today
+	Week     bool   `json:"week,omitempty"`     // only tasks due or scheduled this week
+	Before   string `json:"before,omitempty"`   // only tasks due before this date, see ParseDate
+	After    string `json:"after,omitempty"`    // only tasks due after this date
+	Detailed bool   `json:"detailed,omitempty"`
+	Tree     bool   `json:"tree,omitempty"`
+}
+
+// reservedViewNames are the view subcommands of the CLI
+var reservedViewNames = map[string]bool{
+	"list": true, "ls": true, "save": true, "edit": true, "show": true, "delete": true, "rm": true,
+}
+
+// ViewNotFoundError is returned when no view has the given name
+type ViewNotFoundError struct {
+	Name string
+}
+
+func (e *ViewNotFoundError) Error() string {
+	return fmt.Sprintf("view %q not found", e.Name)
+}
+
+// NormalizeViewName validates a view name and returns its canonical
+// (lower-case) form. Names follow the rules for project names.
+func NormalizeViewName(name string) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", fmt.Errorf("view name is required")
+	}
+	if reservedViewNames[name] {
+		return "", fmt.Errorf("view name %q is reserved", name)
+	}
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+		default:
+			return "", fmt.Errorf("invalid view name %q (use letters, digits, '-', '_' and '.')", name)
+		}
+	}
+	return name, nil
+}
+
+// Validate checks the view's name and that its expressions parse, resolving
+// relative dates against now
+func (v View) Validate(now time.Time) error {
+	if _, err := NormalizeViewName(v.Name); err != nil {
+		return err
+	}
+	if v.Filter != "" {
+		if _, err := ParseFilter(v.Filter, now); err != nil {
+			return err
+		}
+	}
+	if _, err := ParseSort(v.Sort); err != nil {
+		return err
+	}
+	if _, err := FilterByStatusName(nil, v.Status); err != nil {
+		return err
+	}
+	if p := strings.ToLower(strings.TrimSpace(v.Project)); p != "" && p != ProjectAll && p != ProjectNone {
+		if _, err := NormalizeProject(p); err != nil {
+			return err
+		}
+	}
+	if v.Today && v.Week {
+		return fmt.Errorf("a view cannot be limited to both today and this week")
+	}
+	for _, d := range []struct{ name, value string }{{"before", v.Before}, {"after", v.After}} {
+		if d.value == "" {
+			continue
+		}
+		if v.Today || v.Week {
+			return fmt.Errorf("%s cannot be combined with today or week", d.name)
+		}
+		if _, err := ParseDate(d.value, now); err != nil {
+			return fmt.Errorf("invalid %s date: %w", d.name, err)
+		}
+	}
+	return nil
+}
+
+// FindView returns the view with the given name
+func FindView(views []View, name string) (*View, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for i := range views {
+		if views[i].Name == name {
+			return &views[i], nil
+		}
+	}
+	return nil, &ViewNotFoundError{Name: name}
+}
+
+// PutView adds a view, replacing any view with the same name, and returns
+// the views sorted by name
+func PutView(views []View, v View) []View {
+	result := make([]View, 0, len(views)+1)
+	for _, existing := range views {
+		if existing.Name != v.Name {
+			result = append(result, existing)
+		}
+	}
+	result = append(result, v)
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// RemoveView removes the view with the given name
+func RemoveView(views []View, name string) ([]View, error) {
+	if _, err := FindView(views, name); err != nil {
+		return views, err
+	}
+	name = strings.ToLower(strings.TrimSpace(name))
+	result := make([]View, 0, len(views))
+	for _, v := range views {
+		if v.Name != name {
+			result = append(result, v)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestNormalizeViewName(t *testing.T) {
+	if got, err := NormalizeViewName(" My-Week "); err != nil || got != "my-week" {
+		t.Errorf("NormalizeViewName: got %q, %v; want my-week", got, err)
+	}
+	for _, bad := range []string{"", "list", "save", "my week", "a/b"} {
+		if _, err := NormalizeViewName(bad); err == nil {
+			t.Errorf("Expected an error for %q", bad)
+		}
+	}
+}
+
+func TestViewValidate(t *testing.T) {
+	now := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	good := View{Name: "my-week", Filter: "tag:work and p>=2", Sort: "priority:desc,due", Status: "open", Week: true, Detailed: true}
+	if err := good.Validate(now); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+
+	bad := []View{
+		{Name: "v", Filter: "tag:"},
+		{Name: "v", Sort: "size"},
+		{Name: "v", Status: "finished"},
+		{Name: "v", Project: "all projects"},
+		{Name: "v", Today: true, Week: true},
+		{Name: "v", Week: true, Before: "friday"},
+		{Name: "v", After: "someday"},
+	}
+	for _, v := range bad {
+		if err := v.Validate(now); err == nil {
+			t.Errorf("Expected an error for %+v", v)
+		}
+	}
+}
+
+func TestPutAndRemoveView(t *testing.T) {
+	views := PutView(nil, View{Name: "work", Tags: "work"})
+	views = PutView(views, View{Name: "home", Tags: "home"})
+	views = PutView(views, View{Name: "work", Tags: "work", Detailed: true})
+
+	if len(views) != 2 || views[0].Name != "home" || views[1].Name != "work" || !views[1].Detailed {
+		t.Fatalf("Expected home and the updated work view, got %+v", views)
+	}
+
+	views, err := RemoveView(views, "Home")
+	if err != nil || len(views) != 1 || views[0].Name != "work" {
+		t.Errorf("Expected only work after removing home, got %+v, %v", views, err)
+	}
+
+	var notFound *ViewNotFoundError
+	if _, err := RemoveView(views, "home"); !errors.As(err, &notFound) {
+		t.Errorf("Expected ViewNotFoundError, got %v", err)
+	}
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 
 	"godoit/internal/core"
 	"godoit/internal/store"
@@ -12,6 +13,18 @@ import (
 type TaskRepository interface {
     LoadTasks(ctx context.Context) ([]core.Task, error)
     SaveTasks(ctx context.Context, tasks []core.Task) error
+
+    // Update runs a read-modify-write transaction: it loads the tasks, passes
+    // them to fn and saves whatever fn returns, all while holding the store's
+    // exclusive lock. Nothing is saved if fn returns an error.
+    Update(ctx context.Context, fn func([]core.Task) ([]core.Task, error)) error
+}
+
+// StampedRepository is implemented by task repositories that can tell
+// whether the tasks have changed without loading them. Stamp reports false
+// when it cannot.
+type StampedRepository interface {
+    Stamp(ctx context.Context) (string, bool, error)
 }
 
 // JSONTaskRepository implements TaskRepository over store.Store (JSON file).
@@ -32,9 +45,22 @@ func (r *JSONTaskRepository) LoadTasks(_ context.Context) ([]core.Task, error) {
     if err := json.Unmarshal(data, &tasks); err != nil {
         return nil, err
     }
+    // Repair duplicate IDs left by older versions; saved on the next update
+    core.DedupeIDs(tasks)
     return tasks, nil
 }
 
+// Stamp returns a value that changes whenever the tasks are saved, if the
+// store can tell without loading them (see store.Stamper)
+func (r *JSONTaskRepository) Stamp(_ context.Context) (string, bool, error) {
+    s, ok := r.store.(store.Stamper)
+    if !ok {
+        return "", false, nil
+    }
+    stamp, err := s.Stamp()
+    return stamp, err == nil, err
+}
+
 func (r *JSONTaskRepository) SaveTasks(ctx context.Context, tasks []core.Task) error {
     data, err := json.Marshal(tasks)
     if err != nil {
@@ -43,4 +69,167 @@ func (r *JSONTaskRepository) SaveTasks(ctx context.Context, tasks []core.Task) e
     return r.store.WithExclusive(ctx, func() error { return r.store.Save(data) })
 }
 
+func (r *JSONTaskRepository) Update(ctx context.Context, fn func([]core.Task) ([]core.Task, error)) error {
+    return r.store.WithExclusive(ctx, func() error {
+        tasks, err := r.LoadTasks(ctx)
+        if err != nil {
+            return err
+        }
+        tasks, err = fn(tasks)
+        if err != nil {
+            return err
+        }
+        data, err := json.Marshal(tasks)
+        if err != nil {
+            return err
+        }
+        return r.store.Save(data)
+    })
+}
+
+// ViewRepository abstracts persistence for saved views.
+type ViewRepository interface {
+    LoadViews(ctx context.Context) ([]core.View, error)
+
+    // UpdateViews runs a read-modify-write transaction under the store's
+    // exclusive lock, like TaskRepository.Update.
+    UpdateViews(ctx context.Context, fn func([]core.View) ([]core.View, error)) error
+}
+
+// JSONViewRepository implements ViewRepository over store.Store (JSON file).
+type JSONViewRepository struct {
+    store store.Store
+}
+
+func NewJSONViewRepository(s store.Store) *JSONViewRepository {
+    return &JSONViewRepository{store: s}
+}
+
+func (r *JSONViewRepository) LoadViews(_ context.Context) ([]core.View, error) {
+    data, err := r.store.Load()
+    if err != nil {
+        return nil, err
+    }
+    var views []core.View
+    if err := json.Unmarshal(data, &views); err != nil {
+        return nil, err
+    }
+    return views, nil
+}
+
+func (r *JSONViewRepository) UpdateViews(ctx context.Context, fn func([]core.View) ([]core.View, error)) error {
+    return r.store.WithExclusive(ctx, func() error {
+        views, err := r.LoadViews(ctx)
+        if err != nil {
+            return err
+        }
+        views, err = fn(views)
+        if err != nil {
+            return err
+        }
+        data, err := json.Marshal(views)
+        if err != nil {
+            return err
+        }
+        return r.store.Save(data)
+    })
+}
+
+// UndoRepository abstracts persistence for the undo history.
+type UndoRepository interface {
+    LoadUndo(ctx context.Context) ([]core.Operation, error)
+
+    // UpdateUndo runs a read-modify-write transaction under the store's
+    // exclusive lock, like TaskRepository.Update. The task service calls it
+    // from within TaskRepository.Update, so the task store's lock is always
+    // taken first.
+    UpdateUndo(ctx context.Context, fn func([]core.Operation) ([]core.Operation, error)) error
+}
+
+// JSONUndoRepository implements UndoRepository over store.Store (JSON file).
+type JSONUndoRepository struct {
+    store store.Store
+}
+
+func NewJSONUndoRepository(s store.Store) *JSONUndoRepository {
+    return &JSONUndoRepository{store: s}
+}
+
+func (r *JSONUndoRepository) LoadUndo(_ context.Context) ([]core.Operation, error) {
+    data, err := r.store.Load()
+    if err != nil {
+        return nil, err
+    }
+    var ops []core.Operation
+    if err := json.Unmarshal(data, &ops); err != nil {
+        return nil, err
+    }
+    return ops, nil
+}
+
+func (r *JSONUndoRepository) UpdateUndo(ctx context.Context, fn func([]core.Operation) ([]core.Operation, error)) error {
+    return r.store.WithExclusive(ctx, func() error {
+        ops, err := r.LoadUndo(ctx)
+        if err != nil {
+            return err
+        }
+        ops, err = fn(ops)
+        if err != nil {
+            return err
+        }
+        data, err := json.Marshal(ops)
+        if err != nil {
+            return err
+        }
+        return r.store.Save(data)
+    })
+}
+
+// AuditRepository abstracts persistence for the audit log of task changes.
+// Entries are only ever appended.
+type AuditRepository interface {
+    AppendAudit(ctx context.Context, entries []core.AuditEntry) error
 
+    // LoadAudit returns the entries for one task, oldest first
+    LoadAudit(ctx context.Context, taskID int) ([]core.AuditEntry, error)
+}
+
+// JSONAuditRepository implements AuditRepository over store.AuditLog, one
+// JSON entry per line.
+type JSONAuditRepository struct {
+    log *store.AuditLog
+}
+
+func NewJSONAuditRepository(l *store.AuditLog) *JSONAuditRepository {
+    return &JSONAuditRepository{log: l}
+}
+
+func (r *JSONAuditRepository) AppendAudit(ctx context.Context, entries []core.AuditEntry) error {
+    lines := make([][]byte, 0, len(entries))
+    for _, e := range entries {
+        line, err := json.Marshal(e)
+        if err != nil {
+            return err
+        }
+        lines = append(lines, line)
+    }
+    return r.log.Append(ctx, lines)
+}
+
+func (r *JSONAuditRepository) LoadAudit(_ context.Context, taskID int) ([]core.AuditEntry, error) {
+    lines, err := r.log.Lines()
+    if err != nil {
+        return nil, err
+    }
+    var entries []core.AuditEntry
+    for i, line := range lines {
+        var e core.AuditEntry
+        if err := json.Unmarshal(line, &e); err != nil {
+            return nil, fmt.Errorf("audit log line %d: %w", i+1, err)
+        }
+        if e.TaskID == taskID {
+            entries = append(entries, e)
+        }
+    }
+    return entries, nil
+}
@@ -0,0 +1,531 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"godoit/internal/core"
+	"godoit/internal/store"
+)
+
+// addTasks appends n tasks through Update, one transaction per task
+func addTasks(repo TaskRepository, prefix string, n int) error {
+	for i := 0; i < n; i++ {
+		title := fmt.Sprintf("%s-%d", prefix, i)
+		err := repo.Update(context.Background(), func(tasks []core.Task) ([]core.Task, error) {
+			return core.AddAt(tasks, title, nil, time.Now()), nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openStore opens a store of the given kind (see store.TaskStore) at path
+func openStore(kind, path string) (store.Store, error) {
+	if kind == store.StorageJournal {
+		return store.NewJournalStore(path)
+	}
+	return store.NewJSONStore(path)
+}
+
+// checkTasks verifies that exactly want tasks were saved with unique IDs
+func checkTasks(t *testing.T, kind, path string, want int) {
+	t.Helper()
+
+	s, err := openStore(kind, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tasks, err := NewJSONTaskRepository(s).LoadTasks(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tasks) != want {
+		t.Errorf("Expected %d tasks, got %d (lost updates)", want, len(tasks))
+	}
+
+	seen := make(map[int]bool)
+	for _, task := range tasks {
+		if seen[task.ID] {
+			t.Errorf("Duplicate task ID %d", task.ID)
+		}
+		seen[task.ID] = true
+	}
+}
+
+func TestUpdateConcurrentGoroutines(t *testing.T) {
+	for _, kind := range []string{store.StorageJSON, store.StorageJournal} {
+		t.Run(kind, func(t *testing.T) { testUpdateConcurrentGoroutines(t, kind) })
+	}
+}
+
+func testUpdateConcurrentGoroutines(t *testing.T, kind string) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	shared, err := openStore(kind, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const workers, perWorker = 16, 10
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			// Half the workers share one store (like the HTTP server), the
+			// other half open their own (like separate CLI invocations).
+			s := shared
+			if w%2 == 1 {
+				own, err := openStore(kind, path)
+				if err != nil {
+					errs <- err
+					return
+				}
+				s = own
+			}
+			errs <- addTasks(NewJSONTaskRepository(s), fmt.Sprintf("w%d", w), perWorker)
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Update failed: %v", err)
+		}
+	}
+
+	checkTasks(t, kind, path, workers*perWorker)
+}
+
+func TestUpdateConcurrentProcesses(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spawns subprocesses")
+	}
+
+	for _, kind := range []string{store.StorageJSON, store.StorageJournal} {
+		t.Run(kind, func(t *testing.T) { testUpdateConcurrentProcesses(t, kind) })
+	}
+}
+
+func testUpdateConcurrentProcesses(t *testing.T, kind string) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+
+	const procs, perProc = 4, 15
+	cmds := make([]*exec.Cmd, 0, procs)
+	for p := 0; p < procs; p++ {
+		cmd := exec.Command(os.Args[0], "-test.run=TestHelperProcess")
+		cmd.Env = append(os.Environ(),
+			"GODOIT_HELPER_STORE="+path,
+			"GODOIT_HELPER_KIND="+kind,
+			"GODOIT_HELPER_PREFIX=p"+strconv.Itoa(p),
+			"GODOIT_HELPER_COUNT="+strconv.Itoa(perProc),
+		)
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+		cmds = append(cmds, cmd)
+	}
+
+	// Write from this process as well while the helpers are running
+	s, err := openStore(kind, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addTasks(NewJSONTaskRepository(s), "parent", perProc); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Fatalf("helper process failed: %v", err)
+		}
+	}
+
+	checkTasks(t, kind, path, (procs+1)*perProc)
+}
+
+// TestHelperProcess is not a real test; it is the body of the subprocesses
+// started by TestUpdateConcurrentProcesses.
+func TestHelperProcess(t *testing.T) {
+	path := os.Getenv("GODOIT_HELPER_STORE")
+	if path == "" {
+		return
+	}
+
+	n, err := strconv.Atoi(os.Getenv("GODOIT_HELPER_COUNT"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := openStore(os.Getenv("GODOIT_HELPER_KIND"), path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addTasks(NewJSONTaskRepository(s), os.Getenv("GODOIT_HELPER_PREFIX"), n); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUpdateViewsConcurrentGoroutines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "views.json")
+
+	const workers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+
+			s, err := store.NewJSONStore(path)
+			if err != nil {
+				errs <- err
+				return
+			}
+			view := core.View{Name: fmt.Sprintf("view-%d", w), Tags: "work"}
+			errs <- NewJSONViewRepository(s).UpdateViews(context.Background(), func(views []core.View) ([]core.View, error) {
+				return core.PutView(views, view), nil
+			})
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("UpdateViews failed: %v", err)
+		}
+	}
+
+	s, err := store.NewJSONStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	views, err := NewJSONViewRepository(s).LoadViews(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(views) != workers {
+		t.Fatalf("Expected %d views, got %d (lost updates)", workers, len(views))
+	}
+	for i, v := range views {
+		if want := fmt.Sprintf("view-%d", i); v.Name != want || v.Tags != "work" {
+			t.Errorf("View %d: got %+v, want %s with tags work", i, v, want)
+		}
+	}
+}
+
+// journalRecords returns the number of records in the journal next to path
+func journalRecords(t *testing.T, path string) int {
+	t.Helper()
+
+	data, err := os.ReadFile(strings.TrimSuffix(path, ".json") + ".journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Count(string(data), "\n") - 1 // not counting the header
+}
+
+// loadTitles returns the titles of the tasks in s, in order
+func loadTitles(t *testing.T, s store.Store) string {
+	t.Helper()
+
+	tasks, err := NewJSONTaskRepository(s).LoadTasks(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	titles := make([]string, len(tasks))
+	for i, task := range tasks {
+		titles[i] = task.Title
+	}
+	return strings.Join(titles, ",")
+}
+
+func TestJournalStoreReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	s, err := store.NewJournalStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := NewJSONTaskRepository(s)
+	if err := addTasks(repo, "t", 4); err != nil {
+		t.Fatal(err)
+	}
+
+	// Edit, remove and reorder
+	err = repo.Update(context.Background(), func(tasks []core.Task) ([]core.Task, error) {
+		tasks[1].Title = "edited"
+		return tasks, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.Update(context.Background(), func(tasks []core.Task) ([]core.Task, error) {
+		return append(tasks[:2], tasks[3]), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.Update(context.Background(), func(tasks []core.Task) ([]core.Task, error) {
+		tasks[0], tasks[2] = tasks[2], tasks[0]
+		return tasks, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Saving the same tasks again records nothing
+	if err := repo.Update(context.Background(), func(tasks []core.Task) ([]core.Task, error) { return tasks, nil }); err != nil {
+		t.Fatal(err)
+	}
+
+	const want = "t-3,edited,t-0"
+	if got := loadTitles(t, s); got != want {
+		t.Errorf("Got %s, want %s", got, want)
+	}
+	reopened, err := store.NewJournalStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loadTitles(t, reopened); got != want {
+		t.Errorf("Replayed %s, want %s", got, want)
+	}
+	// The first save starts the journal; each later change is one record
+	if n := journalRecords(t, path); n != 6 {
+		t.Errorf("Expected 6 journal records, got %d", n)
+	}
+}
+
+func TestJournalStoreTornRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	s, err := store.NewJournalStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addTasks(NewJSONTaskRepository(s), "t", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash in the middle of appending a record
+	f, err := os.OpenFile(strings.TrimSuffix(path, ".json")+".journal", os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"seq":3,"at":"2025-10-22T09:00:00Z","put":[{"id":4,"tit`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	reopened, err := store.NewJournalStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loadTitles(t, reopened); got != "t-0,t-1,t-2" {
+		t.Errorf("Got %s after a torn record, want the tasks saved before it", got)
+	}
+
+	// The next save replaces the torn record
+	if err := addTasks(NewJSONTaskRepository(reopened), "u", 1); err != nil {
+		t.Fatal(err)
+	}
+	again, err := store.NewJournalStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loadTitles(t, again); got != "t-0,t-1,t-2,u-0" {
+		t.Errorf("Got %s, want t-0,t-1,t-2,u-0", got)
+	}
+	if n := journalRecords(t, path); n != 3 {
+		t.Errorf("Expected 3 journal records, got %d", n)
+	}
+}
+
+func TestJournalStoreCompaction(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	s, err := store.NewJournalStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.CompactAfter = 4
+	if err := addTasks(NewJSONTaskRepository(s), "t", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := journalRecords(t, path); n > 4 {
+		t.Errorf("Expected the journal to be compacted, got %d records", n)
+	}
+	reopened, err := store.NewJournalStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := loadTitles(t, s)
+	if got := loadTitles(t, reopened); got != want || strings.Count(got, ",") != 9 {
+		t.Errorf("Replayed %s, want %s", got, want)
+	}
+
+	// Compacting leaves every task in the snapshot, readable by JSONStore
+	if err := s.Compact(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	js, err := store.NewJSONStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loadTitles(t, js); got != want {
+		t.Errorf("Snapshot holds %s, want %s", got, want)
+	}
+
+	// Changes made through JSONStore replace the journal's, which no longer
+	// applies to the snapshot
+	if err := addTasks(NewJSONTaskRepository(reopened), "u", 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := js.Save([]byte(`[{"id":1,"title":"only"}]`)); err != nil {
+		t.Fatal(err)
+	}
+	if got := loadTitles(t, reopened); got != "only" {
+		t.Errorf("Got %s, want the snapshot written by JSONStore", got)
+	}
+}
+
+func TestTaskStoreFoldsJournal(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	s, err := store.TaskStore(store.StorageJournal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := addTasks(NewJSONTaskRepository(s), "t", 3); err != nil {
+		t.Fatal(err)
+	}
+
+	// Switching back to JSON keeps the changes still in the journal
+	js, err := store.TaskStore(store.StorageJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := loadTitles(t, js); got != "t-0,t-1,t-2" {
+		t.Errorf("Got %s, want t-0,t-1,t-2", got)
+	}
+	path, err := store.GetDataFile()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(strings.TrimSuffix(path, ".json") + ".journal"); !os.IsNotExist(err) {
+		t.Errorf("Expected the journal to be removed, got %v", err)
+	}
+
+	if _, err := store.TaskStore("sqlite"); err == nil {
+		t.Error("Expected an error for an unknown storage kind")
+	}
+}
+
+func TestTaskRepositoryStamp(t *testing.T) {
+	for _, kind := range []string{store.StorageJSON, store.StorageJournal} {
+		t.Run(kind, func(t *testing.T) { testTaskRepositoryStamp(t, kind) })
+	}
+}
+
+func testTaskRepositoryStamp(t *testing.T, kind string) {
+	path := filepath.Join(t.TempDir(), "tasks.json")
+	var repos [2]*JSONTaskRepository
+	for i := range repos {
+		s, err := openStore(kind, path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		repos[i] = NewJSONTaskRepository(s)
+	}
+	stamp := func() string {
+		t.Helper()
+		s, ok, err := repos[0].Stamp(context.Background())
+		if err != nil || !ok {
+			t.Fatalf("Stamp: %v, %v", ok, err)
+		}
+		return s
+	}
+
+	before := stamp()
+	if stamp() != before {
+		t.Fatal("Expected the stamp to stay the same without changes")
+	}
+	// Saved through another store, as by another process
+	if err := addTasks(repos[1], "other", 1); err != nil {
+		t.Fatal(err)
+	}
+	after := stamp()
+	if after == before {
+		t.Error("Expected a save through another store to change the stamp")
+	}
+	// A change that keeps the file's size
+	err := repos[0].Update(context.Background(), func(tasks []core.Task) ([]core.Task, error) {
+		tasks[0].Priority = 2
+		return tasks, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stamp() == after {
+		t.Error("Expected a save of the same size to change the stamp")
+	}
+}
+
+func TestAuditRepository(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	l, err := store.NewAuditLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := NewJSONAuditRepository(l)
+	ctx := context.Background()
+	at := time.Date(2025, 10, 22, 9, 0, 0, 0, time.UTC)
+	entry := func(id int, action string) core.AuditEntry {
+		return core.AuditEntry{TaskID: id, At: at, Action: action, Event: core.AuditChanged}
+	}
+
+	if err := repo.AppendAudit(ctx, []core.AuditEntry{entry(1, "add"), entry(2, "add")}); err != nil {
+		t.Fatal(err)
+	}
+
+	// A crash in the middle of appending an entry
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(`{"task_id":1,"at":"2025-10-22T09:`); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	entries, err := repo.LoadAudit(ctx, 1)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Expected the entry before the torn one, got %+v, %v", entries, err)
+	}
+
+	// The next append drops the torn entry
+	if err := repo.AppendAudit(ctx, []core.AuditEntry{entry(1, "edit")}); err != nil {
+		t.Fatal(err)
+	}
+	entries, err = repo.LoadAudit(ctx, 1)
+	if err != nil || len(entries) != 2 || entries[1].Action != "edit" {
+		t.Errorf("Expected add and edit for #1, got %+v, %v", entries, err)
+	}
+	if lines, _ := l.Lines(); len(lines) != 3 {
+		t.Errorf("Expected 3 lines in the log, got %d", len(lines))
+	}
+}
@@ -3,8 +3,10 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,35 +14,26 @@ import (
 	"strings"
 	"time"
 
-	"godoit/internal/clock"
 	"godoit/internal/core"
-	"godoit/internal/repository"
 	"godoit/internal/service"
-	"godoit/internal/store"
 )
 
 // Server represents the HTTP API server
 type Server struct {
-    store  *store.JSONStore
 	mux    *http.ServeMux
 	server *http.Server
     svc    *service.TaskService
 }
 
-// NewServer creates a new HTTP server
-func NewServer(host string, port int, s *store.JSONStore) *Server {
+// NewServer creates a new HTTP server backed by the given task service
+func NewServer(host string, port int, svc *service.TaskService) *Server {
 	mux := http.NewServeMux()
 
-    // wire repository and service
-    repo := repository.NewJSONTaskRepository(s)
-	svc := service.NewTaskService(repo, clock.SystemClock{})
-
     srv := &Server{
-        store: s,
         mux:   mux,
         server: &http.Server{
 			Addr:         fmt.Sprintf("%s:%d", host, port),
-			Handler:      mux,
+			Handler:      withOrigin(mux),
 			ReadTimeout:  15 * time.Second,
 			WriteTimeout: 15 * time.Second,
 			IdleTimeout:  60 * time.Second,
@@ -58,6 +51,16 @@ func (s *Server) setupRoutes() {
 	s.mux.HandleFunc("/tasks", s.corsMiddleware(s.handleTasks))
 	s.mux.HandleFunc("/tasks/", s.corsMiddleware(s.handleTask))
 	s.mux.HandleFunc("/stats", s.corsMiddleware(s.handleStats))
+	s.mux.HandleFunc("/graph", s.corsMiddleware(s.handleGraph))
+	s.mux.HandleFunc("/projects", s.corsMiddleware(s.handleProjects))
+	s.mux.HandleFunc("/projects/", s.corsMiddleware(s.handleProject))
+	s.mux.HandleFunc("/views", s.corsMiddleware(s.handleViews))
+	s.mux.HandleFunc("/views/", s.corsMiddleware(s.handleView))
+	s.mux.HandleFunc("/search", s.corsMiddleware(s.handleSearch))
+	s.mux.HandleFunc("/undo", s.corsMiddleware(s.handleUndo))
+	s.mux.HandleFunc("/redo", s.corsMiddleware(s.handleUndo))
+	s.mux.HandleFunc("/trash", s.corsMiddleware(s.handleTrash))
+	s.mux.HandleFunc("/trash/", s.corsMiddleware(s.handleTrash))
 	s.mux.HandleFunc("/health", s.handleHealth)
 }
 
@@ -84,12 +87,28 @@ func (s *Server) Start() error {
 	return s.server.Shutdown(ctx)
 }
 
+// withOrigin marks the changes a request makes as coming over HTTP, for the
+// audit log: from the client's address, and from the user named in the
+// X-Godoit-User header. Nothing checks the header, so the user is only
+// what the client claims to be.
+func withOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		origin := core.Origin{Source: "http", User: r.Header.Get("X-Godoit-User"), Addr: host}
+		ctx := service.WithOrigin(r.Context(), origin)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
 // corsMiddleware adds CORS headers
 func (s *Server) corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, X-Godoit-User")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -104,14 +123,51 @@ func (s *Server) corsMiddleware(next http.HandlerFunc) http.HandlerFunc {
 func (s *Server) handleTasks(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case "GET":
-		s.listTasks(w, r)
+		s.listTasks(w, r, r.URL.Query().Get("project"))
 	case "POST":
-		s.createTask(w, r)
+		s.createTask(w, r, "")
 	default:
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
+// handleProjects lists the projects that have tasks
+func (s *Server) handleProjects(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	projects, err := s.svc.Projects(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, projects)
+}
+
+// handleProject handles /projects/:name/tasks (list and create) and
+// /projects/:name/stats
+func (s *Server) handleProject(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/projects/"), "/")
+	if len(parts) != 2 || parts[0] == "" {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+	project := parts[0]
+
+	switch {
+	case parts[1] == "tasks" && r.Method == "GET":
+		s.listTasks(w, r, project)
+	case parts[1] == "tasks" && r.Method == "POST":
+		s.createTask(w, r, project)
+	case parts[1] == "stats" && r.Method == "GET":
+		s.projectStats(w, r, project)
+	default:
+		http.Error(w, "Not found", http.StatusNotFound)
+	}
+}
+
 // handleTask handles /tasks/:id endpoint (get, update, delete)
 func (s *Server) handleTask(w http.ResponseWriter, r *http.Request) {
 	// Extract ID from path
@@ -123,6 +179,16 @@ func (s *Server) handleTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// /tasks/next is not a task ID
+	if parts[0] == "next" && len(parts) == 1 {
+		if r.Method != "GET" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		s.nextTasks(w, r)
+		return
+	}
+
 	id, err := strconv.Atoi(parts[0])
 	if err != nil {
 		http.Error(w, "Invalid task ID", http.StatusBadRequest)
@@ -135,6 +201,41 @@ func (s *Server) handleTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Status transitions: /tasks/:id/start, /tasks/:id/wait, /tasks/:id/cancel
+	if len(parts) > 1 && r.Method == "POST" {
+		if status, ok := statusActions[parts[1]]; ok {
+			s.setStatus(w, r, id, status)
+			return
+		}
+	}
+
+	// Time tracking: /tasks/:id/time, /tasks/:id/time/start, /tasks/:id/time/stop
+	if len(parts) > 1 && parts[1] == "time" {
+		s.handleTime(w, r, id, parts[2:])
+		return
+	}
+
+	// Subtasks (/tasks/:id/children), the audit log (/tasks/:id/history) and recurring task series: /tasks/:id/series, /tasks/:id/skip, /tasks/:id/stop
+	if len(parts) > 1 {
+		switch {
+		case parts[1] == "history" && r.Method == "GET":
+			s.getHistory(w, r, id)
+		case parts[1] == "critical-path" && r.Method == "GET":
+			s.getCriticalPath(w, r, id)
+		case parts[1] == "children" && r.Method == "GET":
+			s.getChildren(w, r, id)
+		case parts[1] == "series" && r.Method == "GET":
+			s.getSeries(w, r, id)
+		case parts[1] == "skip" && r.Method == "POST":
+			s.skipOccurrence(w, r, id)
+		case parts[1] == "stop" && r.Method == "POST":
+			s.stopSeries(w, r, id)
+		default:
+			http.Error(w, "Not found", http.StatusNotFound)
+		}
+		return
+	}
+
 	switch r.Method {
 	case "GET":
 		s.getTask(w, r, id)
@@ -147,20 +248,47 @@ func (s *Server) handleTask(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// listTasks returns all tasks with optional filtering
-func (s *Server) listTasks(w http.ResponseWriter, r *http.Request) {
+// listTasks returns the tasks in a project (empty for the default project)
+// with optional filtering
+func (s *Server) listTasks(w http.ResponseWriter, r *http.Request, project string) {
 	q := r.URL.Query()
 	showAll := q.Get("all") == "true"
+	deferred := q.Get("deferred") == "true"
 	grep := q.Get("grep")
 	tags := q.Get("tags")
 	sortKey := q.Get("sort")
-	if sortKey == "" { sortKey = "due" }
+	if _, err := core.ParseSort(sortKey); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	status := q.Get("status")
+	if _, err := core.FilterByStatusName(nil, status); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	filter := q.Get("q")
+	if filter != "" {
+		if _, err := core.ParseFilter(filter, s.svc.Now()); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
 	var beforePtr, afterPtr *time.Time
 	if bs := q.Get("before"); bs != "" {
-		if t, err := time.Parse("2006-01-02", bs); err == nil { beforePtr = &t }
+		t, err := s.svc.ParseDate(bs)
+		if err != nil {
+			http.Error(w, "Invalid before date: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		beforePtr = &t
 	}
 	if as := q.Get("after"); as != "" {
-		if t, err := time.Parse("2006-01-02", as); err == nil { afterPtr = &t }
+		t, err := s.svc.ParseDate(as)
+		if err != nil {
+			http.Error(w, "Invalid after date: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		afterPtr = &t
 	}
 	result, err := s.svc.QueryTasks(r.Context(), service.Query{
 		ShowAll: showAll,
@@ -169,35 +297,70 @@ func (s *Server) listTasks(w http.ResponseWriter, r *http.Request) {
 		Tags:    tags,
 		Before:  beforePtr,
 		After:   afterPtr,
+		Status:  status,
+		Project: project,
+		Deferred: deferred,
+		Filter:  filter,
 	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	respondJSON(w, result)
+	scored, err := s.svc.WithUrgency(r.Context(), result)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, scored)
 }
 
-// createTask creates a new task
-func (s *Server) createTask(w http.ResponseWriter, r *http.Request) {
+// createTask creates a new task, in project unless the body names one
+func (s *Server) createTask(w http.ResponseWriter, r *http.Request, project string) {
 	var input struct {
 		Title       string   `json:"title"`
 		Description string   `json:"description"`
 		Due         *string  `json:"due"`
+		Scheduled   *string  `json:"scheduled"`
 		Priority    int      `json:"priority"`
+		Estimate    string   `json:"estimate"`
 		Tags        []string `json:"tags"`
 		Repeat      string   `json:"repeat"`
+		RepeatFrom  string   `json:"repeat_from"`
+		SkipMissed  bool     `json:"skip_missed"`
 		DependsOn   []int    `json:"depends_on"`
+		ParentID    int      `json:"parent_id"`
+		AutoComplete bool    `json:"auto_complete"`
+		Project     string   `json:"project"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
 		http.Error(w, "Invalid JSON", http.StatusBadRequest)
 		return
 	}
+	if input.Project == "" {
+		input.Project = project
+	}
 
 	var due *time.Time
+	var timed bool
 	if input.Due != nil && *input.Due != "" {
-		if t, err := time.Parse("2006-01-02", *input.Due); err == nil { due = &t } else {
-			http.Error(w, "Invalid date format", http.StatusBadRequest)
+		if t, hasTime, err := s.svc.ParseDue(*input.Due); err == nil { due, timed = &t, hasTime } else {
+			http.Error(w, "Invalid date format: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	var scheduled *time.Time
+	var scheduledTimed bool
+	if input.Scheduled != nil && *input.Scheduled != "" {
+		if t, hasTime, err := s.svc.ParseDue(*input.Scheduled); err == nil { scheduled, scheduledTimed = &t, hasTime } else {
+			http.Error(w, "Invalid scheduled date: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	var estimate core.Estimate
+	if input.Estimate != "" {
+		if e, err := s.svc.ParseEstimate(input.Estimate); err == nil { estimate = e } else {
+			http.Error(w, "Invalid estimate: "+err.Error(), http.StatusBadRequest)
 			return
 		}
 	}
@@ -205,10 +368,19 @@ func (s *Server) createTask(w http.ResponseWriter, r *http.Request) {
 		Title:       input.Title,
 		Description: input.Description,
 		Due:         due,
+		Timed:       timed,
+		Scheduled:   scheduled,
+		ScheduledTimed: scheduledTimed,
 		Priority:    input.Priority,
+		Estimate:    estimate,
 		Tags:        input.Tags,
 		Repeat:      input.Repeat,
+		RepeatFrom:  input.RepeatFrom,
+		SkipMissed:  input.SkipMissed,
 		DependsOn:   input.DependsOn,
+		ParentID:    input.ParentID,
+		AutoComplete: input.AutoComplete,
+		Project:     input.Project,
 	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -217,14 +389,19 @@ func (s *Server) createTask(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, created)
 }
 
-// getTask returns a single task by ID
+// getTask returns a single task by ID, with its urgency
 func (s *Server) getTask(w http.ResponseWriter, r *http.Request, id int) {
 	task, err := s.svc.GetTask(r.Context(), id)
 	if err != nil {
 		http.Error(w, "Task not found", http.StatusNotFound)
 		return
 	}
-	respondJSON(w, task)
+	scored, err := s.svc.WithUrgency(r.Context(), []core.Task{task})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, scored[0])
 }
 
 // updateTask updates an existing task
@@ -233,10 +410,18 @@ func (s *Server) updateTask(w http.ResponseWriter, r *http.Request, id int) {
 		Title       *string   `json:"title"`
 		Description *string   `json:"description"`
 		Due         *string   `json:"due"`
+		Scheduled   *string   `json:"scheduled"`
 		Priority    *int      `json:"priority"`
+		Estimate    *string   `json:"estimate"`
 		Tags        *[]string `json:"tags"`
 		Repeat      *string   `json:"repeat"`
+		RepeatFrom  *string   `json:"repeat_from"`
+		SkipMissed  *bool     `json:"skip_missed"`
 		DependsOn   *[]int    `json:"depends_on"`
+		ParentID    *int      `json:"parent_id"`
+		AutoComplete *bool    `json:"auto_complete"`
+		Status      *string   `json:"status"`
+		Project     *string   `json:"project"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
@@ -244,14 +429,29 @@ func (s *Server) updateTask(w http.ResponseWriter, r *http.Request, id int) {
 		return
 	}
 
+	scope, err := core.ParseEditScope(r.URL.Query().Get("scope"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	updated, err := s.svc.UpdateTask(r.Context(), id, service.UpdateTaskInput{
 		Title:       input.Title,
 		Description: input.Description,
 		Due:         input.Due,
+		Scheduled:   input.Scheduled,
 		Priority:    input.Priority,
+		Estimate:    input.Estimate,
 		Tags:        input.Tags,
 		Repeat:      input.Repeat,
+		RepeatFrom:  input.RepeatFrom,
+		SkipMissed:  input.SkipMissed,
 		DependsOn:   input.DependsOn,
+		ParentID:    input.ParentID,
+		AutoComplete: input.AutoComplete,
+		Scope:       scope,
+		Status:      input.Status,
+		Project:     input.Project,
 	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -262,17 +462,57 @@ func (s *Server) updateTask(w http.ResponseWriter, r *http.Request, id int) {
 
 // deleteTask deletes a task by ID
 func (s *Server) deleteTask(w http.ResponseWriter, r *http.Request, id int) {
-	if err := s.svc.DeleteTaskByID(r.Context(), id); err != nil {
-		if err.Error() == "task not found" {
-			http.Error(w, "Task not found", http.StatusNotFound)
+	query := r.URL.Query()
+	permanent := query.Get("permanent") == "true"
+	deps := query.Get("deps")
+	if deps == "" && !permanent {
+		deps = string(core.RemoveKeep)
+	}
+	policy, err := core.ParseRemovePolicy(deps)
+	if err == nil && permanent && policy == core.RemoveKeep {
+		err = fmt.Errorf("deps=keep only applies without permanent=true")
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !permanent {
+		trashed, dependents, err := s.svc.TrashTask(r.Context(), id, policy)
+		if err != nil {
+			s.removeError(w, err)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		if dependents == nil {
+			dependents = []core.Task{}
+		}
+		respondJSON(w, map[string]interface{}{"trashed": trashed, "dependents": dependents})
+		return
+	}
+
+	if _, err := s.svc.DeleteTask(r.Context(), id, policy); err != nil {
+		s.removeError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// removeError reports an error deleting a task
+func (s *Server) removeError(w http.ResponseWriter, err error) {
+	var notFound *core.TaskNotFoundError
+	var trashed *core.TrashedError
+	if errors.As(err, &notFound) || errors.As(err, &trashed) {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	var dependents *core.DependentsError
+	if errors.As(err, &dependents) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
 // markDone marks a task as complete
 func (s *Server) markDone(w http.ResponseWriter, r *http.Request, id int) {
 	updated, err := s.svc.MarkDoneByID(r.Context(), id)
@@ -283,6 +523,210 @@ func (s *Server) markDone(w http.ResponseWriter, r *http.Request, id int) {
 	respondJSON(w, updated)
 }
 
+// statusActions maps POST /tasks/:id/<action> to the status it moves the
+// task to
+var statusActions = map[string]core.Status{
+	"start":  core.StatusInProgress,
+	"wait":   core.StatusWaiting,
+	"cancel": core.StatusCancelled,
+}
+
+// setStatus moves a task to a new status
+func (s *Server) setStatus(w http.ResponseWriter, r *http.Request, id int, status core.Status) {
+	updated, err := s.svc.SetStatus(r.Context(), id, status)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	respondJSON(w, updated)
+}
+
+// handleTime handles a task's time tracking: GET lists its time entries,
+// POST logs time spent ({"duration": "45m", "note": "..."}), and POST to
+// /start or /stop controls the timer. Each responds with the time summary.
+func (s *Server) handleTime(w http.ResponseWriter, r *http.Request, id int, rest []string) {
+	if len(rest) > 1 || (len(rest) == 1 && rest[0] != "start" && rest[0] != "stop") {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	var task core.Task
+	var err error
+	switch {
+	case len(rest) == 0 && r.Method == "GET":
+		summary, err := s.svc.TimeSummary(r.Context(), id)
+		if err != nil {
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return
+		}
+		respondJSON(w, summary)
+		return
+	case r.Method != "POST":
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	case len(rest) == 0:
+		var input struct {
+			Duration string `json:"duration"`
+			Note     string `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		d, perr := core.ParseTimeSpent(input.Duration)
+		if perr != nil {
+			http.Error(w, perr.Error(), http.StatusBadRequest)
+			return
+		}
+		task, err = s.svc.LogTime(r.Context(), id, d, input.Note)
+	case rest[0] == "start":
+		task, err = s.svc.StartTimer(r.Context(), id)
+	default:
+		task, err = s.svc.StopTimer(r.Context(), id)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	respondJSON(w, task.TimeSummary(s.svc.Now()))
+}
+
+// nextTasks recommends what to work on next (?n=, default 1; ?project=)
+func (s *Server) nextTasks(w http.ResponseWriter, r *http.Request) {
+	n := 1
+	if ns := r.URL.Query().Get("n"); ns != "" {
+		v, err := strconv.Atoi(ns)
+		if err != nil || v < 0 {
+			http.Error(w, "Invalid n", http.StatusBadRequest)
+			return
+		}
+		n = v
+	}
+
+	recs, err := s.svc.Recommend(r.Context(), n, r.URL.Query().Get("project"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if recs == nil {
+		recs = []core.Recommendation{}
+	}
+	respondJSON(w, recs)
+}
+
+// getCriticalPath returns the critical path to a task
+func (s *Server) getCriticalPath(w http.ResponseWriter, r *http.Request, id int) {
+	cp, err := s.svc.CriticalPath(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+	respondJSON(w, cp)
+}
+
+// getChildren returns the direct subtasks of a task with its progress
+func (s *Server) getChildren(w http.ResponseWriter, r *http.Request, id int) {
+	children, err := s.svc.ChildrenOf(r.Context(), id)
+	if err != nil {
+		http.Error(w, "Task not found", http.StatusNotFound)
+		return
+	}
+
+	done, total := 0, 0
+	for _, c := range children {
+		switch c.State() {
+		case core.StatusCancelled:
+			continue
+		case core.StatusDone:
+			done++
+		}
+		total++
+	}
+	if children == nil {
+		children = []core.Task{}
+	}
+
+	respondJSON(w, map[string]interface{}{
+		"id":       id,
+		"done":     done,
+		"total":    total,
+		"children": children,
+	})
+}
+
+// getSeries returns all occurrences of a recurring task, oldest first
+func (s *Server) getSeries(w http.ResponseWriter, r *http.Request, id int) {
+	series, err := s.svc.SeriesOf(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	respondJSON(w, series)
+}
+
+// getHistory returns the audit log of a task, oldest first
+func (s *Server) getHistory(w http.ResponseWriter, r *http.Request, id int) {
+	entries, err := s.svc.TaskHistory(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if entries == nil {
+		entries = []core.AuditEntry{}
+	}
+	respondJSON(w, entries)
+}
+
+// skipOccurrence skips one occurrence and returns the next one (null when
+// the series has ended)
+func (s *Server) skipOccurrence(w http.ResponseWriter, r *http.Request, id int) {
+	next, err := s.svc.SkipByID(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	respondJSON(w, map[string]interface{}{"next": next})
+}
+
+// stopSeries ends a recurring task's series
+func (s *Server) stopSeries(w http.ResponseWriter, r *http.Request, id int) {
+	stopped, err := s.svc.StopSeries(r.Context(), id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	respondJSON(w, stopped)
+}
+
+// handleGraph renders the dependency graph (?format=dot|mermaid|ascii,
+// ?tags=, ?status=, ?project=)
+func (s *Server) handleGraph(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	format, err := core.ParseGraphFormat(q.Get("format"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	graph, err := s.svc.Graph(r.Context(), format, q.Get("tags"), q.Get("status"), q.Get("project"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	contentType := "text/plain; charset=utf-8"
+	if format == core.GraphDOT {
+		contentType = "text/vnd.graphviz; charset=utf-8"
+	}
+	w.Header().Set("Content-Type", contentType)
+	_, _ = w.Write([]byte(graph))
+}
+
 // handleStats returns task statistics
 func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 	if r.Method != "GET" {
@@ -290,16 +734,225 @@ func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tasks, err := s.svc.QueryTasks(r.Context(), service.Query{ShowAll: true, SortKey: "due"})
+	s.projectStats(w, r, r.URL.Query().Get("project"))
+}
+
+// projectStats returns statistics for the tasks in a project
+func (s *Server) projectStats(w http.ResponseWriter, r *http.Request, project string) {
+	stats, err := s.svc.Stats(r.Context(), project)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-
-	stats := core.CalculateStats(tasks, time.Now())
 	respondJSON(w, stats)
 }
 
+// handleSearch searches tasks by words (?q=, required; ?limit=, default 50,
+// 0 for every match; ?open=true; ?project=)
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	q := r.URL.Query()
+	query := q.Get("q")
+	if _, err := core.ParseSearchQuery(query); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := 50
+	if ls := q.Get("limit"); ls != "" {
+		v, err := strconv.Atoi(ls)
+		if err != nil || v < 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = v
+	}
+
+	results, err := s.svc.Search(r.Context(), query, service.SearchOptions{
+		Open:    q.Get("open") == "true",
+		Project: q.Get("project"),
+		Limit:   limit,
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, results)
+}
+
+// handleTrash handles /trash: GET lists the deleted tasks, most recent
+// first, DELETE empties the trash, and POST /trash/:id/restore restores a task
+func (s *Server) handleTrash(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/trash"), "/")
+	if path != "" {
+		parts := strings.Split(path, "/")
+		id, err := strconv.Atoi(parts[0])
+		if err != nil {
+			http.Error(w, "Invalid task ID", http.StatusBadRequest)
+			return
+		}
+		if len(parts) != 2 || parts[1] != "restore" {
+			http.Error(w, "Not found", http.StatusNotFound)
+			return
+		}
+		if r.Method != "POST" {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		restored, err := s.svc.RestoreTask(r.Context(), id)
+		var notFound *core.TaskNotFoundError
+		var notTrashed *core.NotInTrashError
+		switch {
+		case errors.As(err, &notFound):
+			http.Error(w, "Task not found", http.StatusNotFound)
+			return
+		case errors.As(err, &notTrashed):
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, restored)
+		return
+	}
+
+	var tasks []core.Task
+	var err error
+	switch r.Method {
+	case "GET":
+		tasks, err = s.svc.Trash(r.Context())
+	case "DELETE":
+		tasks, err = s.svc.EmptyTrash(r.Context())
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if tasks == nil {
+		tasks = []core.Task{}
+	}
+	respondJSON(w, tasks)
+}
+
+// handleUndo handles /undo and /redo: POST undoes the last operation, or
+// redoes the last undone one, and returns it; GET /undo lists the history
+func (s *Server) handleUndo(w http.ResponseWriter, r *http.Request) {
+	redo := r.URL.Path == "/redo"
+	switch {
+	case r.Method == "GET" && !redo:
+		ops, err := s.svc.UndoHistory(r.Context())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if ops == nil {
+			ops = []core.Operation{}
+		}
+		respondJSON(w, ops)
+	case r.Method == "POST":
+		step := s.svc.Undo
+		if redo {
+			step = s.svc.Redo
+		}
+		op, err := step(r.Context())
+		var conflict *core.UndoConflictError
+		switch {
+		case errors.Is(err, core.ErrNothingToUndo), errors.Is(err, core.ErrNothingToRedo), errors.As(err, &conflict):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			respondJSON(w, op)
+		}
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleViews lists the saved views
+func (s *Server) handleViews(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	views, err := s.svc.Views(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	respondJSON(w, views)
+}
+
+// viewResult is a saved view with the tasks it shows
+type viewResult struct {
+	View  core.View         `json:"view"`
+	Tasks []core.ScoredTask `json:"tasks"`
+}
+
+// handleView handles /views/:name: GET runs the view, PUT saves it and
+// DELETE removes it
+func (s *Server) handleView(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/views/")
+	if name == "" || strings.Contains(name, "/") {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		view, tasks, err := s.svc.RunView(r.Context(), name)
+		if err != nil {
+			viewError(w, err)
+			return
+		}
+		scored, err := s.svc.WithUrgency(r.Context(), tasks)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		respondJSON(w, viewResult{View: view, Tasks: scored})
+	case "PUT":
+		var view core.View
+		if err := json.NewDecoder(r.Body).Decode(&view); err != nil {
+			http.Error(w, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+		view.Name = name
+		saved, err := s.svc.SaveView(r.Context(), view)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		respondJSON(w, saved)
+	case "DELETE":
+		if err := s.svc.DeleteView(r.Context(), name); err != nil {
+			viewError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// viewError reports a failure to load or run a view: 404 for an unknown view
+func viewError(w http.ResponseWriter, err error) {
+	var notFound *core.ViewNotFoundError
+	if errors.As(err, &notFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	http.Error(w, err.Error(), http.StatusInternalServerError)
+}
+
 // handleHealth returns health status
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, map[string]string{
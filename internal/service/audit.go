@@ -0,0 +1,62 @@
+package service
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "godoit/internal/core"
+    "godoit/internal/repository"
+)
+
+type originKey struct{}
+
+// WithOrigin returns a context for changes made by origin, so that they are
+// recorded as such in the audit log
+func WithOrigin(ctx context.Context, origin core.Origin) context.Context {
+    return context.WithValue(ctx, originKey{}, origin)
+}
+
+// SetAuditRepository sets where the audit log of task changes is kept.
+// Without one, changes are not logged.
+func (s *TaskService) SetAuditRepository(r repository.AuditRepository) {
+    s.audit = r
+}
+
+// SetOrigin sets who or what changes are made by when the context does not
+// say (see WithOrigin)
+func (s *TaskService) SetOrigin(origin core.Origin) {
+    s.origin = origin
+}
+
+func (s *TaskService) originOf(ctx context.Context) core.Origin {
+    if o, ok := ctx.Value(originKey{}).(core.Origin); ok { return o }
+    return s.origin
+}
+
+// logAudit appends the entries for a saved operation to the audit log
+func (s *TaskService) logAudit(ctx context.Context, op core.Operation) error {
+    if s.audit == nil || len(op.Changes) == 0 { return nil }
+    err := s.audit.AppendAudit(ctx, core.AuditEntries(op, s.originOf(ctx)))
+    if err != nil { return fmt.Errorf("change saved but not recorded in the audit log: %w", err) }
+    return nil
+}
+
+// TaskHistory returns the audit log of a task, oldest first. It includes
+// removed tasks, the last one to have the ID if it was given out again; a
+// task that never existed is an error.
+func (s *TaskService) TaskHistory(ctx context.Context, id int) ([]core.AuditEntry, error) {
+    if s.audit == nil { return nil, fmt.Errorf("task history is not available") }
+    entries, err := s.audit.LoadAudit(ctx, id)
+    if err != nil { return nil, err }
+    tasks, err := s.repo.LoadTasks(ctx)
+    if err != nil { return nil, err }
+    var created time.Time
+    if t, err := core.GetByID(tasks, id); err == nil { created = t.CreatedAt }
+    entries = core.TaskAudit(entries, created)
+    if len(entries) == 0 {
+        // Tasks from before the audit log have no entries yet
+        if _, err := s.GetTask(ctx, id); err != nil { return nil, err }
+    }
+    return entries, nil
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+    "context"
+    "sync"
+
+    "godoit/internal/core"
+    "godoit/internal/repository"
+)
+
+type SearchOptions struct {
+    Open    bool   // only open tasks
+    Project string // empty for every project; otherwise a project or "none" (see core.FilterByProject)
+    Limit   int    // 0 for every match
+}
+
+// searchCache keeps the search index between searches, with the stamp of
+// the tasks it was built from (see repository.StampedRepository)
+type searchCache struct {
+    mu    sync.Mutex
+    stamp string
+    index *core.SearchIndex
+}
+
+// Search looks for tasks by words in their title, description, tags and
+// time entry notes (see core.ParseSearchQuery), most relevant first. Closed
+// tasks are included unless opts.Open is set.
+func (s *TaskService) Search(ctx context.Context, query string, opts SearchOptions) ([]core.SearchResult, error) {
+    q, err := core.ParseSearchQuery(query)
+    if err != nil { return nil, err }
+    ix, err := s.searchIndex(ctx)
+    if err != nil { return nil, err }
+    return ix.SearchMatching(q, opts.Limit, func(t core.Task) bool {
+        if opts.Open && t.IsDone() { return false }
+        return opts.Project == "" || t.InProject(opts.Project)
+    }), nil
+}
+
+// searchIndex returns the search index over the tasks, building it again
+// only when they have changed since the last search. Repositories that
+// cannot tell get a new index every time.
+func (s *TaskService) searchIndex(ctx context.Context) (*core.SearchIndex, error) {
+    var stamp string
+    stamped := false
+    if r, ok := s.repo.(repository.StampedRepository); ok {
+        var err error
+        stamp, stamped, err = r.Stamp(ctx)
+        if err != nil { return nil, err }
+    }
+
+    s.search.mu.Lock()
+    defer s.search.mu.Unlock()
+    if stamped && s.search.index != nil && s.search.stamp == stamp { return s.search.index, nil }
+    // The stamp is taken before loading, so a change made in between only
+    // causes another rebuild
+    tasks, err := s.loadTasks(ctx)
+    if err != nil { return nil, err }
+    ix := core.NewSearchIndex(tasks)
+    s.search.stamp, s.search.index = stamp, nil
+    if stamped { s.search.index = ix }
+    return ix, nil
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"godoit/internal/clock"
@@ -14,159 +15,560 @@ type AddTaskInput struct {
     Title       string
     Description string
     Due         *time.Time
+    Timed       bool // Due includes a time of day; otherwise it is an all-day date
+    Scheduled   *time.Time // hide the task until then
+    ScheduledTimed bool
     Priority    int
+    Estimate    core.Estimate // see TaskService.ParseEstimate
     Tags        []string
     Repeat      string
+    RepeatFrom  string // "due" (default) or "completion"
+    SkipMissed  bool
     DependsOn   []int
+    ParentID    int  // make the task a subtask of this task
+    AutoComplete bool // complete the task once all its subtasks are done
+    Project     string // empty: the parent's project for subtasks, otherwise the default project
 }
 
 type UpdateTaskInput struct {
     Title       *string
     Description *string
-    Due         *string // YYYY-MM-DD or empty to clear
+    Due         *string // date expression (see core.ParseDate) or empty to clear
+    Scheduled   *string // date expression or empty to clear
     Priority    *int
+    Estimate    *string // duration or story points (see core.ParseEstimate) or empty to clear
     Tags        *[]string
     Repeat      *string
+    RepeatFrom  *string
+    SkipMissed  *bool
     DependsOn   *[]int
+    ParentID    *int // 0 makes the task top-level
+    AutoComplete *bool
+    Scope       core.EditScope // for recurring tasks: this occurrence (default) or future ones too
+    Status      *string // see core.ParseStatus; applied after the other edits
+    Project     *string // moves the task and its subtasks; "none" or empty for no project
 }
 
 type Query struct {
     ShowAll bool
     Grep    string
-    SortKey string
+    SortKey string // sort expression, e.g. "priority:desc,due" (see core.ParseSort)
     Tags    string // raw form; reused from existing semantics
     Before  *time.Time
     After   *time.Time
+    Status  string // open, all or a task status (see core.FilterByStatusName); overrides ShowAll
+    Project string // empty for the default project; "all" or "none" (see core.FilterByProject)
+    Deferred bool // include tasks scheduled to start later (always included with ShowAll or Status)
+    Scheduled bool // Before/After also match tasks scheduled in the range, deferred or not
+    Filter  string // filter expression (see core.ParseFilter); closed tasks, deferred tasks and other projects are included when it tests for them
 }
 
 type TaskService struct {
     repo  repository.TaskRepository
     clock clock.Clock
+    defaultProject string
+    storyPoint time.Duration
+    urgency core.UrgencyWeights
+    views  repository.ViewRepository // nil until SetViewRepository
+    undo   repository.UndoRepository // nil until SetUndoRepository
+    audit  repository.AuditRepository // nil until SetAuditRepository
+    origin core.Origin // who changes are made by when the context does not say
+    trashRetention time.Duration // how long tasks stay in the trash; 0 for ever
+    search searchCache // the search index as of the last search
 }
 
 func NewTaskService(repo repository.TaskRepository, clk clock.Clock) *TaskService {
-    return &TaskService{repo: repo, clock: clk}
+    return &TaskService{repo: repo, clock: clk, storyPoint: core.DefaultStoryPoint, urgency: core.DefaultUrgencyWeights(), trashRetention: core.DefaultTrashRetention}
+}
+
+// SetUrgencyWeights sets the weights of the urgency score
+func (s *TaskService) SetUrgencyWeights(w core.UrgencyWeights) {
+    s.urgency = w
+}
+
+// scorer scores urgency now, counting blocked tasks among all
+func (s *TaskService) scorer(all []core.Task) core.UrgencyScorer {
+    return core.UrgencyScorer{Weights: s.urgency, All: all, Now: s.clock.Now()}
+}
+
+// WithUrgency pairs each of tasks with its current urgency
+func (s *TaskService) WithUrgency(ctx context.Context, tasks []core.Task) ([]core.ScoredTask, error) {
+    all, err := s.loadTasks(ctx)
+    if err != nil { return nil, err }
+    return s.scorer(all).ScoreAll(tasks), nil
+}
+
+// SetStoryPoint sets the effort one story point of an estimate stands for
+func (s *TaskService) SetStoryPoint(d time.Duration) {
+    s.storyPoint = d
+}
+
+// ParseEstimate parses an estimate such as "2h" or "3pt", converting story
+// points at the configured size
+func (s *TaskService) ParseEstimate(expr string) (core.Estimate, error) {
+    return core.ParseEstimate(expr, s.storyPoint)
+}
+
+// SetDefaultProject sets the project new tasks go to, and that listings are
+// limited to, when no project is given. Empty means no project for new tasks
+// and all projects for listings.
+func (s *TaskService) SetDefaultProject(name string) error {
+    name, err := core.NormalizeProject(name)
+    if err != nil { return err }
+    s.defaultProject = name
+    return nil
+}
+
+// DefaultProject returns the configured default project
+func (s *TaskService) DefaultProject() string {
+    return s.defaultProject
+}
+
+// selectProject resolves the project a listing is limited to
+func (s *TaskService) selectProject(project string) string {
+    if strings.TrimSpace(project) == "" { return s.defaultProject }
+    return project
+}
+
+// projectName validates the project tasks are put in; "none" means no project
+func projectName(project string) (string, error) {
+    if strings.EqualFold(strings.TrimSpace(project), core.ProjectNone) { return "", nil }
+    return core.NormalizeProject(project)
+}
+
+// Now returns the current time in the user's time zone
+func (s *TaskService) Now() time.Time {
+    return s.clock.Now()
+}
+
+// ParseDate resolves a date expression such as "tomorrow 14:30" or
+// "next friday" against the service clock
+func (s *TaskService) ParseDate(expr string) (time.Time, error) {
+    return core.ParseDate(expr, s.clock.Now())
+}
+
+// ParseDue is ParseDate that also reports whether a time of day was given
+func (s *TaskService) ParseDue(expr string) (time.Time, bool, error) {
+    return core.ParseDue(expr, s.clock.Now())
 }
 
 func (s *TaskService) AddTask(ctx context.Context, in AddTaskInput) (core.Task, error) {
     if in.Title == "" {
         return core.Task{}, fmt.Errorf("title is required")
     }
-    tasks, err := s.repo.LoadTasks(ctx)
+    var created core.Task
+    err := s.update(ctx, "add", func(tasks []core.Task) ([]core.Task, error) {
+        // use injected clock for deterministic CreatedAt
+        now := s.clock.Now()
+        tasks = core.AddAt(tasks, in.Title, in.Due, now)
+        t := &tasks[len(tasks)-1]
+        t.Timed = in.Due != nil && in.Timed
+        t.Scheduled = in.Scheduled
+        t.ScheduledTimed = in.Scheduled != nil && in.ScheduledTimed
+        t.Description = in.Description
+        t.Priority = core.NormalizePriority(in.Priority)
+        t.Estimate = in.Estimate
+        t.Tags = in.Tags
+        repeat, err := core.NormalizeRepeat(in.Repeat)
+        if err != nil { return nil, err }
+        t.Repeat = repeat
+        from, err := core.NormalizeRepeatFrom(in.RepeatFrom)
+        if err != nil { return nil, err }
+        t.RepeatFrom = from
+        t.SkipMissed = in.SkipMissed
+        if err := requireLive(tasks, append([]int{in.ParentID}, in.DependsOn...)...); err != nil { return nil, err }
+        if err := core.ValidateDependencies(tasks, t.ID, in.DependsOn); err != nil { return nil, err }
+        t.DependsOn = in.DependsOn
+        t.AutoComplete = in.AutoComplete
+        project, err := projectName(in.Project)
+        if err != nil { return nil, err }
+        if in.Project == "" { project = s.defaultProject }
+        if in.ParentID != 0 {
+            if err := core.SetParent(tasks, t.ID, in.ParentID); err != nil { return nil, err }
+            if parent, _ := core.GetByID(tasks, in.ParentID); in.Project == "" { project = parent.Project }
+        }
+        t.Project = project
+        created = *t
+        return tasks, nil
+    })
     if err != nil { return core.Task{}, err }
-
-    // use injected clock for deterministic CreatedAt
-    now := s.clock.Now()
-    tasks = core.AddAt(tasks, in.Title, in.Due, now)
-    t := &tasks[len(tasks)-1]
-    t.Description = in.Description
-    t.Priority = core.NormalizePriority(in.Priority)
-    t.Tags = in.Tags
-    t.Repeat = core.NormalizeRepeat(in.Repeat)
-    t.DependsOn = in.DependsOn
-
-    if err := s.repo.SaveTasks(ctx, tasks); err != nil { return core.Task{}, err }
-    return *t, nil
+    return created, nil
 }
 
 func (s *TaskService) UpdateTask(ctx context.Context, id int, in UpdateTaskInput) (core.Task, error) {
-    tasks, err := s.repo.LoadTasks(ctx)
+    var updated core.Task
+    err := s.update(ctx, "edit", func(tasks []core.Task) ([]core.Task, error) {
+        task, err := core.GetLiveByID(tasks, id)
+        if err != nil { return nil, err }
+
+        // Resolve inputs once so every edited occurrence gets the same values
+        var due *time.Time
+        var timed bool
+        if in.Due != nil && *in.Due != "" {
+            t, hasTime, err := s.ParseDue(*in.Due)
+            if err != nil { return nil, fmt.Errorf("invalid due date: %w", err) }
+            due, timed = &t, hasTime
+        }
+        var scheduled *time.Time
+        var scheduledTimed bool
+        if in.Scheduled != nil && *in.Scheduled != "" {
+            t, hasTime, err := s.ParseDue(*in.Scheduled)
+            if err != nil { return nil, fmt.Errorf("invalid scheduled date: %w", err) }
+            scheduled, scheduledTimed = &t, hasTime
+        }
+        var estimate core.Estimate
+        if in.Estimate != nil && *in.Estimate != "" {
+            if estimate, err = s.ParseEstimate(*in.Estimate); err != nil { return nil, err }
+        }
+        var repeat string
+        if in.Repeat != nil {
+            if repeat, err = core.NormalizeRepeat(*in.Repeat); err != nil { return nil, err }
+        }
+        if in.DependsOn != nil {
+            if err := requireLive(tasks, *in.DependsOn...); err != nil { return nil, err }
+            if err := core.ValidateDependencies(tasks, id, *in.DependsOn); err != nil { return nil, err }
+        }
+        if in.ParentID != nil {
+            if err := requireLive(tasks, *in.ParentID); err != nil { return nil, err }
+        }
+        var from core.RepeatAnchor
+        if in.RepeatFrom != nil {
+            if from, err = core.NormalizeRepeatFrom(*in.RepeatFrom); err != nil { return nil, err }
+        }
+        var project string
+        if in.Project != nil {
+            if project, err = projectName(*in.Project); err != nil { return nil, err }
+        }
+        var status core.Status
+        if in.Status != nil {
+            if status, err = core.ParseStatus(*in.Status); err != nil { return nil, err }
+        }
+
+        // Occurrence fields go to this occurrence only, unless the edit is for
+        // future occurrences too, in which case the series pattern changes as well
+        targets := []*core.Task{task}
+        if in.Scope == core.ScopeFuture {
+            if task.Pattern != nil { targets = append(targets, task.Pattern) }
+        } else if in.editsOccurrence() {
+            task.Detach()
+        }
+        for _, t := range targets {
+            if in.Title != nil { t.Title = *in.Title }
+            if in.Description != nil { t.Description = *in.Description }
+            if in.Due != nil { t.Due, t.Timed = due, timed }
+            if in.Scheduled != nil { t.Scheduled, t.ScheduledTimed = scheduled, scheduledTimed }
+            if in.Priority != nil {
+                t.Priority = core.NormalizePriority(*in.Priority)
+            }
+            if in.Estimate != nil { t.Estimate = estimate }
+            if in.Tags != nil { t.Tags = *in.Tags }
+            if in.DependsOn != nil { t.DependsOn = *in.DependsOn }
+        }
+
+        // Recurrence settings always apply to the whole series
+        for _, t := range []*core.Task{task, task.Pattern} {
+            if t == nil { continue }
+            if in.Repeat != nil { t.Repeat = repeat }
+            if in.RepeatFrom != nil { t.RepeatFrom = from }
+            if in.SkipMissed != nil { t.SkipMissed = *in.SkipMissed }
+        }
+        if task.Repeat == "" { task.Pattern = nil }
+
+        // So does the task's place in the tree
+        if in.ParentID != nil {
+            if err := core.SetParent(tasks, task.ID, *in.ParentID); err != nil { return nil, err }
+            if task.Pattern != nil { task.Pattern.ParentID = task.ParentID }
+        }
+        if in.AutoComplete != nil {
+            task.AutoComplete = *in.AutoComplete
+            if task.Pattern != nil { task.Pattern.AutoComplete = task.AutoComplete }
+        }
+        if in.Project != nil {
+            if err := core.MoveToProject(tasks, task.ID, project); err != nil { return nil, err }
+        }
+
+        updated = *task
+        if tasks, err = core.Update(tasks, updated); err != nil { return nil, err }
+
+        // Changing the status may complete the task and spawn its next
+        // occurrence, so it comes last
+        if in.Status != nil {
+            if tasks, err = core.SetStatus(tasks, id, status, s.clock.Now()); err != nil { return nil, err }
+            t, _ := core.GetByID(tasks, id)
+            updated = *t
+        }
+        return tasks, nil
+    })
     if err != nil { return core.Task{}, err }
-    task, err := core.GetByID(tasks, id)
+    return updated, nil
+}
+
+// SetStatus moves the task with the given ID to a new status
+func (s *TaskService) SetStatus(ctx context.Context, id int, status core.Status) (core.Task, error) {
+    var updated core.Task
+    err := s.update(ctx, "status "+string(status), func(tasks []core.Task) ([]core.Task, error) {
+        if err := requireLive(tasks, id); err != nil { return nil, err }
+        tasks, err := core.SetStatus(tasks, id, status, s.clock.Now())
+        if err != nil { return nil, err }
+        t, _ := core.GetByID(tasks, id)
+        updated = *t
+        return tasks, nil
+    })
     if err != nil { return core.Task{}, err }
+    return updated, nil
+}
 
-    if in.Title != nil { task.Title = *in.Title }
-    if in.Description != nil { task.Description = *in.Description }
-    if in.Due != nil {
-        if *in.Due == "" { task.Due = nil } else if t, err := time.Parse("2006-01-02", *in.Due); err == nil { task.Due = &t } else { return core.Task{}, fmt.Errorf("invalid due date") }
-    }
-    if in.Priority != nil {
-        task.Priority = core.NormalizePriority(*in.Priority)
+// editsOccurrence reports whether the input changes per-occurrence fields
+func (in UpdateTaskInput) editsOccurrence() bool {
+    return in.Title != nil || in.Description != nil || in.Due != nil || in.Scheduled != nil ||
+        in.Priority != nil || in.Estimate != nil || in.Tags != nil || in.DependsOn != nil
+}
+
+// ChildrenOf returns the direct subtasks of the task with the given ID
+func (s *TaskService) ChildrenOf(ctx context.Context, id int) ([]core.Task, error) {
+    tasks, err := s.loadTasks(ctx)
+    if err != nil { return nil, err }
+    if _, err := core.GetByID(tasks, id); err != nil { return nil, err }
+    return core.ChildrenOf(tasks, id), nil
+}
+
+// Graph renders the dependency graph of the tasks in project matching tags
+// and status (see core.FilterByStatusName)
+func (s *TaskService) Graph(ctx context.Context, format core.GraphFormat, tags, status, project string) (string, error) {
+    all, err := s.loadTasks(ctx)
+    if err != nil { return "", err }
+    selected, err := core.FilterByStatusName(all, status)
+    if err != nil { return "", err }
+    selected = core.FilterByProject(selected, s.selectProject(project))
+    selected = core.FilterByTags(selected, tags)
+    return core.RenderGraph(format, selected, all, s.clock.Now())
+}
+
+// Recommend returns up to n tasks in project that can be worked on now, best
+// first (all of them when n <= 0)
+func (s *TaskService) Recommend(ctx context.Context, n int, project string) ([]core.Recommendation, error) {
+    tasks, err := s.loadTasks(ctx)
+    if err != nil { return nil, err }
+    project = s.selectProject(project)
+    var recs []core.Recommendation
+    for _, rec := range core.RankActionable(tasks, s.clock.Now()) {
+        if rec.Task.InProject(project) { recs = append(recs, rec) }
     }
-    if in.Tags != nil { task.Tags = *in.Tags }
-    if in.Repeat != nil { task.Repeat = core.NormalizeRepeat(*in.Repeat) }
-    if in.DependsOn != nil { task.DependsOn = *in.DependsOn }
+    if n > 0 && len(recs) > n { recs = recs[:n] }
+    return recs, nil
+}
 
-    tasks, err = core.Update(tasks, *task)
+// CriticalPath returns the longest chain of open work leading to the task
+// with the given ID, counting tasks without an estimate as core.DefaultEstimate
+func (s *TaskService) CriticalPath(ctx context.Context, id int) (core.CriticalPath, error) {
+    tasks, err := s.loadTasks(ctx)
+    if err != nil { return core.CriticalPath{}, err }
+    return core.FindCriticalPath(tasks, id, s.clock.Now(), core.EstimateOrDefault)
+}
+
+// SeriesOf returns all occurrences of the recurring task with the given ID
+func (s *TaskService) SeriesOf(ctx context.Context, id int) ([]core.Task, error) {
+    tasks, err := s.loadTasks(ctx)
+    if err != nil { return nil, err }
+    return core.SeriesOf(tasks, id)
+}
+
+// SkipByID skips one occurrence of a recurring task and returns the next
+// occurrence, or nil if the series has ended
+func (s *TaskService) SkipByID(ctx context.Context, id int) (*core.Task, error) {
+    var next *core.Task
+    err := s.update(ctx, "skip", func(tasks []core.Task) ([]core.Task, error) {
+        if err := requireLive(tasks, id); err != nil { return nil, err }
+        tasks, spawned, err := core.SkipOccurrence(tasks, id, s.clock.Now())
+        if err != nil { return nil, err }
+        if spawned != nil { t := *spawned; next = &t }
+        return tasks, nil
+    })
+    if err != nil { return nil, err }
+    return next, nil
+}
+
+// StopSeries ends the series of the recurring task with the given ID
+func (s *TaskService) StopSeries(ctx context.Context, id int) (core.Task, error) {
+    var stopped core.Task
+    err := s.update(ctx, "stop series", func(tasks []core.Task) ([]core.Task, error) {
+        if err := requireLive(tasks, id); err != nil { return nil, err }
+        tasks, err := core.StopSeries(tasks, id)
+        if err != nil { return nil, err }
+        t, _ := core.GetByID(tasks, id)
+        stopped = *t
+        return tasks, nil
+    })
     if err != nil { return core.Task{}, err }
-    if err := s.repo.SaveTasks(ctx, tasks); err != nil { return core.Task{}, err }
-    return *task, nil
+    return stopped, nil
 }
 
-func (s *TaskService) RemoveTask(ctx context.Context, visible []core.Task, idx int) error {
-    tasks, err := s.repo.LoadTasks(ctx)
-    if err != nil { return err }
-    tasks, err = core.Remove(tasks, visible, idx)
-    if err != nil { return err }
-    return s.repo.SaveTasks(ctx, tasks)
+// StartTimer starts the timer on the task with the given ID. The check that
+// no other timer is running happens under the store's lock, so at most one
+// timer runs across processes.
+func (s *TaskService) StartTimer(ctx context.Context, id int) (core.Task, error) {
+    var started core.Task
+    err := s.update(ctx, "start timer", func(tasks []core.Task) ([]core.Task, error) {
+        if err := requireLive(tasks, id); err != nil { return nil, err }
+        if err := core.StartTimer(tasks, id, s.clock.Now()); err != nil { return nil, err }
+        t, _ := core.GetByID(tasks, id)
+        started = *t
+        return tasks, nil
+    })
+    if err != nil { return core.Task{}, err }
+    return started, nil
 }
 
-func (s *TaskService) MarkDone(ctx context.Context, visible []core.Task, idx int) (core.Task, error) {
-    tasks, err := s.repo.LoadTasks(ctx)
+// StopTimer stops the running timer and returns the task it ran on. With a
+// non-zero id it fails unless the timer runs on that task.
+func (s *TaskService) StopTimer(ctx context.Context, id int) (core.Task, error) {
+    var stopped core.Task
+    err := s.update(ctx, "stop timer", func(tasks []core.Task) ([]core.Task, error) {
+        if running, ok := core.RunningTimer(tasks); ok && id != 0 && running.ID != id {
+            return nil, fmt.Errorf("timer is running on task %d, not %d", running.ID, id)
+        }
+        t, err := core.StopTimer(tasks, s.clock.Now())
+        if err != nil { return nil, err }
+        stopped = *t
+        return tasks, nil
+    })
     if err != nil { return core.Task{}, err }
-    before := tasks
-    // use injected clock for deterministic DoneAt and recurrence
-    tasks, err = core.MarkDoneAt(tasks, visible, 1, s.clock.Now())
+    return stopped, nil
+}
+
+// RunningTimer returns the task whose timer is running, or nil
+func (s *TaskService) RunningTimer(ctx context.Context) (*core.Task, error) {
+    tasks, err := s.loadTasks(ctx)
+    if err != nil { return nil, err }
+    if t, ok := core.RunningTimer(tasks); ok { return t, nil }
+    return nil, nil
+}
+
+// LogTime records d spent on the task with the given ID, ending now
+func (s *TaskService) LogTime(ctx context.Context, id int, d time.Duration, note string) (core.Task, error) {
+    var logged core.Task
+    err := s.update(ctx, "log time", func(tasks []core.Task) ([]core.Task, error) {
+        if err := requireLive(tasks, id); err != nil { return nil, err }
+        if err := core.LogTime(tasks, id, d, note, s.clock.Now()); err != nil { return nil, err }
+        t, _ := core.GetByID(tasks, id)
+        logged = *t
+        return tasks, nil
+    })
     if err != nil { return core.Task{}, err }
-    if err := s.repo.SaveTasks(ctx, tasks); err != nil { return core.Task{}, err }
-    // Return the updated task (visible[idx-1] maps by ID)
-    updated, _ := core.GetByID(tasks, visible[idx-1].ID)
-    if updated != nil { return *updated, nil }
-    // Fallback: in recurrence cases the original might be done; return done task by matching title/time
-    if len(before) > 0 { return before[0], nil }
-    return core.Task{}, nil
+    return logged, nil
+}
+
+// TimeSummary returns the time entries and total for the task with the
+// given ID
+func (s *TaskService) TimeSummary(ctx context.Context, id int) (core.TimeSummary, error) {
+    t, err := s.GetTask(ctx, id)
+    if err != nil { return core.TimeSummary{}, err }
+    return t.TimeSummary(s.clock.Now()), nil
 }
 
 func (s *TaskService) QueryTasks(ctx context.Context, q Query) ([]core.Task, error) {
-    tasks, err := s.repo.LoadTasks(ctx)
+    order, err := core.ParseSort(q.SortKey)
+    if err != nil { return nil, err }
+    var filter *core.Filter
+    if q.Filter != "" {
+        if filter, err = core.ParseFilter(q.Filter, s.clock.Now()); err != nil { return nil, err }
+    }
+    tasks, err := s.loadTasks(ctx)
     if err != nil { return nil, err }
+    all := tasks
+    scorer := s.scorer(tasks)
+    // The status filter needs every task to tell which are blocked
+    showAll := q.ShowAll
+    if q.Status != "" {
+        if tasks, err = core.FilterByStatusName(tasks, q.Status); err != nil { return nil, err }
+        showAll = true
+    }
+    deferred := q.Deferred || q.Scheduled
+    project := s.selectProject(q.Project)
+    if filter != nil {
+        // Let the filter decide on the tasks it asks about
+        showAll = showAll || filter.Uses("status") || filter.Uses("done")
+        deferred = deferred || filter.Uses("scheduled")
+        if q.Project == "" && filter.Uses("project") { project = core.ProjectAll }
+        tasks = filter.Apply(tasks, all)
+    }
+    tasks = core.FilterByProject(tasks, project)
+    if !showAll && !deferred {
+        tasks = core.FilterDeferred(tasks, s.clock.Now())
+    }
     // Apply layered filters similar to existing code
-    result := core.SortedWith(tasks, q.ShowAll, q.Grep, q.SortKey)
+    result := core.SortedWithAt(tasks, showAll, q.Grep, order, scorer)
     result = core.FilterByTags(result, q.Tags)
-    if q.Before != nil || q.After != nil {
-        before := ""; after := ""
-        if q.Before != nil { before = q.Before.Format("2006-01-02") }
-        if q.After != nil { after = q.After.Format("2006-01-02") }
-        result = core.FilterByDate(result, before, after)
+    if q.Scheduled {
+        result = core.FilterByDueOrScheduledRange(result, q.Before, q.After)
+    } else {
+        result = core.FilterByDueRange(result, q.Before, q.After)
     }
     return result, nil
 }
 
+// Stats computes statistics for the tasks in project
+func (s *TaskService) Stats(ctx context.Context, project string) (core.Stats, error) {
+    all, err := s.loadTasks(ctx)
+    if err != nil { return core.Stats{}, err }
+    selected := core.FilterByProject(all, s.selectProject(project))
+    return core.CalculateStatsIn(selected, all, s.clock.Now()), nil
+}
+
+// Projects summarizes every project that has tasks
+func (s *TaskService) Projects(ctx context.Context) ([]core.ProjectSummary, error) {
+    tasks, err := s.loadTasks(ctx)
+    if err != nil { return nil, err }
+    return core.Projects(tasks, s.clock.Now()), nil
+}
+
+// MoveToProject moves the task with the given ID, and its subtasks, to a
+// project ("none" for no project)
+func (s *TaskService) MoveToProject(ctx context.Context, id int, project string) (core.Task, error) {
+    return s.UpdateTask(ctx, id, UpdateTaskInput{Project: &project})
+}
+
 func (s *TaskService) GetTask(ctx context.Context, id int) (core.Task, error) {
-    tasks, err := s.repo.LoadTasks(ctx)
+    tasks, err := s.loadTasks(ctx)
     if err != nil { return core.Task{}, err }
     t, err := core.GetByID(tasks, id)
     if err != nil { return core.Task{}, err }
     return *t, nil
 }
 
-func (s *TaskService) DeleteTaskByID(ctx context.Context, id int) error {
-    tasks, err := s.repo.LoadTasks(ctx)
-    if err != nil { return err }
-    found := false
-    newTasks := make([]core.Task, 0, len(tasks))
-    for _, t := range tasks {
-        if t.ID == id { found = true; continue }
-        newTasks = append(newTasks, t)
-    }
-    if !found { return fmt.Errorf("task not found") }
-    return s.repo.SaveTasks(ctx, newTasks)
+// DeleteTask removes the task with the given ID for good, whether or not it
+// is in the trash, handling tasks that depend on it according to policy. It
+// returns the removed tasks, the requested one first. See TrashTask for
+// deleting to the trash.
+func (s *TaskService) DeleteTask(ctx context.Context, id int, policy core.RemovePolicy) ([]core.Task, error) {
+    var removed []core.Task
+    err := s.update(ctx, "remove", func(tasks []core.Task) ([]core.Task, error) {
+        if _, err := core.GetByID(tasks, id); err != nil { return nil, err }
+        tasks, gone, err := core.RemoveWithPolicy(tasks, id, policy)
+        if err != nil { return nil, err }
+        removed = gone
+        // Subtasks move up to the nearest ancestor that is kept
+        core.LiftSubtasks(tasks, removed)
+        return tasks, nil
+    })
+    if err != nil { return nil, err }
+    return removed, nil
 }
 
 func (s *TaskService) MarkDoneByID(ctx context.Context, id int) (core.Task, error) {
-    tasks, err := s.repo.LoadTasks(ctx)
-    if err != nil { return core.Task{}, err }
-    // create a visible slice containing the specific task
-    idx := -1
-    for i, t := range tasks { if t.ID == id { idx = i; break } }
-    if idx == -1 { return core.Task{}, fmt.Errorf("task not found") }
-    visible := []core.Task{tasks[idx]}
-    tasks, err = core.MarkDone(tasks, visible, 1)
-    if err != nil { return core.Task{}, err }
-    if err := s.repo.SaveTasks(ctx, tasks); err != nil { return core.Task{}, err }
-    updated, err := core.GetByID(tasks, id)
+    var updated core.Task
+    err := s.update(ctx, "done", func(tasks []core.Task) ([]core.Task, error) {
+        // create a visible slice containing the specific task
+        idx := -1
+        for i, t := range tasks { if t.ID == id && !t.IsTrashed() { idx = i; break } }
+        if idx == -1 { return nil, fmt.Errorf("task not found") }
+        visible := []core.Task{tasks[idx]}
+        // use injected clock for deterministic DoneAt and recurrence
+        tasks, err := core.MarkDoneAt(tasks, visible, 1, s.clock.Now())
+        if err != nil { return nil, err }
+        updated = tasks[idx]
+        return tasks, nil
+    })
     if err != nil { return core.Task{}, err }
-    return *updated, nil
+    return updated, nil
 }
-
-
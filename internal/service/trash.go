@@ -0,0 +1,123 @@
+package service
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "godoit/internal/core"
+)
+
+// SetTrashRetention sets how long deleted tasks stay in the trash before
+// they are purged; 0 keeps them until the trash is emptied
+func (s *TaskService) SetTrashRetention(d time.Duration) {
+    s.trashRetention = d
+}
+
+// TrashRetention returns how long deleted tasks stay in the trash
+func (s *TaskService) TrashRetention() time.Duration {
+    return s.trashRetention
+}
+
+// loadTasks loads the tasks that are not in the trash
+func (s *TaskService) loadTasks(ctx context.Context) ([]core.Task, error) {
+    tasks, err := s.repo.LoadTasks(ctx)
+    if err != nil { return nil, err }
+    return core.LiveTasks(tasks), nil
+}
+
+// requireLive fails unless every task with the given IDs, other than 0,
+// exists outside the trash
+func requireLive(tasks []core.Task, ids ...int) error {
+    for _, id := range ids {
+        if id == 0 { continue }
+        if _, err := core.GetLiveByID(tasks, id); err != nil { return err }
+    }
+    return nil
+}
+
+// TrashTask moves the task with the given ID to the trash, handling tasks
+// that depend on it according to policy (see core.TrashWithPolicy). It
+// returns the trashed tasks, the requested one first, and under
+// core.RemoveKeep the open tasks left depending on it.
+func (s *TaskService) TrashTask(ctx context.Context, id int, policy core.RemovePolicy) ([]core.Task, []core.Task, error) {
+    if err := s.purgeExpired(ctx); err != nil { return nil, nil, err }
+    var trashed, dependents []core.Task
+    err := s.update(ctx, "trash", func(tasks []core.Task) ([]core.Task, error) {
+        if _, err := core.GetByID(tasks, id); err != nil { return nil, err }
+        var kept []int
+        var err error
+        trashed, kept, err = core.TrashWithPolicy(tasks, id, policy, s.clock.Now())
+        if err != nil { return nil, err }
+        dependents = nil
+        for _, dep := range kept {
+            t, _ := core.GetByID(tasks, dep)
+            dependents = append(dependents, *t)
+        }
+        return tasks, nil
+    })
+    if err != nil { return nil, nil, err }
+    return trashed, dependents, nil
+}
+
+// RestoreTask takes the task with the given ID out of the trash
+func (s *TaskService) RestoreTask(ctx context.Context, id int) (core.Task, error) {
+    if err := s.purgeExpired(ctx); err != nil { return core.Task{}, err }
+    var restored core.Task
+    err := s.update(ctx, "restore", func(tasks []core.Task) ([]core.Task, error) {
+        t, err := core.Restore(tasks, id)
+        if err != nil { return nil, err }
+        restored = *t
+        return tasks, nil
+    })
+    if err != nil { return core.Task{}, err }
+    return restored, nil
+}
+
+// Trash returns the tasks in the trash, most recently deleted first. Those
+// kept longer than the retention are left out; they are purged by the next
+// change to the trash.
+func (s *TaskService) Trash(ctx context.Context) ([]core.Task, error) {
+    tasks, err := s.repo.LoadTasks(ctx)
+    if err != nil { return nil, err }
+    if s.trashRetention > 0 { tasks, _ = core.PurgeTrash(tasks, s.clock.Now().Add(-s.trashRetention)) }
+    return core.TrashedTasks(tasks), nil
+}
+
+// EmptyTrash removes every task in the trash for good and returns them
+func (s *TaskService) EmptyTrash(ctx context.Context) ([]core.Task, error) {
+    var purged []core.Task
+    err := s.update(ctx, "empty trash", func(tasks []core.Task) ([]core.Task, error) {
+        tasks, purged = core.PurgeTrash(tasks, s.clock.Now())
+        return tasks, nil
+    })
+    if err != nil { return nil, err }
+    return purged, nil
+}
+
+// purgeExpired removes for good the tasks kept in the trash longer than the
+// retention. It only takes the store's lock when there are such tasks. The
+// purge is not the user's doing, so it is kept out of the undo history and
+// logged as made by the system.
+func (s *TaskService) purgeExpired(ctx context.Context) error {
+    if s.trashRetention <= 0 { return nil }
+    cutoff := s.clock.Now().Add(-s.trashRetention)
+    tasks, err := s.repo.LoadTasks(ctx)
+    if err != nil { return err }
+    expired := false
+    for _, t := range tasks {
+        if t.IsTrashed() && !t.DeletedAt.After(cutoff) { expired = true; break }
+    }
+    if !expired { return nil }
+
+    var op core.Operation
+    err = s.repo.Update(ctx, func(tasks []core.Task) ([]core.Task, error) {
+        before := make([]core.Task, len(tasks))
+        for i := range tasks { before[i] = tasks[i].Clone() }
+        tasks, _ = core.PurgeTrash(tasks, cutoff)
+        op = core.Operation{Action: "purge trash", At: s.clock.Now(), Changes: core.DiffTasks(before, tasks)}
+        return tasks, nil
+    })
+    if err != nil { return fmt.Errorf("purging the trash: %w", err) }
+    return s.logAudit(WithOrigin(ctx, core.Origin{Source: "system"}), op)
+}
@@ -0,0 +1,86 @@
+package service
+
+import (
+    "context"
+    "fmt"
+
+    "godoit/internal/core"
+    "godoit/internal/repository"
+)
+
+// SetUndoRepository sets where the undo history is kept. Without one,
+// mutations are not recorded and cannot be undone.
+func (s *TaskService) SetUndoRepository(r repository.UndoRepository) {
+    s.undo = r
+}
+
+func (s *TaskService) undoRepo() (repository.UndoRepository, error) {
+    if s.undo == nil { return nil, fmt.Errorf("undo is not available") }
+    return s.undo, nil
+}
+
+// update runs fn as a transaction like TaskRepository.Update and records the
+// changes it made in the undo history and the audit log as action. The
+// history is written while the task store's lock is held, so operations are
+// recorded in the order they were made, across processes; the audit log once
+// the changes are saved.
+func (s *TaskService) update(ctx context.Context, action string, fn func([]core.Task) ([]core.Task, error)) error {
+    if s.undo == nil && s.audit == nil { return s.repo.Update(ctx, fn) }
+    var op core.Operation
+    err := s.repo.Update(ctx, func(tasks []core.Task) ([]core.Task, error) {
+        before := make([]core.Task, len(tasks))
+        for i := range tasks { before[i] = tasks[i].Clone() }
+        tasks, err := fn(tasks)
+        if err != nil { return nil, err }
+        op = core.Operation{Action: action, At: s.clock.Now(), Changes: core.DiffTasks(before, tasks)}
+        if len(op.Changes) == 0 || s.undo == nil { return tasks, nil }
+        err = s.undo.UpdateUndo(ctx, func(history []core.Operation) ([]core.Operation, error) {
+            return core.RecordOperation(history, op, core.UndoLimit), nil
+        })
+        return tasks, err
+    })
+    if err != nil { return err }
+    return s.logAudit(ctx, op)
+}
+
+// Undo reverts the last operation that has not been undone yet and returns
+// it. It fails with a core.UndoConflictError if a task the operation changed
+// has been changed since, and with core.ErrNothingToUndo if there is no
+// operation left.
+func (s *TaskService) Undo(ctx context.Context) (core.Operation, error) {
+    return s.undoRedo(ctx, core.Undo)
+}
+
+// Redo applies again the operation undone last
+func (s *TaskService) Redo(ctx context.Context) (core.Operation, error) {
+    return s.undoRedo(ctx, core.Redo)
+}
+
+func (s *TaskService) undoRedo(ctx context.Context, step func([]core.Task, []core.Operation) ([]core.Task, []core.Operation, core.Operation, error)) (core.Operation, error) {
+    repo, err := s.undoRepo()
+    if err != nil { return core.Operation{}, err }
+    var op, applied core.Operation
+    err = s.repo.Update(ctx, func(tasks []core.Task) ([]core.Task, error) {
+        var result []core.Task
+        err := repo.UpdateUndo(ctx, func(history []core.Operation) ([]core.Operation, error) {
+            var err error
+            result, history, op, err = step(tasks, history)
+            return history, err
+        })
+        if err != nil { return nil, err }
+        action := "redo "
+        if op.Undone { action = "undo " }
+        applied = core.Operation{Action: action + op.Action, At: s.clock.Now(), Changes: core.DiffTasks(tasks, result)}
+        return result, nil
+    })
+    if err != nil { return op, err }
+    return op, s.logAudit(ctx, applied)
+}
+
+// UndoHistory returns the recorded operations, oldest first. Those that have
+// been undone, and can be redone, are marked Undone.
+func (s *TaskService) UndoHistory(ctx context.Context) ([]core.Operation, error) {
+    repo, err := s.undoRepo()
+    if err != nil { return nil, err }
+    return repo.LoadUndo(ctx)
+}
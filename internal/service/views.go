@@ -0,0 +1,109 @@
+package service
+
+import (
+    "context"
+    "fmt"
+    "time"
+
+    "godoit/internal/core"
+    "godoit/internal/repository"
+)
+
+// SetViewRepository sets where saved views are kept
+func (s *TaskService) SetViewRepository(r repository.ViewRepository) {
+    s.views = r
+}
+
+func (s *TaskService) viewRepo() (repository.ViewRepository, error) {
+    if s.views == nil { return nil, fmt.Errorf("saved views are not available") }
+    return s.views, nil
+}
+
+// Views returns the saved views, sorted by name
+func (s *TaskService) Views(ctx context.Context) ([]core.View, error) {
+    repo, err := s.viewRepo()
+    if err != nil { return nil, err }
+    return repo.LoadViews(ctx)
+}
+
+// GetView returns the saved view with the given name
+func (s *TaskService) GetView(ctx context.Context, name string) (core.View, error) {
+    views, err := s.Views(ctx)
+    if err != nil { return core.View{}, err }
+    v, err := core.FindView(views, name)
+    if err != nil { return core.View{}, err }
+    return *v, nil
+}
+
+// SaveView validates a view and saves it, replacing any view of the same name
+func (s *TaskService) SaveView(ctx context.Context, v core.View) (core.View, error) {
+    repo, err := s.viewRepo()
+    if err != nil { return core.View{}, err }
+    if v.Name, err = core.NormalizeViewName(v.Name); err != nil { return core.View{}, err }
+    if err := v.Validate(s.clock.Now()); err != nil { return core.View{}, err }
+    err = repo.UpdateViews(ctx, func(views []core.View) ([]core.View, error) {
+        return core.PutView(views, v), nil
+    })
+    return v, err
+}
+
+// DeleteView removes the saved view with the given name
+func (s *TaskService) DeleteView(ctx context.Context, name string) error {
+    repo, err := s.viewRepo()
+    if err != nil { return err }
+    return repo.UpdateViews(ctx, func(views []core.View) ([]core.View, error) {
+        return core.RemoveView(views, name)
+    })
+}
+
+// ViewQuery turns a view's filters into a Query, resolving its dates now.
+// Today and week views cover the current day and the week starting on
+// Sunday, and include tasks scheduled in that period as well as those due.
+func (s *TaskService) ViewQuery(v core.View) (Query, error) {
+    q := Query{
+        ShowAll: v.All,
+        Grep:    v.Grep,
+        SortKey: v.Sort,
+        Tags:    v.Tags,
+        Status:  v.Status,
+        Project: v.Project,
+        Deferred: v.Deferred,
+        Filter:  v.Filter,
+    }
+
+    now := s.clock.Now()
+    if v.Before != "" {
+        t, err := s.ParseDate(v.Before)
+        if err != nil { return q, fmt.Errorf("invalid before date: %w", err) }
+        q.Before = &t
+    }
+    if v.After != "" {
+        t, err := s.ParseDate(v.After)
+        if err != nil { return q, fmt.Errorf("invalid after date: %w", err) }
+        q.After = &t
+    }
+
+    // Day boundaries are in the user's zone
+    if v.Today || v.Week {
+        start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+        days := 1
+        if v.Week {
+            start = start.AddDate(0, 0, -int(start.Weekday()))
+            days = 7
+        }
+        end := start.AddDate(0, 0, days).Add(-time.Nanosecond)
+        q.Before, q.After = &end, &start
+        q.Scheduled = true
+    }
+    return q, nil
+}
+
+// RunView returns the saved view with the given name and the tasks it shows
+func (s *TaskService) RunView(ctx context.Context, name string) (core.View, []core.Task, error) {
+    v, err := s.GetView(ctx, name)
+    if err != nil { return v, nil, err }
+    q, err := s.ViewQuery(v)
+    if err != nil { return v, nil, err }
+    tasks, err := s.QueryTasks(ctx, q)
+    return v, tasks, err
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+// AuditLog is an append-only file of JSON lines. Lines are only ever added
+// at the end; a line cut short by a crash is ignored when reading and
+// dropped before the next append.
+type AuditLog struct {
+	filePath string
+	lock     *flock.Flock
+	// txn serializes Append callers within this process, as in JSONStore
+	txn chan struct{}
+}
+
+// NewAuditLog creates an audit log at filePath
+func NewAuditLog(filePath string) (*AuditLog, error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, err
+	}
+
+	return &AuditLog{
+		filePath: filePath,
+		lock:     flock.New(filePath + ".lock"),
+		txn:      make(chan struct{}, 1),
+	}, nil
+}
+
+// AuditStore returns the AuditLog using the audit log file in the data
+// directory
+func AuditStore() (*AuditLog, error) {
+	filePath, err := GetAuditFile()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAuditLog(filePath)
+}
+
+// Lines returns every complete line in the log, oldest first
+func (l *AuditLog) Lines() ([][]byte, error) {
+	data, err := os.ReadFile(l.filePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var lines [][]byte
+	for len(data) > 0 {
+		line, rest, complete := cutLine(data)
+		if !complete {
+			break
+		}
+		if len(line) > 0 {
+			lines = append(lines, line)
+		}
+		data = rest
+	}
+	return lines, nil
+}
+
+// Append adds lines at the end of the log in a single write, under a
+// cross-process lock. Lines must not contain newlines.
+func (l *AuditLog) Append(ctx context.Context, lines [][]byte) error {
+	if len(lines) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, line := range lines {
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+
+	select {
+	case l.txn <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-l.txn }()
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		locked, err := l.lock.TryLock()
+		if err != nil {
+			return err
+		}
+		if locked {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	defer l.lock.Unlock()
+
+	file, err := os.OpenFile(l.filePath, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	end, err := completeEnd(file)
+	if err != nil {
+		return err
+	}
+	if err := file.Truncate(end); err != nil {
+		return err
+	}
+	if _, err := file.WriteAt(buf.Bytes(), end); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+// completeEnd returns the offset just past the last complete line in file
+func completeEnd(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	size := info.Size()
+	if size == 0 {
+		return 0, nil
+	}
+	last := make([]byte, 1)
+	if _, err := file.ReadAt(last, size-1); err != nil {
+		return 0, err
+	}
+	if last[0] == '\n' {
+		return size, nil
+	}
+
+	// A torn line: cut back to the newline before it
+	data, err := io.ReadAll(io.NewSectionReader(file, 0, size))
+	if err != nil {
+		return 0, err
+	}
+	return int64(bytes.LastIndexByte(data, '\n') + 1), nil
+}
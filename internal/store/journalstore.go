@@ -0,0 +1,594 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofrs/flock"
+)
+
+// DefaultCompactAfter is how many records the journal may hold before it is
+// folded into the snapshot
+const DefaultCompactAfter = 500
+
+// minCompactRecords keeps small lists, whose journal soon outgrows the
+// snapshot, from being compacted on every save
+const minCompactRecords = 32
+
+// journalVersion is the format of the journal header and records
+const journalVersion = 1
+
+// JournalStore implements Store as a snapshot plus an append-only journal.
+// The snapshot is a JSON array in the same file, and the same format, as
+// JSONStore uses. Each Save appends one record to the journal listing the
+// elements added, changed and removed since the last save, keyed by their
+// "id" field, and Load replays the journal over the snapshot. Once the
+// journal holds CompactAfter records, or grows larger than the snapshot, a
+// save folds it into a new snapshot and starts a new journal.
+//
+// The journal header records a checksum of the snapshot it applies to, so
+// a journal left behind by an interrupted compaction, or one predating
+// changes made to the snapshot by JSONStore, is ignored rather than
+// replayed twice. A final record cut short by a crash is ignored when
+// loading and overwritten by the next save.
+//
+// Save must be called within WithExclusive, as the repositories do: the
+// record it appends is computed against the state it last loaded.
+type JournalStore struct {
+	snapshotPath string
+	journalPath  string
+
+	// CompactAfter is the number of records after which the journal is
+	// compacted; zero means DefaultCompactAfter
+	CompactAfter int
+
+	mu   sync.Mutex
+	lock *flock.Flock
+	// txn serializes WithExclusive callers within this process, as in JSONStore
+	txn   chan struct{}
+	state *journalState // as of the last load or save; nil until then
+	saves int           // saves made through this store, for Stamp
+}
+
+// journalHeader is the first line of the journal
+type journalHeader struct {
+	Version int    `json:"journal"`
+	Base    uint32 `json:"base"` // CRC-32 of the snapshot the records apply to
+	Seq     int64  `json:"seq"`  // sequence number of the last record folded into the snapshot
+}
+
+// journalRecord is one line of the journal after the header: the changes
+// made by one Save. Keys are the raw JSON "id" values of the elements.
+type journalRecord struct {
+	Seq   int64             `json:"seq"`
+	At    time.Time         `json:"at"`
+	Reset bool              `json:"reset,omitempty"` // Put replaces every element
+	Put   []json.RawMessage `json:"put,omitempty"`   // added or changed elements; new ones go last
+	Del   []string          `json:"del,omitempty"`   // keys of removed elements
+	Order []string          `json:"order,omitempty"` // keys in their new order, if not implied by Put and Del
+}
+
+// fileStamp identifies a version of a file, to tell when the cached state
+// is out of date
+type fileStamp struct {
+	exists bool
+	size   int64
+	mod    int64
+}
+
+func stampOf(info os.FileInfo) fileStamp {
+	return fileStamp{exists: true, size: info.Size(), mod: info.ModTime().UnixNano()}
+}
+
+func statStamp(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fileStamp{}, nil
+	}
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return stampOf(info), nil
+}
+
+// journalState is the data as of some point of the journal
+type journalState struct {
+	order []string
+	items map[string]json.RawMessage
+
+	base    uint32 // checksum of the snapshot
+	seq     int64  // last record applied
+	records int    // records in the journal
+	valid   bool   // the journal exists and applies to the snapshot
+	end     int64  // offset just past the last complete record
+
+	snapshot, journal fileStamp
+}
+
+// NewJournalStore creates a journal store whose snapshot is at filePath.
+// The journal is kept next to it, with the extension ".journal".
+func NewJournalStore(filePath string) (*JournalStore, error) {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return nil, err
+	}
+
+	return &JournalStore{
+		snapshotPath: filePath,
+		journalPath:  journalPathFor(filePath),
+		lock:         flock.New(filePath + ".lock"),
+		txn:          make(chan struct{}, 1),
+	}, nil
+}
+
+// journalPathFor returns the journal belonging to the snapshot at filePath
+func journalPathFor(filePath string) string {
+	return strings.TrimSuffix(filePath, ".json") + ".journal"
+}
+
+// Load returns the snapshot with the journal replayed over it
+func (s *JournalStore) Load() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	st, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+	return st.marshal(), nil
+}
+
+// Save appends the difference between data, a JSON array, and the stored
+// array to the journal, compacting it if it has grown large enough
+func (s *JournalStore) Save(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return err
+	}
+	var elems []json.RawMessage
+	if err := json.Unmarshal(compact.Bytes(), &elems); err != nil {
+		return fmt.Errorf("journal store: %w", err)
+	}
+
+	st, err := s.load()
+	if err != nil {
+		return err
+	}
+	rec := st.diff(elems)
+	if rec == nil {
+		return nil // nothing changed
+	}
+	s.saves++
+	rec.Seq = st.seq + 1
+	rec.At = time.Now().UTC()
+	if err := st.apply(rec); err != nil {
+		s.state = nil
+		return err
+	}
+	st.seq = rec.Seq
+
+	if !st.valid || s.needsCompaction(st) {
+		return s.compact(st)
+	}
+	if err := s.appendRecord(st, rec); err != nil {
+		s.state = nil // the cached state is ahead of the files
+		return err
+	}
+	return nil
+}
+
+// Stamp implements Stamper, from the stamps of the snapshot and the journal
+// and, as in JSONStore, the saves made through this store
+func (s *JournalStore) Stamp() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot, err := statStamp(s.snapshotPath)
+	if err != nil {
+		return "", err
+	}
+	journal, err := statStamp(s.journalPath)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%v/%v/%d", snapshot, journal, s.saves), nil
+}
+
+// Close implements Store interface (no-op for file-based storage)
+func (s *JournalStore) Close() error {
+	return nil
+}
+
+// WithExclusive acquires a cross-process exclusive lock for the duration of
+// fn. The lock is shared with JSONStore, which uses the same lock file.
+func (s *JournalStore) WithExclusive(ctx context.Context, fn func() error) error {
+	select {
+	case s.txn <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-s.txn }()
+
+	ticker := time.NewTicker(50 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		locked, err := s.lock.TryLock()
+		if err != nil {
+			return err
+		}
+		if locked {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+	defer s.lock.Unlock()
+	return fn()
+}
+
+// Compact folds the journal into the snapshot and starts an empty journal
+func (s *JournalStore) Compact(ctx context.Context) error {
+	return s.WithExclusive(ctx, func() error {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		st, err := s.load()
+		if err != nil {
+			return err
+		}
+		return s.compact(st)
+	})
+}
+
+func (s *JournalStore) needsCompaction(st *journalState) bool {
+	limit := s.CompactAfter
+	if limit <= 0 {
+		limit = DefaultCompactAfter
+	}
+	return st.records >= limit || (st.records >= minCompactRecords && st.journal.size > st.snapshot.size)
+}
+
+// load returns the current state, reading the files only if they changed
+// since the last load or save. It must be called with s.mu held.
+func (s *JournalStore) load() (*journalState, error) {
+	for attempt := 0; ; attempt++ {
+		snapshotStamp, err := statStamp(s.snapshotPath)
+		if err != nil {
+			return nil, err
+		}
+		journalStamp, err := statStamp(s.journalPath)
+		if err != nil {
+			return nil, err
+		}
+		if s.state != nil && s.state.snapshot == snapshotStamp && s.state.journal == journalStamp {
+			return s.state, nil
+		}
+
+		st, err := s.read()
+		if err != nil {
+			return nil, err
+		}
+		// A compaction may have replaced the snapshot between reading it and
+		// the journal; read both again before deciding the journal is stale
+		if !st.valid && st.journal.exists && attempt < 2 {
+			continue
+		}
+		s.state = st
+		return st, nil
+	}
+}
+
+// read loads the snapshot and replays the journal over it
+func (s *JournalStore) read() (*journalState, error) {
+	snapshot, snapshotStamp, err := readStamped(s.snapshotPath)
+	if err != nil {
+		return nil, err
+	}
+	st := &journalState{base: crc32.ChecksumIEEE(snapshot), snapshot: snapshotStamp}
+	if len(bytes.TrimSpace(snapshot)) == 0 {
+		snapshot = []byte("[]")
+	}
+	var elems []json.RawMessage
+	if err := json.Unmarshal(snapshot, &elems); err != nil {
+		return nil, fmt.Errorf("snapshot %s: %w", s.snapshotPath, err)
+	}
+	if err := st.apply(&journalRecord{Reset: true, Put: elems}); err != nil {
+		return nil, err
+	}
+
+	journal, journalStamp, err := readStamped(s.journalPath)
+	if err != nil {
+		return nil, err
+	}
+	st.journal = journalStamp
+	if !journalStamp.exists {
+		return st, nil
+	}
+
+	line, rest, complete := cutLine(journal)
+	var header journalHeader
+	if !complete || json.Unmarshal(line, &header) != nil {
+		return nil, fmt.Errorf("journal %s: invalid header", s.journalPath)
+	}
+	if header.Version != journalVersion {
+		return nil, fmt.Errorf("journal %s: unsupported version %d", s.journalPath, header.Version)
+	}
+	if header.Base != st.base {
+		return st, nil // stale: the snapshot already holds its changes, or replaced them
+	}
+	st.valid = true
+	st.seq = header.Seq
+	st.end = int64(len(line) + 1)
+
+	for n := 2; len(rest) > 0; n++ {
+		line, rest, complete = cutLine(rest)
+		var rec journalRecord
+		err := json.Unmarshal(line, &rec)
+		if !complete || err != nil {
+			if len(rest) == 0 {
+				break // torn final record from an interrupted save
+			}
+			return nil, fmt.Errorf("journal %s: line %d: invalid record", s.journalPath, n)
+		}
+		if rec.Seq != st.seq+1 {
+			return nil, fmt.Errorf("journal %s: line %d: record %d out of sequence (expected %d)", s.journalPath, n, rec.Seq, st.seq+1)
+		}
+		if err := st.apply(&rec); err != nil {
+			return nil, fmt.Errorf("journal %s: line %d: %w", s.journalPath, n, err)
+		}
+		st.seq = rec.Seq
+		st.records++
+		st.end += int64(len(line) + 1)
+	}
+	return st, nil
+}
+
+// readStamped reads a file and the stamp of the version read. A missing file
+// reads as empty.
+func readStamped(path string) ([]byte, fileStamp, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fileStamp{}, nil
+	}
+	if err != nil {
+		return nil, fileStamp{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, fileStamp{}, err
+	}
+	data, err := io.ReadAll(io.LimitReader(file, info.Size()))
+	if err != nil {
+		return nil, fileStamp{}, err
+	}
+	return data, stampOf(info), nil
+}
+
+// cutLine splits data after the first newline, reporting whether there was one
+func cutLine(data []byte) (line, rest []byte, complete bool) {
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		return data[:i], data[i+1:], true
+	}
+	return data, nil, false
+}
+
+// appendRecord writes rec at the end of the last complete record, dropping
+// any torn record after it
+func (s *JournalStore) appendRecord(st *journalState, rec *journalRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	file, err := os.OpenFile(s.journalPath, os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if err := file.Truncate(st.end); err != nil {
+		return err
+	}
+	if _, err := file.WriteAt(line, st.end); err != nil {
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	st.records++
+	st.end += int64(len(line))
+	st.journal = stampOf(info)
+	return nil
+}
+
+// compact writes st as the new snapshot, then replaces the journal with an
+// empty one that applies to it. A crash in between leaves a journal that no
+// longer matches the snapshot, which is then ignored.
+func (s *JournalStore) compact(st *journalState) error {
+	// Invalidate the cache first, in case writing fails half way
+	s.state = nil
+
+	snapshot := st.marshal()
+	if err := writeFileAtomic(s.snapshotPath, snapshot); err != nil {
+		return err
+	}
+	header, err := json.Marshal(journalHeader{Version: journalVersion, Base: crc32.ChecksumIEEE(snapshot), Seq: st.seq})
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(s.journalPath, append(header, '\n')); err != nil {
+		return err
+	}
+
+	st.base = crc32.ChecksumIEEE(snapshot)
+	st.valid = true
+	st.records = 0
+	st.end = int64(len(header) + 1)
+	if st.snapshot, err = statStamp(s.snapshotPath); err != nil {
+		return err
+	}
+	if st.journal, err = statStamp(s.journalPath); err != nil {
+		return err
+	}
+	s.state = st
+	return nil
+}
+
+// marshal renders the state as a JSON array
+func (st *journalState) marshal() []byte {
+	size := 2
+	for _, k := range st.order {
+		size += len(st.items[k]) + 1
+	}
+	buf := make([]byte, 0, size)
+	buf = append(buf, '[')
+	for i, k := range st.order {
+		if i > 0 {
+			buf = append(buf, ',')
+		}
+		buf = append(buf, st.items[k]...)
+	}
+	return append(buf, ']')
+}
+
+// elementKey returns the raw JSON "id" of an element, if it has a number
+// or string one
+func elementKey(elem json.RawMessage) (string, bool) {
+	var v struct {
+		ID json.RawMessage `json:"id"`
+	}
+	if json.Unmarshal(elem, &v) != nil || len(v.ID) == 0 {
+		return "", false
+	}
+	switch v.ID[0] {
+	case '{', '[', 'n', 't', 'f':
+		return "", false
+	}
+	return string(v.ID), true
+}
+
+// diff returns the record that turns st into elems, or nil if they are the
+// same. Arrays whose elements lack unique ids are recorded in full.
+func (st *journalState) diff(elems []json.RawMessage) *journalRecord {
+	keys := make([]string, len(elems))
+	seen := make(map[string]bool, len(elems))
+	for i, e := range elems {
+		k, ok := elementKey(e)
+		if !ok || seen[k] {
+			next := &journalState{}
+			if next.apply(&journalRecord{Reset: true, Put: elems}) != nil || bytes.Equal(next.marshal(), st.marshal()) {
+				return nil
+			}
+			return &journalRecord{Reset: true, Put: elems}
+		}
+		seen[k] = true
+		keys[i] = k
+	}
+
+	rec := &journalRecord{}
+	// Replaying keeps the remaining elements in place and adds new ones last
+	implied := make([]string, 0, len(keys))
+	for _, k := range st.order {
+		if seen[k] {
+			implied = append(implied, k)
+		} else {
+			rec.Del = append(rec.Del, k)
+		}
+	}
+	for i, e := range elems {
+		old, ok := st.items[keys[i]]
+		if !ok {
+			implied = append(implied, keys[i])
+		}
+		if !ok || !bytes.Equal(old, e) {
+			rec.Put = append(rec.Put, e)
+		}
+	}
+	for i := range keys {
+		if implied[i] != keys[i] {
+			rec.Order = keys
+			break
+		}
+	}
+
+	if rec.Put == nil && rec.Del == nil && rec.Order == nil {
+		return nil
+	}
+	return rec
+}
+
+// apply replays a record
+func (st *journalState) apply(rec *journalRecord) error {
+	if rec.Reset {
+		st.order = make([]string, 0, len(rec.Put))
+		st.items = make(map[string]json.RawMessage, len(rec.Put))
+		for i, e := range rec.Put {
+			k, ok := elementKey(e)
+			if _, dup := st.items[k]; !ok || dup {
+				// Keyed by position; "#" cannot start a JSON value
+				k = "#" + strconv.Itoa(i)
+			}
+			st.order = append(st.order, k)
+			st.items[k] = e
+		}
+		return nil
+	}
+
+	if len(rec.Del) > 0 {
+		for _, k := range rec.Del {
+			delete(st.items, k)
+		}
+		kept := make([]string, 0, len(st.items))
+		for _, k := range st.order {
+			if _, ok := st.items[k]; ok {
+				kept = append(kept, k)
+			}
+		}
+		st.order = kept
+	}
+	for _, e := range rec.Put {
+		k, ok := elementKey(e)
+		if !ok {
+			return fmt.Errorf("element without an id")
+		}
+		if _, exists := st.items[k]; !exists {
+			st.order = append(st.order, k)
+		}
+		st.items[k] = e
+	}
+	if rec.Order != nil {
+		if len(rec.Order) != len(st.items) {
+			return fmt.Errorf("order lists %d elements, expected %d", len(rec.Order), len(st.items))
+		}
+		for _, k := range rec.Order {
+			if _, ok := st.items[k]; !ok {
+				return fmt.Errorf("order lists unknown element %s", k)
+			}
+		}
+		st.order = append(st.order[:0:0], rec.Order...)
+	}
+	return nil
+}
@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -16,7 +17,11 @@ import (
 type JSONStore struct {
 	filePath string
 	mu       sync.RWMutex
+	saves    int // saves made through this store, for Stamp
   lock     *flock.Flock
+  // txn serializes WithExclusive callers within this process; the file lock
+  // alone does not, because flock treats the holder as the whole process.
+  txn      chan struct{}
 }
 
 // NewJSONStore creates a new JSON-based store
@@ -29,6 +34,7 @@ func NewJSONStore(filePath string) (*JSONStore, error) {
     return &JSONStore{
         filePath: filePath,
         lock:     flock.New(filePath + ".lock"),
+        txn:      make(chan struct{}, 1),
     }, nil
 }
 
@@ -72,8 +78,35 @@ func (s *JSONStore) Save(data []byte) error {
 		return err
 	}
 
+	if err := writeFileAtomic(s.filePath, data); err != nil {
+		return err
+	}
+	s.saves++
+	return nil
+}
+
+// Stamp implements Stamper. It combines the file's size and modification
+// time, which tell of saves by other processes, with the saves made through
+// this store, which may fall within the same tick of the file system clock.
+func (s *JSONStore) Stamp() (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	info, err := os.Stat(s.filePath)
+	if os.IsNotExist(err) {
+		return fmt.Sprintf("none/%d", s.saves), nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d/%d/%d", info.Size(), info.ModTime().UnixNano(), s.saves), nil
+}
+
+// writeFileAtomic replaces the file at path with data, so that readers see
+// either the old or the new contents in full
+func writeFileAtomic(path string, data []byte) error {
 	// Write to temporary file first
-	tempFile := s.filePath + ".tmp"
+	tempFile := path + ".tmp"
 	file, err := os.OpenFile(tempFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return err
@@ -99,7 +132,7 @@ func (s *JSONStore) Save(data []byte) error {
 	}
 
 	// Atomic rename
-	return os.Rename(tempFile, s.filePath)
+	return os.Rename(tempFile, path)
 }
 
 // Close implements Store interface (no-op for file-based storage)
@@ -108,7 +141,15 @@ func (s *JSONStore) Close() error {
 }
 
 // WithExclusive acquires a cross-process exclusive lock for the duration of fn.
+// It is not reentrant: fn must not call WithExclusive on the same store.
 func (s *JSONStore) WithExclusive(ctx context.Context, fn func() error) error {
+    select {
+    case s.txn <- struct{}{}:
+    case <-ctx.Done():
+        return ctx.Err()
+    }
+    defer func() { <-s.txn }()
+
     // Try to acquire the file lock with retry/backoff honoring ctx
     // Use a short poll interval to avoid busy waiting
     ticker := time.NewTicker(50 * time.Millisecond)
@@ -166,3 +207,25 @@ func DefaultStore() (*JSONStore, error) {
 	return NewJSONStore(filePath)
 }
 
+
+// ViewStore returns a JSONStore using the saved views file in the config
+// directory
+func ViewStore() (*JSONStore, error) {
+	filePath, err := GetViewsFile()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewJSONStore(filePath)
+}
+
+// UndoStore returns a JSONStore using the undo history file in the data
+// directory
+func UndoStore() (*JSONStore, error) {
+	filePath, err := GetUndoFile()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewJSONStore(filePath)
+}
@@ -95,3 +95,40 @@ func GetDataFile() (string, error) {
 	return filepath.Join(dataDir, "tasks.json"), nil
 }
 
+// GetViewsFile returns the full path to the saved views file. Views are
+// settings rather than data, so they live in the config directory.
+func GetViewsFile() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "views.json"), nil
+}
+
+// GetUndoFile returns the full path to the undo history file
+func GetUndoFile() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "undo.json"), nil
+}
+
+// GetAuditFile returns the full path to the audit log of task changes
+func GetAuditFile() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "audit.jsonl"), nil
+}
+
+// GetLastViewFile returns the path to the file remembering the task IDs of
+// the most recently displayed list, used for index-based addressing
+func GetLastViewFile() (string, error) {
+	dataDir, err := GetDataDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dataDir, "last_view.json"), nil
+}
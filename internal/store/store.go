@@ -1,6 +1,17 @@
 package store
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Storage kinds for the tasks file, chosen with the "storage" setting
+const (
+	StorageJSON    = "json"    // rewrite tasks.json on every change (default)
+	StorageJournal = "journal" // append changes to tasks.journal, see JournalStore
+)
 
 // Store defines the interface for task storage operations
 // This abstraction allows for future implementations (SQLite, PostgreSQL, etc.)
@@ -19,3 +30,61 @@ type Store interface {
     WithExclusive(ctx context.Context, fn func() error) error
 }
 
+// Stamper is implemented by stores that can tell whether their data has
+// changed without reading it
+type Stamper interface {
+	// Stamp returns a value that changes whenever the data is saved
+	Stamp() (string, error)
+}
+
+
+// TaskStore returns the store for the default data file of the given kind
+// ("" for StorageJSON). Switching back to StorageJSON first folds any
+// journal left by StorageJournal into the data file.
+func TaskStore(kind string) (Store, error) {
+	filePath, err := GetDataFile()
+	if err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case "", StorageJSON:
+		if err := foldJournal(filePath); err != nil {
+			return nil, err
+		}
+		return NewJSONStore(filePath)
+	case StorageJournal:
+		return NewJournalStore(filePath)
+	default:
+		return nil, fmt.Errorf("invalid storage %q (use %s or %s)", kind, StorageJSON, StorageJournal)
+	}
+}
+
+// foldJournal compacts the journal next to filePath, if there is one, and
+// removes it, leaving every change in the snapshot for JSONStore
+func foldJournal(filePath string) error {
+	journalPath := journalPathFor(filePath)
+	if _, err := os.Stat(journalPath); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	js, err := NewJournalStore(filePath)
+	if err != nil {
+		return err
+	}
+	return js.WithExclusive(context.Background(), func() error {
+		js.mu.Lock()
+		defer js.mu.Unlock()
+
+		st, err := js.load()
+		if err != nil {
+			return err
+		}
+		if st.valid && st.records > 0 {
+			if err := js.compact(st); err != nil {
+				return err
+			}
+		}
+		return os.Remove(journalPath)
+	})
+}